@@ -0,0 +1,50 @@
+package geoservice
+
+import (
+	"github.com/aliforever/geo-service/asn"
+	"github.com/aliforever/geo-service/geolocation"
+	"io"
+	"net"
+)
+
+// WithASN merges the networks of table into lookup results, see ImportASN
+func WithASN(table *asn.Table) Option {
+	return func(g *GeoService) {
+		g.networks.Store(table)
+	}
+}
+
+// ImportASN reads a network,asn,org CSV (see asn.LoadCSV) and swaps it in for the networks merged into lookup results.
+// The previous networks are kept when the CSV is invalid.
+func (g *GeoService) ImportASN(source io.Reader) (networks int, err error) {
+	table, err := asn.LoadCSV(source)
+	if err != nil {
+		return
+	}
+	g.networks.Store(table)
+	networks = table.Len()
+	return
+}
+
+// network returns the most specific network containing ip, ok is false without ASN data
+func (g *GeoService) network(ip net.IP) (network geolocation.Network, ok bool) {
+	table := g.networks.Load()
+	if table == nil {
+		return
+	}
+	return table.Lookup(ip)
+}
+
+// Network returns the most specific network containing ip and its owner.
+// It returns ErrInvalidIP for malformed addresses and geolocation.ErrNotFound when no network contains ip.
+func (g *GeoService) Network(ip net.IP) (network geolocation.Network, err error) {
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		err = ErrInvalidIP
+		return
+	}
+	network, ok := g.network(ip)
+	if !ok {
+		err = geolocation.ErrNotFound
+	}
+	return
+}
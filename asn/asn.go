@@ -0,0 +1,134 @@
+package asn
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Table is a range index of announced networks answering longest prefix matches, it is immutable and safe for
+// concurrent use. IPv4 networks also match IPv4-mapped IPv6 addresses.
+type Table struct {
+	// networks holds the networks of every prefix length by masked prefix
+	networks map[int]map[netip.Prefix]geolocation.Network
+	// bits lists the prefix lengths of networks, the most specific first
+	bits []int
+	size int
+}
+
+// New builds a table over networks, a later network replaces an earlier one with the same prefix
+func New(networks []geolocation.Network) *Table {
+	t := &Table{networks: map[int]map[netip.Prefix]geolocation.Network{}}
+	for _, network := range networks {
+		network.Prefix = unmapPrefix(network.Prefix).Masked()
+		bits := network.Prefix.Bits()
+		if t.networks[bits] == nil {
+			t.networks[bits] = map[netip.Prefix]geolocation.Network{}
+			t.bits = append(t.bits, bits)
+		}
+		if _, ok := t.networks[bits][network.Prefix]; !ok {
+			t.size++
+		}
+		t.networks[bits][network.Prefix] = network
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(t.bits)))
+	return t
+}
+
+// unmapPrefix turns IPv4-mapped IPv6 prefixes like ::ffff:1.1.1.0/120 into IPv4 ones
+func unmapPrefix(prefix netip.Prefix) netip.Prefix {
+	if addr := prefix.Addr(); addr.Is4In6() && prefix.Bits() >= 96 {
+		return netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+	}
+	return prefix
+}
+
+// Len returns the number of networks
+func (t *Table) Len() int {
+	return t.size
+}
+
+// Lookup returns the most specific network containing ip
+func (t *Table) Lookup(ip net.IP) (network geolocation.Network, ok bool) {
+	addr, valid := netip.AddrFromSlice(ip)
+	if !valid {
+		return
+	}
+	addr = addr.Unmap()
+
+	for _, bits := range t.bits {
+		// Prefix fails for IPv6 lengths longer than IPv4 addresses
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if network, ok = t.networks[bits][prefix]; ok {
+			return
+		}
+	}
+	return
+}
+
+// LoadCSV reads networks from a CSV with the header network,asn,org. Networks are CIDRs or single IP addresses,
+// autonomous system numbers may start with "AS".
+func LoadCSV(r io.Reader) (table *Table, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.ReuseRecord = true
+
+	if _, err = reader.Read(); err != nil {
+		return
+	}
+
+	var networks []geolocation.Network
+	for line := 2; ; line++ {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			err = readErr
+			return
+		}
+
+		var network geolocation.Network
+		if network, err = parseNetwork(record); err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			return
+		}
+		networks = append(networks, network)
+	}
+	table = New(networks)
+	return
+}
+
+// parseNetwork parses a network,asn,org record
+func parseNetwork(record []string) (network geolocation.Network, err error) {
+	value := strings.TrimSpace(record[0])
+	if strings.Contains(value, "/") {
+		network.Prefix, err = netip.ParsePrefix(value)
+	} else {
+		var addr netip.Addr
+		if addr, err = netip.ParseAddr(value); err == nil {
+			network.Prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	number := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(record[1])), "AS")
+	asn, err := strconv.ParseUint(number, 10, 32)
+	if err != nil {
+		return
+	}
+	network.ASN = uint32(asn)
+	network.Organization = strings.TrimSpace(record[2])
+	return
+}
@@ -0,0 +1,72 @@
+package asn
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+const networksCSV = `network,asn,org
+1.0.0.0/8,AS64500,Eight
+1.1.0.0/16,64501,Sixteen
+1.1.1.0/24,as13335,Cloudflare
+1.1.1.1,13335,Single
+2001:db8::/32,64502,Documentation
+`
+
+func TestTable_Lookup(t *testing.T) {
+	table, err := LoadCSV(strings.NewReader(networksCSV))
+	if err != nil {
+		t.Fatalf("LoadCSV() error = %v", err)
+	}
+	if table.Len() != 5 {
+		t.Fatalf("Len() = %d, want 5", table.Len())
+	}
+
+	tests := []struct {
+		ip       string
+		wantASN  uint32
+		wantOrg  string
+		wantNone bool
+	}{
+		{ip: "1.1.1.1", wantASN: 13335, wantOrg: "Single"},
+		{ip: "1.1.1.2", wantASN: 13335, wantOrg: "Cloudflare"},
+		{ip: "::ffff:1.1.1.2", wantASN: 13335, wantOrg: "Cloudflare"},
+		{ip: "1.1.2.1", wantASN: 64501, wantOrg: "Sixteen"},
+		{ip: "1.2.3.4", wantASN: 64500, wantOrg: "Eight"},
+		{ip: "2001:db8:1::1", wantASN: 64502, wantOrg: "Documentation"},
+		{ip: "8.8.8.8", wantNone: true},
+		{ip: "2001:db9::1", wantNone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			network, ok := table.Lookup(net.ParseIP(tt.ip))
+			if ok == tt.wantNone {
+				t.Fatalf("Lookup() = %+v, %v, want none %v", network, ok, tt.wantNone)
+			}
+			if network.ASN != tt.wantASN || network.Organization != tt.wantOrg {
+				t.Errorf("Lookup() = %+v, want AS%d %s", network, tt.wantASN, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr bool
+	}{
+		{name: "Valid", csv: "network,asn,org\n10.0.0.0/8,1,Private\n"},
+		{name: "InvalidNetwork", csv: "network,asn,org\n10.0.0/8,1,Private\n", wantErr: true},
+		{name: "InvalidASN", csv: "network,asn,org\n10.0.0.0/8,ASX,Private\n", wantErr: true},
+		{name: "MissingColumn", csv: "network,asn,org\n10.0.0.0/8,1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadCSV(strings.NewReader(tt.csv)); (err != nil) != tt.wantErr {
+				t.Errorf("LoadCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"testing"
+)
+
+const asnCSV = `network,asn,org
+160.103.0.0/16,64500,Example Transit
+160.103.7.0/24,64501,Example Hosting
+`
+
+func TestGeoService_RetrieveLocationNetwork(t *testing.T) {
+	db := memory.New()
+	g := NewGeoService(db)
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if networks, err := g.ImportASN(strings.NewReader(asnCSV)); err != nil || networks != 2 {
+		t.Fatalf("ImportASN() = %d, error = %v", networks, err)
+	}
+
+	location, err := g.RetrieveLocation(net.ParseIP("160.103.7.140"))
+	if err != nil {
+		t.Fatalf("RetrieveLocation() error = %v", err)
+	}
+	if location.City != "New Neva" || location.Network == nil || location.Network.ASN != 64501 || location.Network.Organization != "Example Hosting" {
+		t.Fatalf("RetrieveLocation() = %+v, network %+v, want AS64501", location, location.Network)
+	}
+	if stored, _ := db.Retrieve(net.ParseIP("160.103.7.140")); stored.Network != nil {
+		t.Errorf("RetrieveLocation() modified the stored location")
+	}
+
+	if location, err = g.RetrieveLocation(net.ParseIP("70.95.73.73")); err != nil || location.Network != nil {
+		t.Errorf("RetrieveLocation() = %+v, error = %v, want no network", location, err)
+	}
+
+	// an invalid CSV keeps the previous networks
+	if _, err = g.ImportASN(strings.NewReader("network,asn,org\ninvalid,1,Broken\n")); err == nil {
+		t.Fatalf("ImportASN() error = nil, want an error")
+	}
+	if network, err := g.Network(net.ParseIP("160.103.1.1")); err != nil || network.ASN != 64500 {
+		t.Errorf("Network() = %+v, error = %v, want AS64500", network, err)
+	}
+}
+
+func TestGeoService_Network(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       *GeoService
+		ip      net.IP
+		wantErr error
+	}{
+		{name: "NoData", g: NewGeoService(memory.New()), ip: net.ParseIP("160.103.7.140"), wantErr: geolocation.ErrNotFound},
+		{name: "InvalidIP", g: NewGeoService(memory.New()), ip: net.IP{1, 2}, wantErr: ErrInvalidIP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.g.Network(tt.ip); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Network() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package geoservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"sync"
+	"time"
+)
+
+const DefaultChunkSize = 1000
+
+var (
+	ErrIncompleteStore         = errors.New("incomplete_store")
+	ErrTransactionsUnsupported = geolocation.ErrTransactionsUnsupported
+	ErrRolledBack              = errors.New("rolled_back")
+)
+
+// BatchOptions configures how StoreLocationsBatch writes locations to the Repository
+type BatchOptions struct {
+	// ChunkSize is the number of locations handed to a single StoreMany call, defaults to DefaultChunkSize
+	ChunkSize int
+	// Writers is the number of goroutines writing chunks concurrently, defaults to 1.
+	// It is ignored when Transactional is set since a transaction is written sequentially.
+	Writers int
+	// Transactional stores every chunk inside a single transaction so either all locations land or none does.
+	// The Repository has to implement geolocation.Transactor.
+	Transactional bool
+}
+
+// FailedLocation is a location that could not be stored along with the reason
+type FailedLocation struct {
+	Location *geolocation.GeoLocation
+	Err      error
+}
+
+// BatchResult describes which locations were stored and which failed
+type BatchResult struct {
+	Stored  []*geolocation.GeoLocation
+	Failed  []FailedLocation
+	Chunks  int
+	Elapsed time.Duration
+}
+
+// chunkResult is the outcome of writing a single chunk
+type chunkResult struct {
+	stored  []*geolocation.GeoLocation
+	failed  []FailedLocation
+	elapsed time.Duration
+}
+
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultChunkSize
+	}
+	if o.Writers <= 0 {
+		o.Writers = 1
+	}
+	return o
+}
+
+// splitChunks splits locations into slices of at most size locations
+func splitChunks(locations []*geolocation.GeoLocation, size int) (chunks [][]*geolocation.GeoLocation) {
+	for first := 0; first < len(locations); first += size {
+		last := first + size
+		if last > len(locations) {
+			last = len(locations)
+		}
+		chunks = append(chunks, locations[first:last])
+	}
+	return
+}
+
+// chunkFailures maps the index of every location of a chunk that wasn't stored to its error based on the error
+// returned by StoreMany. A geolocation.BatchError pinpoints the failed locations, any other error fails the whole chunk.
+func chunkFailures(chunk []*geolocation.GeoLocation, err error) (failures map[int]error) {
+	failures = map[int]error{}
+	if err == nil {
+		return
+	}
+
+	var batchErr *geolocation.BatchError
+	if !errors.As(err, &batchErr) {
+		for index := range chunk {
+			failures[index] = err
+		}
+		return
+	}
+
+	for index, locErr := range batchErr.Errors {
+		if index >= 0 && index < len(chunk) {
+			failures[index] = locErr
+		}
+	}
+	return
+}
+
+// classifyChunk separates stored from failed locations of a chunk based on the error returned by StoreMany
+func classifyChunk(chunk []*geolocation.GeoLocation, err error) (result chunkResult) {
+	failures := chunkFailures(chunk, err)
+	for index, location := range chunk {
+		if locErr, ok := failures[index]; ok {
+			result.failed = append(result.failed, FailedLocation{Location: location, Err: locErr})
+			continue
+		}
+		result.stored = append(result.stored, location)
+	}
+	return
+}
+
+// mergeChunks flattens chunk results in chunk order
+func mergeChunks(results []chunkResult) (result *BatchResult) {
+	result = &BatchResult{Chunks: len(results)}
+	for _, chunk := range results {
+		result.Stored = append(result.Stored, chunk.stored...)
+		result.Failed = append(result.Failed, chunk.failed...)
+	}
+	return
+}
+
+// incompleteError summarizes failed locations of a result, nil if every location was stored
+func incompleteError(result *BatchResult) error {
+	if len(result.Failed) == 0 {
+		return nil
+	}
+	total := len(result.Stored) + len(result.Failed)
+	return fmt.Errorf("%w: %d of %d locations failed, first: %v", ErrIncompleteStore, len(result.Failed), total, result.Failed[0].Err)
+}
+
+// storeChunks writes chunks to the repository using the given number of concurrent writers
+func storeChunks(db geolocation.Repository, chunks [][]*geolocation.GeoLocation, writers int) (results []chunkResult) {
+	results = make([]chunkResult, len(chunks))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				begin := time.Now()
+				results[index] = classifyChunk(chunks[index], db.StoreMany(chunks[index]))
+				results[index].elapsed = time.Now().Sub(begin)
+			}
+		}()
+	}
+
+	for index := range chunks {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return
+}
+
+// storeChunksTx writes chunks sequentially inside a single transaction.
+// When a chunk fails the transaction is rolled back and every location is reported as failed.
+func storeChunksTx(db geolocation.Repository, chunks [][]*geolocation.GeoLocation) (results []chunkResult, err error) {
+	transactor, ok := geolocation.As[geolocation.Transactor](db)
+	if !ok {
+		err = ErrTransactionsUnsupported
+		return
+	}
+
+	var tx geolocation.Transaction
+	tx, err = transactor.Begin()
+	if err != nil {
+		return
+	}
+
+	results = make([]chunkResult, len(chunks))
+
+	failedIndex := -1
+	var txErr error
+	for index, chunk := range chunks {
+		begin := time.Now()
+		results[index] = classifyChunk(chunk, tx.StoreMany(chunk))
+		results[index].elapsed = time.Now().Sub(begin)
+		if len(results[index].failed) > 0 {
+			failedIndex = index
+			txErr = results[index].failed[0].Err
+			break
+		}
+	}
+
+	if failedIndex == -1 {
+		if txErr = tx.Commit(); txErr == nil {
+			return
+		}
+	} else {
+		tx.Rollback()
+	}
+
+	for index, chunk := range chunks {
+		if index == failedIndex {
+			// Keep the real errors of the failing chunk, the rest of it was rolled back
+			result := chunkResult{failed: results[index].failed, elapsed: results[index].elapsed}
+			for _, location := range results[index].stored {
+				result.failed = append(result.failed, FailedLocation{Location: location, Err: ErrRolledBack})
+			}
+			results[index] = result
+			continue
+		}
+
+		reason := ErrRolledBack
+		if failedIndex == -1 {
+			reason = txErr
+		}
+		results[index] = chunkResult{elapsed: results[index].elapsed}
+		for _, location := range chunk {
+			results[index].failed = append(results[index].failed, FailedLocation{Location: location, Err: reason})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,157 @@
+package geoservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"testing"
+)
+
+// nonTxDB hides the Transactor implementation of testDB
+type nonTxDB struct {
+	geolocation.Repository
+}
+
+func generateLocations(count int) (locations []*geolocation.GeoLocation) {
+	for i := 0; i < count; i++ {
+		locations = append(locations, &geolocation.GeoLocation{
+			IPAddress:   net.ParseIP(fmt.Sprintf("10.0.%d.%d", i/256, i%256)),
+			CountryCode: "CZ",
+			Country:     "Nicaragua",
+			City:        "New Neva",
+		})
+	}
+	return
+}
+
+func TestGeoService_StoreLocationsBatch(t *testing.T) {
+	locations := generateLocations(25)
+
+	tests := []struct {
+		name        string
+		db          func() geolocation.Repository
+		opts        BatchOptions
+		wantStored  int
+		wantFailed  int
+		wantChunks  int
+		wantErr     error
+		wantInStore int
+	}{
+		{
+			name:        "Chunked",
+			db:          func() geolocation.Repository { return newTestDB() },
+			opts:        BatchOptions{ChunkSize: 10, Writers: 3},
+			wantStored:  25,
+			wantChunks:  3,
+			wantInStore: 25,
+		},
+		{
+			name: "PartialFailure",
+			db: func() geolocation.Repository {
+				db := newTestDB()
+				db.Store(locations[3])
+				db.Store(locations[17])
+				return db
+			},
+			opts:        BatchOptions{ChunkSize: 10, Writers: 2},
+			wantStored:  23,
+			wantFailed:  2,
+			wantChunks:  3,
+			wantErr:     ErrIncompleteStore,
+			wantInStore: 25,
+		},
+		{
+			name: "TransactionalRollback",
+			db: func() geolocation.Repository {
+				db := newTestDB()
+				db.Store(locations[17])
+				return db
+			},
+			opts:        BatchOptions{ChunkSize: 10, Transactional: true},
+			wantFailed:  25,
+			wantChunks:  3,
+			wantErr:     ErrIncompleteStore,
+			wantInStore: 1,
+		},
+		{
+			name:        "TransactionalCommit",
+			db:          func() geolocation.Repository { return newTestDB() },
+			opts:        BatchOptions{ChunkSize: 10, Transactional: true},
+			wantStored:  25,
+			wantChunks:  3,
+			wantInStore: 25,
+		},
+		{
+			name:    "TransactionsUnsupported",
+			db:      func() geolocation.Repository { return nonTxDB{newTestDB()} },
+			opts:    BatchOptions{Transactional: true},
+			wantErr: ErrTransactionsUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := tt.db()
+			g := NewGeoService(db)
+			result, err := g.StoreLocationsBatch(locations, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("StoreLocationsBatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if result == nil {
+				return
+			}
+			if len(result.Stored) != tt.wantStored || len(result.Failed) != tt.wantFailed || result.Chunks != tt.wantChunks {
+				t.Errorf("StoreLocationsBatch() stored = %d, failed = %d, chunks = %d, want %d, %d, %d",
+					len(result.Stored), len(result.Failed), result.Chunks, tt.wantStored, tt.wantFailed, tt.wantChunks)
+			}
+
+			var inStore int
+			for _, location := range locations {
+				if _, retrieveErr := db.Retrieve(location.IPAddress); retrieveErr == nil {
+					inStore++
+				}
+			}
+			if inStore != tt.wantInStore {
+				t.Errorf("StoreLocationsBatch() locations in store = %d, want %d", inStore, tt.wantInStore)
+			}
+		})
+	}
+}
+
+func TestGeoService_StoreLocationsContinuesOnError(t *testing.T) {
+	locations := generateLocations(5)
+
+	db := newTestDB()
+	db.Store(locations[1])
+
+	g := NewGeoService(db)
+	result, err := g.StoreLocationsResult(locations)
+	if !errors.Is(err, ErrIncompleteStore) {
+		t.Errorf("StoreLocationsResult() error = %v, want %v", err, ErrIncompleteStore)
+	}
+	if len(result.Stored) != 4 || len(result.Failed) != 1 || result.Failed[0].Location != locations[1] {
+		t.Errorf("StoreLocationsResult() stored = %d, failed = %v", len(result.Stored), result.Failed)
+	}
+
+	// StoreLocations keeps its signature and only reports the error
+	if err = NewGeoService(newTestDB()).StoreLocations(locations[:1]); err != nil {
+		t.Errorf("StoreLocations() error = %v", err)
+	}
+	if err = g.StoreLocations(locations); !errors.Is(err, ErrIncompleteStore) {
+		t.Errorf("StoreLocations() error = %v, want %v", err, ErrIncompleteStore)
+	}
+}
+
+func TestGeoService_StoreLocationsWrites(t *testing.T) {
+	g := NewGeoService(newTestDB())
+
+	g.StoreLocations(generateLocations(3))
+	if writes := g.writes.Load(); writes != 1 {
+		t.Errorf("StoreLocations() counted %d writes, want 1", writes)
+	}
+	g.StoreLocationsBatch(generateLocations(5)[3:], BatchOptions{ChunkSize: 1, Writers: 2})
+	if writes := g.writes.Load(); writes != 2 {
+		t.Errorf("StoreLocationsBatch() counted %d writes, want 2 in total", writes)
+	}
+}
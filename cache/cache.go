@@ -0,0 +1,310 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"sync"
+	"time"
+)
+
+// entryOverhead is a rough estimate of the memory used by an entry besides its strings and IP address
+const entryOverhead = 160
+
+// Options bounds the cache, a zero value means unlimited for MaxEntries, MaxBytes and TTL
+type Options struct {
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+	// NegativeTTL caches not found IP addresses for the given duration, zero disables negative caching
+	NegativeTTL time.Duration
+}
+
+// Stats are the cache metrics since it was created
+type Stats struct {
+	Hits         int64
+	NegativeHits int64
+	Misses       int64
+	Evictions    int64
+	Entries      int
+	Bytes        int64
+}
+
+type entry struct {
+	key string
+	// location is nil for negative entries
+	location *geolocation.GeoLocation
+	expires  time.Time
+	size     int64
+}
+
+// Repository is a read-through LRU cache in front of any geolocation.Repository.
+// Writes pass through to the wrapped repository and invalidate the cached IP addresses. Every optional capability of
+// the geolocation package is forwarded to the wrapped repository, returning geolocation.ErrUnsupported when it lacks it.
+// It is a geolocation.Wrapper, geolocation.As reports the capabilities of the wrapped repository.
+type Repository struct {
+	sync.Mutex
+	db      geolocation.Repository
+	opts    Options
+	lru     *list.List
+	entries map[string]*list.Element
+	stats   Stats
+	now     func() time.Time
+	// generation changes on every invalidation so a Retrieve racing with a write doesn't cache a stale result
+	generation uint64
+}
+
+func New(db geolocation.Repository, opts Options) *Repository {
+	return &Repository{
+		db:      db,
+		opts:    opts,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		now:     time.Now,
+	}
+}
+
+// Unwrap returns the wrapped repository
+func (r *Repository) Unwrap() geolocation.Repository {
+	return r.db
+}
+
+func entrySize(key string, location *geolocation.GeoLocation) (size int64) {
+	size = entryOverhead + int64(len(key))
+	if location != nil {
+		size += int64(len(location.IPAddress) + len(location.CountryCode) + len(location.Country) + len(location.City))
+	}
+	return
+}
+
+func (r *Repository) Retrieve(ip net.IP) (location *geolocation.GeoLocation, err error) {
+	key := ip.String()
+
+	r.Lock()
+	if element, ok := r.entries[key]; ok {
+		e := element.Value.(*entry)
+		if e.expires.IsZero() || r.now().Before(e.expires) {
+			r.lru.MoveToFront(element)
+			if e.location == nil {
+				r.stats.NegativeHits++
+				r.Unlock()
+				err = geolocation.ErrNotFound
+				return
+			}
+			r.stats.Hits++
+			r.Unlock()
+			location = e.location
+			return
+		}
+		r.removeElement(element)
+	}
+	r.stats.Misses++
+	generation := r.generation
+	r.Unlock()
+
+	location, err = r.db.Retrieve(ip)
+	switch {
+	case err == nil:
+		r.add(generation, key, location, r.opts.TTL)
+	case errors.Is(err, geolocation.ErrNotFound) && r.opts.NegativeTTL > 0:
+		r.add(generation, key, nil, r.opts.NegativeTTL)
+	}
+	return
+}
+
+func (r *Repository) Store(location *geolocation.GeoLocation) (err error) {
+	err = r.db.Store(location)
+	r.Invalidate(location.IPAddress)
+	return
+}
+
+func (r *Repository) StoreMany(locations []*geolocation.GeoLocation) (err error) {
+	err = r.db.StoreMany(locations)
+	r.invalidateLocations(locations)
+	return
+}
+
+// Upsert passes through to the wrapped repository if it implements geolocation.Upserter
+func (r *Repository) Upsert(location *geolocation.GeoLocation) (err error) {
+	upserter, ok := r.db.(geolocation.Upserter)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	err = upserter.Upsert(location)
+	r.Invalidate(location.IPAddress)
+	return
+}
+
+// Delete passes through to the wrapped repository if it implements geolocation.Deleter
+func (r *Repository) Delete(ip net.IP) (err error) {
+	deleter, ok := r.db.(geolocation.Deleter)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	err = deleter.Delete(ip)
+	r.Invalidate(ip)
+	return
+}
+
+// Begin passes through to the wrapped repository if it implements geolocation.Transactor,
+// cached IP addresses are invalidated when the transaction commits
+func (r *Repository) Begin() (tx geolocation.Transaction, err error) {
+	transactor, ok := r.db.(geolocation.Transactor)
+	if !ok {
+		err = geolocation.ErrTransactionsUnsupported
+		return
+	}
+
+	var inner geolocation.Transaction
+	if inner, err = transactor.Begin(); err != nil {
+		return
+	}
+	tx = &transaction{Transaction: inner, cache: r}
+	return
+}
+
+// Each passes through to the wrapped repository if it implements geolocation.Iterable
+func (r *Repository) Each(fn func(*geolocation.GeoLocation) bool) error {
+	iterable, ok := r.db.(geolocation.Iterable)
+	if !ok {
+		return geolocation.ErrUnsupported
+	}
+	return iterable.Each(fn)
+}
+
+// FindBy passes through to the wrapped repository if it implements geolocation.Indexer
+func (r *Repository) FindBy(field geolocation.IndexField, key string, page geolocation.Page) (locations []*geolocation.GeoLocation, total int, err error) {
+	indexer, ok := r.db.(geolocation.Indexer)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	return indexer.FindBy(field, key, page)
+}
+
+// CountBy passes through to the wrapped repository if it implements geolocation.Indexer
+func (r *Repository) CountBy(field geolocation.IndexField) (counts []geolocation.KeyCount, err error) {
+	indexer, ok := r.db.(geolocation.Indexer)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	return indexer.CountBy(field)
+}
+
+// SearchBox passes through to the wrapped repository if it implements geolocation.RegionSearcher
+func (r *Repository) SearchBox(box geolocation.BoundingBox) (locations []*geolocation.GeoLocation, err error) {
+	searcher, ok := r.db.(geolocation.RegionSearcher)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	return searcher.SearchBox(box)
+}
+
+// Invalidate drops the cached entry of an IP address
+func (r *Repository) Invalidate(ip net.IP) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.generation++
+	if element, ok := r.entries[ip.String()]; ok {
+		r.removeElement(element)
+	}
+}
+
+// Purge drops every cached entry
+func (r *Repository) Purge() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.generation++
+	r.lru.Init()
+	r.entries = map[string]*list.Element{}
+	r.stats.Entries = 0
+	r.stats.Bytes = 0
+}
+
+func (r *Repository) Stats() Stats {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stats
+}
+
+func (r *Repository) invalidateLocations(locations []*geolocation.GeoLocation) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.generation++
+	for _, location := range locations {
+		if element, ok := r.entries[location.IPAddress.String()]; ok {
+			r.removeElement(element)
+		}
+	}
+}
+
+func (r *Repository) add(generation uint64, key string, location *geolocation.GeoLocation, ttl time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	if element, ok := r.entries[key]; ok {
+		r.removeElement(element)
+	}
+
+	e := &entry{key: key, location: location, size: entrySize(key, location)}
+	if ttl > 0 {
+		e.expires = r.now().Add(ttl)
+	}
+	r.entries[key] = r.lru.PushFront(e)
+	r.stats.Entries++
+	r.stats.Bytes += e.size
+
+	for r.overLimit() {
+		r.removeElement(r.lru.Back())
+		r.stats.Evictions++
+	}
+}
+
+func (r *Repository) overLimit() bool {
+	if r.lru.Len() == 0 {
+		return false
+	}
+	if r.opts.MaxEntries > 0 && r.stats.Entries > r.opts.MaxEntries {
+		return true
+	}
+	return r.opts.MaxBytes > 0 && r.stats.Bytes > r.opts.MaxBytes
+}
+
+func (r *Repository) removeElement(element *list.Element) {
+	e := r.lru.Remove(element).(*entry)
+	delete(r.entries, e.key)
+	r.stats.Entries--
+	r.stats.Bytes -= e.size
+}
+
+// transaction invalidates the stored IP addresses once committed
+type transaction struct {
+	geolocation.Transaction
+	cache     *Repository
+	locations []*geolocation.GeoLocation
+}
+
+func (t *transaction) StoreMany(locations []*geolocation.GeoLocation) (err error) {
+	err = t.Transaction.StoreMany(locations)
+	t.locations = append(t.locations, locations...)
+	return
+}
+
+func (t *transaction) Commit() (err error) {
+	err = t.Transaction.Commit()
+	t.cache.invalidateLocations(t.locations)
+	return
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapDB counts Retrieve calls to tell cache hits from repository reads
+type mapDB struct {
+	sync.Mutex
+	data      map[string]*geolocation.GeoLocation
+	retrieves int
+}
+
+func newMapDB() *mapDB {
+	return &mapDB{data: map[string]*geolocation.GeoLocation{}}
+}
+
+func (m *mapDB) Store(g *geolocation.GeoLocation) error {
+	m.Lock()
+	defer m.Unlock()
+	m.data[g.IPAddress.String()] = g
+	return nil
+}
+
+func (m *mapDB) StoreMany(gs []*geolocation.GeoLocation) error {
+	for _, g := range gs {
+		m.Store(g)
+	}
+	return nil
+}
+
+func (m *mapDB) Retrieve(ip net.IP) (*geolocation.GeoLocation, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.retrieves++
+	if g := m.data[ip.String()]; g != nil {
+		return g, nil
+	}
+	return nil, geolocation.ErrNotFound
+}
+
+func (m *mapDB) Delete(ip net.IP) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.data, ip.String())
+	return nil
+}
+
+func location(ip string) *geolocation.GeoLocation {
+	return &geolocation.GeoLocation{IPAddress: net.ParseIP(ip), CountryCode: "CZ", Country: "Nicaragua", City: "New Neva"}
+}
+
+func TestRepository_Retrieve(t *testing.T) {
+	db := newMapDB()
+	db.Store(location("200.106.141.15"))
+
+	c := New(db, Options{NegativeTTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		got, err := c.Retrieve(net.ParseIP("200.106.141.15"))
+		if err != nil || got.City != "New Neva" {
+			t.Fatalf("Retrieve() got = %v, error = %v", got, err)
+		}
+		if _, err = c.Retrieve(net.ParseIP("160.103.7.140")); !errors.Is(err, geolocation.ErrNotFound) {
+			t.Fatalf("Retrieve() error = %v, want %v", err, geolocation.ErrNotFound)
+		}
+	}
+
+	stats := c.Stats()
+	if db.retrieves != 2 || stats.Hits != 2 || stats.NegativeHits != 2 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Retrieve() repository reads = %d, stats = %+v", db.retrieves, stats)
+	}
+
+	// Storing the not found IP address invalidates its negative entry
+	if err := c.Store(location("160.103.7.140")); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := c.Retrieve(net.ParseIP("160.103.7.140")); err != nil {
+		t.Errorf("Retrieve() after Store() error = %v", err)
+	}
+
+	if err := c.Delete(net.ParseIP("200.106.141.15")); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := c.Retrieve(net.ParseIP("200.106.141.15")); !errors.Is(err, geolocation.ErrNotFound) {
+		t.Errorf("Retrieve() after Delete() error = %v, want %v", err, geolocation.ErrNotFound)
+	}
+
+	if err := c.Upsert(location("200.106.141.15")); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("Upsert() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+}
+
+func TestRepository_Limits(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantEntries int
+		wantEvicted int64
+	}{
+		{
+			name:        "MaxEntries",
+			opts:        Options{MaxEntries: 2},
+			wantEntries: 2,
+			wantEvicted: 2,
+		},
+		{
+			name:        "MaxBytes",
+			opts:        Options{MaxBytes: 3 * entrySize("10.0.0.1", location("10.0.0.1"))},
+			wantEntries: 3,
+			wantEvicted: 1,
+		},
+		{
+			name:        "Unlimited",
+			wantEntries: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMapDB()
+			c := New(db, tt.opts)
+			for _, ip := range ips {
+				db.Store(location(ip))
+				c.Retrieve(net.ParseIP(ip))
+			}
+
+			stats := c.Stats()
+			if stats.Entries != tt.wantEntries || stats.Evictions != tt.wantEvicted {
+				t.Errorf("Stats() = %+v, want %d entries and %d evictions", stats, tt.wantEntries, tt.wantEvicted)
+			}
+
+			// The most recently used IP address is always kept
+			retrieves := db.retrieves
+			c.Retrieve(net.ParseIP(ips[len(ips)-1]))
+			if db.retrieves != retrieves {
+				t.Errorf("Retrieve() of the most recent IP address hit the repository")
+			}
+		})
+	}
+}
+
+func TestRepository_TTL(t *testing.T) {
+	db := newMapDB()
+	db.Store(location("10.0.0.1"))
+
+	now := time.Now()
+	c := New(db, Options{TTL: time.Minute})
+	c.now = func() time.Time { return now }
+
+	c.Retrieve(net.ParseIP("10.0.0.1"))
+	c.Retrieve(net.ParseIP("10.0.0.1"))
+	if db.retrieves != 1 {
+		t.Fatalf("Retrieve() repository reads = %d before expiry, want 1", db.retrieves)
+	}
+
+	now = now.Add(2 * time.Minute)
+	c.Retrieve(net.ParseIP("10.0.0.1"))
+	if db.retrieves != 2 {
+		t.Errorf("Retrieve() repository reads = %d after expiry, want 2", db.retrieves)
+	}
+}
+
+func TestRepository_Capabilities(t *testing.T) {
+	box := geolocation.BoundingBox{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180}
+
+	unsupported := New(newMapDB(), Options{})
+	if err := unsupported.Each(func(*geolocation.GeoLocation) bool { return true }); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("Each() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+	if _, _, err := unsupported.FindBy(geolocation.FieldCity, "New Neva", geolocation.Page{}); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("FindBy() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+	if _, err := unsupported.CountBy(geolocation.FieldCity); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("CountBy() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+	if _, err := unsupported.SearchBox(box); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("SearchBox() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+	if _, err := unsupported.Begin(); !errors.Is(err, geolocation.ErrTransactionsUnsupported) {
+		t.Errorf("Begin() error = %v, want %v", err, geolocation.ErrTransactionsUnsupported)
+	}
+
+	// geolocation.As reports the capabilities of the wrapped repository, mapDB can delete but not upsert
+	if _, ok := geolocation.As[geolocation.Upserter](unsupported); ok {
+		t.Errorf("As[Upserter]() ok = true for a repository without Upsert")
+	}
+	if _, ok := geolocation.As[geolocation.Iterable](New(unsupported, Options{})); ok {
+		t.Errorf("As[Iterable]() ok = true through two caches of a repository without Each")
+	}
+	if deleter, ok := geolocation.As[geolocation.Deleter](unsupported); !ok || deleter != geolocation.Deleter(unsupported) {
+		t.Errorf("As[Deleter]() = %v, %v, want the cache", deleter, ok)
+	}
+
+	db := memory.New()
+	db.Store(location("10.0.0.1"))
+	c := New(db, Options{})
+
+	var each int
+	if err := c.Each(func(*geolocation.GeoLocation) bool { each++; return true }); err != nil || each != 1 {
+		t.Errorf("Each() error = %v, listed %d locations, want 1", err, each)
+	}
+	if locations, total, err := c.FindBy(geolocation.FieldCity, "new neva", geolocation.Page{}); err != nil || total != 1 || len(locations) != 1 {
+		t.Errorf("FindBy() = %v, total = %d, error = %v, want 1 location", locations, total, err)
+	}
+	if counts, err := c.CountBy(geolocation.FieldCountryCode); err != nil || len(counts) != 1 || counts[0].Count != 1 {
+		t.Errorf("CountBy() = %v, error = %v, want a single key counted once", counts, err)
+	}
+
+	// A committed transaction invalidates the negative entry of its IP address
+	c.opts.NegativeTTL = time.Minute
+	if _, err := c.Retrieve(net.ParseIP("10.0.0.2")); !errors.Is(err, geolocation.ErrNotFound) {
+		t.Fatalf("Retrieve() error = %v, want %v", err, geolocation.ErrNotFound)
+	}
+	tx, err := c.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.2")})
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if _, err = c.Retrieve(net.ParseIP("10.0.0.2")); err != nil {
+		t.Errorf("Retrieve() after Commit() error = %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package geoservice
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const DefaultCheckpointEvery = 100000
+
+var ErrCheckpointMismatch = errors.New("checkpoint_mismatch")
+
+// Checkpoint is the persisted progress of an import.
+// Every row up to Line (ending at byte Offset) was parsed, deduplicated and stored or rejected.
+// Rows after Line up to UntilLine may have been handed to the Repository before the import stopped,
+// a resumed import checks them with Retrieve before storing them again.
+type Checkpoint struct {
+	Offset       int64     `json:"offset"`
+	Line         int       `json:"line"`
+	UntilLine    int       `json:"until_line"`
+	DedupeDigest string    `json:"dedupe_digest"`
+	Stored       int       `json:"stored"`
+	Failed       int       `json:"failed"`
+	Duplicates   int       `json:"duplicates"`
+	Discarded    int       `json:"discarded"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CheckpointStore persists import checkpoints, Load returns a nil Checkpoint if nothing was saved yet
+type CheckpointStore interface {
+	Save(*Checkpoint) error
+	Load() (*Checkpoint, error)
+}
+
+// FileCheckpointStore saves checkpoints as JSON to a file, replacing it atomically on every save
+type FileCheckpointStore struct {
+	path string
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (f *FileCheckpointStore) Save(checkpoint *Checkpoint) (err error) {
+	var data []byte
+	data, err = json.Marshal(checkpoint)
+	if err != nil {
+		return
+	}
+
+	var file *os.File
+	file, err = os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return
+	}
+	if err = file.Close(); err != nil {
+		return
+	}
+
+	err = os.Rename(file.Name(), f.path)
+	return
+}
+
+func (f *FileCheckpointStore) Load() (checkpoint *Checkpoint, err error) {
+	var data []byte
+	data, err = os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+
+	checkpoint = &Checkpoint{}
+	err = json.Unmarshal(data, checkpoint)
+	return
+}
+
+// rowOutcome is what happened to a row once it left the pipeline
+type rowOutcome int
+
+const (
+	rowDiscarded rowOutcome = iota
+	rowDuplicate
+	rowStored
+	rowFailed
+)
+
+// rowProgress is the position of a row in the source along with the hash of its IP address
+type rowProgress struct {
+	seq  int64
+	line int
+	end  int64
+	// hash is 0 for rows which don't parse, excluded rows and rows rejected by an enricher have one
+	hash uint64
+}
+
+// importTally counts row outcomes, digest is an order independent hash of the IP address of every row that parses
+type importTally struct {
+	stored, failed, duplicates, discarded int
+	digest                                uint64
+}
+
+func (t *importTally) add(row rowProgress, outcome rowOutcome) {
+	t.digest += row.hash
+	switch outcome {
+	case rowDiscarded:
+		t.discarded++
+	case rowDuplicate:
+		t.duplicates++
+	case rowStored:
+		t.stored++
+	case rowFailed:
+		t.failed++
+	}
+}
+
+func hashIP(location *geolocation.GeoLocation) uint64 {
+	h := fnv.New64a()
+	h.Write(location.IPAddress.To16())
+	return h.Sum64()
+}
+
+func formatDigest(digest uint64) string {
+	return fmt.Sprintf("%016x", digest)
+}
+
+type pendingRow struct {
+	row     rowProgress
+	outcome rowOutcome
+}
+
+// progressTracker follows rows completing out of order and keeps the contiguous prefix of completed rows,
+// which is the only safe position to resume from
+type progressTracker struct {
+	sync.Mutex
+	next    int64
+	offset  int64
+	line    int
+	pending map[int64]pendingRow
+	// committed tallies rows before next, running tallies every completed row
+	committed importTally
+	running   importTally
+}
+
+func newProgressTracker(checkpoint *Checkpoint) (p *progressTracker) {
+	p = &progressTracker{pending: map[int64]pendingRow{}}
+	if checkpoint == nil {
+		return
+	}
+
+	if checkpoint.Line > 1 {
+		p.next = int64(checkpoint.Line - 1)
+	}
+	p.offset = checkpoint.Offset
+	p.line = checkpoint.Line
+	p.committed = importTally{
+		stored:     checkpoint.Stored,
+		failed:     checkpoint.Failed,
+		duplicates: checkpoint.Duplicates,
+		discarded:  checkpoint.Discarded,
+	}
+	fmt.Sscanf(checkpoint.DedupeDigest, "%x", &p.committed.digest)
+	p.running = p.committed
+	return
+}
+
+func (p *progressTracker) complete(row rowProgress, outcome rowOutcome) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.completeLocked(row, outcome)
+}
+
+func (p *progressTracker) completeLocked(row rowProgress, outcome rowOutcome) {
+	p.running.add(row, outcome)
+
+	if row.seq != p.next {
+		p.pending[row.seq] = pendingRow{row: row, outcome: outcome}
+		return
+	}
+
+	p.advance(row, outcome)
+	for {
+		pending, ok := p.pending[p.next]
+		if !ok {
+			return
+		}
+		delete(p.pending, p.next)
+		p.advance(pending.row, pending.outcome)
+	}
+}
+
+func (p *progressTracker) advance(row rowProgress, outcome rowOutcome) {
+	p.committed.add(row, outcome)
+	p.next = row.seq + 1
+	p.offset = row.end
+	p.line = row.line
+}
+
+// totals returns the tally of every completed row
+func (p *progressTracker) totals() importTally {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.running
+}
+
+func (p *progressTracker) progressLocked() Progress {
+	return Progress{
+		Line:       p.line,
+		Stored:     p.running.stored,
+		Failed:     p.running.failed,
+		Duplicates: p.running.duplicates,
+		Discarded:  p.running.discarded,
+	}
+}
+
+func (p *progressTracker) checkpoint(untilLine int) *Checkpoint {
+	p.Lock()
+	defer p.Unlock()
+
+	if untilLine < p.line {
+		untilLine = p.line
+	}
+
+	return &Checkpoint{
+		Offset:       p.offset,
+		Line:         p.line,
+		UntilLine:    untilLine,
+		DedupeDigest: formatDigest(p.committed.digest),
+		Stored:       p.committed.stored,
+		Failed:       p.committed.failed,
+		Duplicates:   p.committed.duplicates,
+		Discarded:    p.committed.discarded,
+		UpdatedAt:    time.Now(),
+	}
+}
+
+// restoreCheckpoint positions the source right after the checkpoint.
+// Rows before the checkpoint are re-parsed to rebuild the dedupe state and verify the digest, unless dedupe is
+// disabled (accept is nil) and the source can seek. accept reports whether Import kept a parsed row, only those are
+// remembered for deduplication.
+func restoreCheckpoint(source io.Reader, checkpoint *Checkpoint, accept func(*geolocation.GeoLocation) bool) (r *bufio.Reader, seen map[string]uint64, err error) {
+	seen = map[string]uint64{}
+
+	if seeker, ok := source.(io.Seeker); ok && accept == nil {
+		if _, err = seeker.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+			return
+		}
+		r = bufio.NewReader(source)
+		return
+	}
+
+	r = bufio.NewReader(source)
+
+	var (
+		offset int64
+		line   int
+		digest uint64
+	)
+	for line < checkpoint.Line {
+		data, readErr := r.ReadBytes('\n')
+		if len(data) > 0 {
+			offset += int64(len(data))
+			line++
+			if line > 1 {
+				if location, locErr := geolocation.NewGeoLocationFromBytes(trimRow(data)); locErr == nil && location != nil {
+					digest += hashIP(location)
+					key, content := location.IPAddress.String(), hashContent(location)
+					if _, ok := seen[key]; !ok && accept != nil && accept(location) {
+						seen[key] = content
+					}
+				}
+			}
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+				return
+			}
+			break
+		}
+	}
+
+	if line != checkpoint.Line || offset != checkpoint.Offset || formatDigest(digest) != checkpoint.DedupeDigest {
+		err = fmt.Errorf("%w: source doesn't match the checkpoint at line %d", ErrCheckpointMismatch, checkpoint.Line)
+		return
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/enrich"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/ipclass"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// crashingDB simulates a crash on a number of StoreMany calls, nothing is stored from then on
+type crashingDB struct {
+	*testDB
+	sync.Mutex
+	calls   int
+	crashAt int
+	crash   func()
+}
+
+func (c *crashingDB) StoreMany(gs []*geolocation.GeoLocation) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.calls++
+	if c.calls == c.crashAt {
+		c.crash()
+	}
+	if c.calls >= c.crashAt {
+		return errors.New("crashed")
+	}
+	return c.testDB.StoreMany(gs)
+}
+
+// crashingStore drops every checkpoint saved after the crash, like a process that died would
+type crashingStore struct {
+	sync.Mutex
+	*FileCheckpointStore
+	crashed bool
+}
+
+func (c *crashingStore) Save(checkpoint *Checkpoint) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.crashed {
+		return nil
+	}
+	return c.FileCheckpointStore.Save(checkpoint)
+}
+
+func generateCSV(rows int) string {
+	var b strings.Builder
+	b.WriteString("ip_address,country_code,country,city,latitude,longitude,mystery_value\n")
+	for i := 0; i < rows; i++ {
+		switch {
+		case i%10 == 3:
+			b.WriteString(",PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0\n")
+		case i%10 == 7:
+			// Duplicate of the previous row
+			fmt.Fprintf(&b, "10.1.%d.%d,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115\n", (i-1)/256, (i-1)%256)
+		default:
+			fmt.Fprintf(&b, "10.1.%d.%d,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115\n", i/256, i%256)
+		}
+	}
+	return b.String()
+}
+
+func TestGeoService_ImportResume(t *testing.T) {
+	// The last row repeats the first one, which is before the checkpoint
+	data := generateCSV(500) + "10.1.0.0,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115\n"
+	upper := enrich.Func("upper", func(location *geolocation.GeoLocation) error {
+		location.Country = strings.ToUpper(location.Country)
+		return nil
+	})
+	even := enrich.Func("even", func(location *geolocation.GeoLocation) error {
+		if location.IPAddress.To4()[3]%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name        string
+		data        string
+		serviceOpts []Option
+		opts        ImportOptions
+	}{
+		{name: "Plain", data: data},
+		{
+			// The first 256 rows have documentation addresses, which are excluded
+			name: "Exclude",
+			data: strings.ReplaceAll(data, "10.1.0.", "192.0.2."),
+			opts: ImportOptions{Exclude: ipclass.Documentation},
+		},
+		{
+			name:        "Enrichers",
+			data:        data,
+			serviceOpts: []Option{WithGeohash(5), WithTimezone(nil)},
+			opts:        ImportOptions{Enrichers: []EnrichStage{{Enricher: upper}, {Enricher: even, OnError: EnrichReject}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Workers, opts.Batch, opts.CheckpointEvery = 4, BatchOptions{ChunkSize: 10, Writers: 3}, 25
+
+			want, err := NewGeoService(newTestDB(), tt.serviceOpts...).Import(context.Background(), strings.NewReader(tt.data), opts)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			store := &crashingStore{FileCheckpointStore: NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))}
+			opts.Checkpoints = store
+
+			ctx, cancel := context.WithCancel(context.Background())
+			db := &crashingDB{testDB: newTestDB(), crashAt: 12, crash: func() {
+				store.Lock()
+				store.crashed = true
+				store.Unlock()
+				cancel()
+			}}
+
+			_, err = NewGeoService(db, tt.serviceOpts...).Import(ctx, strings.NewReader(tt.data), opts)
+			if err == nil {
+				t.Fatalf("Import() error = nil, want the crash")
+			}
+
+			checkpoint, err := store.Load()
+			if err != nil || checkpoint == nil {
+				t.Fatalf("Load() checkpoint = %v, error = %v", checkpoint, err)
+			}
+			if checkpoint.Line == 0 || checkpoint.Line >= 500 {
+				t.Errorf("Load() checkpoint line = %d, want a line inside the source", checkpoint.Line)
+			}
+
+			store.crashed = false
+			opts.Resume = true
+			got, err := NewGeoService(db.testDB, tt.serviceOpts...).Import(context.Background(), strings.NewReader(tt.data), opts)
+			if err != nil {
+				t.Fatalf("Import() resumed error = %v", err)
+			}
+
+			if got.StoredEntries != want.StoredEntries || got.FailedEntries != 0 || got.Duplicates != want.Duplicates ||
+				got.DiscardedEntries != want.DiscardedEntries || got.AcceptedEntries != want.AcceptedEntries ||
+				got.ConflictingDuplicates != want.ConflictingDuplicates {
+				t.Errorf("Import() resumed stat = %+v, want %+v", got, want)
+			}
+			if len(db.testDB.data) != want.StoredEntries {
+				t.Errorf("Import() resumed stored %d locations, want %d", len(db.testDB.data), want.StoredEntries)
+			}
+		})
+	}
+}
+
+func TestGeoService_ImportResumeMismatch(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	opts := ImportOptions{Batch: BatchOptions{ChunkSize: 10}, CheckpointEvery: 10, Checkpoints: store}
+
+	_, err := NewGeoService(newTestDB()).Import(context.Background(), strings.NewReader(generateCSV(50)), opts)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	opts.Resume = true
+	changed := strings.Replace(generateCSV(50), "10.1.0.5,", "10.2.0.5,", 1)
+	_, err = NewGeoService(newTestDB()).Import(context.Background(), strings.NewReader(changed), opts)
+	if !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("Import() error = %v, want %v", err, ErrCheckpointMismatch)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/filestore"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// diff prints the JSON report of the changes between a CSV and a file repository or another CSV
+func diff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	db := flags.String("db", "", "file repository to compare with, and to apply the changes to")
+	old := flags.String("old", "", "CSV to compare with instead of the repository")
+	apply := flags.Bool("apply", false, "write the changes to -db")
+	maxChanges := flags.Int("max-changes", 0, "number of changes listed in the report, zero lists all of them")
+	workers := flags.Int("workers", 4, "number of goroutines parsing the CSVs")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: geoservice diff (-db <file> | -old <csv file>) [-apply] [csv file]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if (*db == "" && *old == "") || (*apply && *db == "") {
+		flags.Usage()
+		return exitUsage
+	}
+
+	gs := geoservice.NewGeoService(discardDB{})
+	if *db != "" {
+		repository, err := filestore.Open(*db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer repository.Close()
+		gs = geoservice.NewGeoService(repository)
+	}
+
+	source, err := openSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer source.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := geoservice.DiffOptions{
+		Import:     geoservice.ImportOptions{Workers: *workers},
+		MaxChanges: *maxChanges,
+		Apply:      *apply,
+	}
+
+	var report *geoservice.DiffReport
+	if *old != "" {
+		oldSource, openErr := os.Open(*old)
+		if openErr != nil {
+			fmt.Fprintln(os.Stderr, openErr)
+			return exitError
+		}
+		defer oldSource.Close()
+		report, err = gs.DiffCSV(ctx, oldSource, source, opts)
+	} else {
+		report, err = gs.Diff(ctx, source, opts)
+	}
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/filestore"
+	"os"
+)
+
+// export writes every location of a file repository to standard output
+func export(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	db := flags.String("db", "", "file repository to export")
+	format := flags.String("format", "csv", "output format: csv, json or jsonl")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: geoservice export -db <file> [-format csv|json|jsonl]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *db == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	repository, err := filestore.Open(*db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer repository.Close()
+
+	count, err := geoservice.NewGeoService(repository).Export(os.Stdout, geoservice.ExportFormat(*format))
+	if errors.Is(err, geoservice.ErrUnknownFormat) {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	fmt.Fprintf(os.Stderr, "exported %d locations\n", count)
+	return exitOK
+}
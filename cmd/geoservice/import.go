@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/asn"
+	"github.com/aliforever/geo-service/enrich"
+	"github.com/aliforever/geo-service/filestore"
+	"github.com/aliforever/geo-service/geocode"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/ipclass"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// importFlags are the flags shared by import and validate
+type importFlags struct {
+	workers          *int
+	dedupe           *string
+	chunkSize        *int
+	writers          *int
+	rejects          *string
+	maxRejected      *int
+	maxRejectedRatio *float64
+	statsFormat      *string
+	profile          *string
+	fillCity         *bool
+	gazetteer        *string
+	enrich           *string
+	enrichPolicy     *string
+	asn              *string
+	exclude          *string
+	sampleHeap       *bool
+}
+
+func addImportFlags(flags *flag.FlagSet) importFlags {
+	return importFlags{
+		workers:          flags.Int("workers", 4, "number of goroutines parsing the CSV"),
+		dedupe:           flags.String("dedupe", "first", "duplicate IP addresses policy: first keeps the first row, none stores every row"),
+		chunkSize:        flags.Int("chunk-size", geoservice.DefaultChunkSize, "number of locations written at once"),
+		writers:          flags.Int("writers", 1, "number of goroutines writing chunks"),
+		rejects:          flags.String("rejects", "", "file receiving the rejected rows, - for standard error"),
+		maxRejected:      flags.Int("max-rejected", -1, "exit with status 3 when more rows are rejected, negative disables the check"),
+		profile:          flags.String("profile", "", "file receiving the JSON profile of the accepted rows, - for standard output"),
+		statsFormat:      flags.String("stats", "text", "format of the printed statistics: text or json"),
+		maxRejectedRatio: flags.Float64("max-rejected-ratio", 0, "exit with status 3 when a larger share of the rows is rejected, zero disables the check"),
+		fillCity:         flags.Bool("fill-city", false, "fill empty cities with the closest city of the gazetteer"),
+		gazetteer:        flags.String("gazetteer", "", "GeoNames dump like cities1000.txt used by -fill-city instead of the embedded cities"),
+		enrich:           flags.String("enrich", "", "comma separated enrichers run on every row: continent, eu_member, asn"),
+		exclude:          flags.String("exclude", "", "comma separated IP address classes whose rows are discarded, e.g. bogon or private,documentation"),
+		enrichPolicy:     flags.String("enrich-policy", "ignore", "rows failing an enricher are: ignore stored anyway, reject discarded, abort stop the import"),
+		asn:              flags.String("asn", "", "network,asn,org CSV used by -enrich asn"),
+		sampleHeap:       flags.Bool("sample-heap", false, "report the peak heap size of the import"),
+	}
+}
+
+// options builds the ImportOptions, rejected rows are written to the returned writer which has to be flushed
+func (f importFlags) options() (opts geoservice.ImportOptions, rejects *bufio.Writer, closeRejects func() error, err error) {
+	opts = geoservice.ImportOptions{
+		Workers: *f.workers,
+		Batch:   geoservice.BatchOptions{ChunkSize: *f.chunkSize, Writers: *f.writers},
+	}
+
+	if *f.statsFormat != "text" && *f.statsFormat != "json" {
+		err = fmt.Errorf("invalid -stats: %s", *f.statsFormat)
+		return
+	}
+
+	if *f.profile != "" {
+		opts.Profile = &geoservice.ProfileOptions{}
+	}
+
+	if *f.fillCity {
+		opts.FillCity = &geoservice.FillCityOptions{}
+		if *f.gazetteer != "" {
+			if opts.FillCity.Gazetteer, err = loadGazetteer(*f.gazetteer); err != nil {
+				return
+			}
+		}
+	}
+
+	if opts.Enrichers, err = f.enrichers(); err != nil {
+		return
+	}
+	if opts.Exclude, err = ipclass.Parse(*f.exclude); err != nil {
+		err = fmt.Errorf("invalid -exclude: %s", *f.exclude)
+		return
+	}
+
+	switch *f.dedupe {
+	case "first":
+		opts.Dedupe = geoservice.DedupeKeepFirst
+	case "none":
+		opts.Dedupe = geoservice.DedupeDisabled
+	default:
+		err = fmt.Errorf("invalid -dedupe: %s", *f.dedupe)
+		return
+	}
+
+	closeRejects = func() error { return nil }
+	var w io.Writer
+	switch *f.rejects {
+	case "":
+		return
+	case "-":
+		w = os.Stderr
+	default:
+		file, openErr := os.Create(*f.rejects)
+		if openErr != nil {
+			err = openErr
+			return
+		}
+		w, closeRejects = file, file.Close
+	}
+
+	rejects = bufio.NewWriter(w)
+	opts.OnReject = func(rejection geoservice.Rejection) {
+		fmt.Fprintf(rejects, "%d\t%v\t%s\n", rejection.Line, rejection.Err, rejection.Row)
+	}
+	return
+}
+
+// serviceOptions returns the GeoService options of the flags
+func (f importFlags) serviceOptions() (opts []geoservice.Option) {
+	if *f.sampleHeap {
+		opts = append(opts, geoservice.WithHeapSampling(0))
+	}
+	return
+}
+
+// enrichers builds the stages of -enrich
+func (f importFlags) enrichers() (stages []geoservice.EnrichStage, err error) {
+	if *f.enrich == "" {
+		return
+	}
+
+	var policy geoservice.EnrichPolicy
+	switch *f.enrichPolicy {
+	case "ignore":
+		policy = geoservice.EnrichIgnore
+	case "reject":
+		policy = geoservice.EnrichReject
+	case "abort":
+		policy = geoservice.EnrichAbort
+	default:
+		err = fmt.Errorf("invalid -enrich-policy: %s", *f.enrichPolicy)
+		return
+	}
+
+	for _, name := range strings.Split(*f.enrich, ",") {
+		var enricher geolocation.Enricher
+		switch strings.TrimSpace(name) {
+		case "continent":
+			enricher = enrich.Continent()
+		case "eu_member":
+			enricher = enrich.EUMembership()
+		case "asn":
+			if *f.asn == "" {
+				err = fmt.Errorf("-enrich asn needs -asn")
+				return
+			}
+			var table *asn.Table
+			if table, err = loadASNTable(*f.asn); err != nil {
+				return
+			}
+			enricher = enrich.ASN(table)
+		default:
+			err = fmt.Errorf("invalid -enrich: %s", name)
+			return
+		}
+		stages = append(stages, geoservice.EnrichStage{Enricher: enricher, OnError: policy})
+	}
+	return
+}
+
+// exitCode reports exitRejected when stat exceeds the rejection thresholds
+func (f importFlags) exitCode(stat *geoservice.Statistics) int {
+	rejected := stat.DiscardedEntries + stat.Duplicates
+	if *f.maxRejected >= 0 && rejected > *f.maxRejected {
+		fmt.Fprintf(os.Stderr, "%d rows rejected, want at most %d\n", rejected, *f.maxRejected)
+		return exitRejected
+	}
+
+	total := stat.AcceptedEntries + stat.DiscardedEntries + stat.Duplicates
+	if *f.maxRejectedRatio > 0 && total > 0 {
+		if ratio := float64(rejected) / float64(total); ratio > *f.maxRejectedRatio {
+			fmt.Fprintf(os.Stderr, "%.2f%% of rows rejected, want at most %.2f%%\n", ratio*100, *f.maxRejectedRatio*100)
+			return exitRejected
+		}
+	}
+	return exitOK
+}
+
+func printStatistics(stat *geoservice.Statistics, format string) {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(stat)
+		return
+	}
+
+	fmt.Printf("elapsed:     %s\n", stat.Elapsed)
+	fmt.Printf("read:        %d bytes, %.0f rows/s\n", stat.BytesRead, stat.RowsPerSecond)
+	fmt.Printf("accepted:    %d\n", stat.AcceptedEntries)
+	fmt.Printf("discarded:   %d\n", stat.DiscardedEntries)
+	reasons := make([]string, 0, len(stat.DiscardReasons))
+	for reason := range stat.DiscardReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Printf("  %s: %d\n", reason, stat.DiscardReasons[reason])
+	}
+	fmt.Printf("duplicates:  %d (%d conflicting)\n", stat.Duplicates, stat.ConflictingDuplicates)
+	fmt.Printf("stored:      %d\n", stat.StoredEntries)
+	fmt.Printf("failed:      %d\n", stat.FailedEntries)
+	fmt.Printf("countries:   %d\n", stat.DistinctCountries)
+	if len(stat.Classes) > 0 {
+		classes := make([]string, 0, len(stat.Classes))
+		for class := range stat.Classes {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		fmt.Printf("special IP addresses:\n")
+		for _, class := range classes {
+			fmt.Printf("  %s: %d\n", class, stat.Classes[class])
+		}
+	}
+	if len(stat.Enrichment) > 0 {
+		fmt.Printf("enrichment:\n")
+		names := make([]string, 0, len(stat.Enrichment))
+		for name := range stat.Enrichment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			enricher := stat.Enrichment[name]
+			fmt.Printf("  %s: %d enriched, %d failed in %s\n", name, enricher.Enriched, enricher.Failed, enricher.Elapsed)
+		}
+	}
+	if stat.PeakHeapBytes > 0 {
+		fmt.Printf("peak heap:   %d bytes\n", stat.PeakHeapBytes)
+	}
+}
+
+// writeProfile writes the profile as JSON to the -profile file
+func (f importFlags) writeProfile(profile *geoservice.Profile) (err error) {
+	if *f.profile == "" || profile == nil {
+		return
+	}
+
+	w := os.Stdout
+	if *f.profile != "-" {
+		if w, err = os.Create(*f.profile); err != nil {
+			return
+		}
+		defer func() {
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(profile)
+	return
+}
+
+// runImport imports source into db until done or interrupted and prints its Statistics
+func runImport(gs *geoservice.GeoService, source io.Reader, f importFlags, opts geoservice.ImportOptions, rejects *bufio.Writer) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stat, err := gs.Import(ctx, source, opts)
+	if rejects != nil {
+		rejects.Flush()
+	}
+	if stat != nil {
+		printStatistics(stat, *f.statsFormat)
+		if profileErr := f.writeProfile(stat.Profile); profileErr != nil {
+			fmt.Fprintln(os.Stderr, profileErr)
+			return exitError
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return f.exitCode(stat)
+}
+
+// loadGazetteer reads a GeoNames dump
+func loadGazetteer(path string) (gazetteer *geocode.Gazetteer, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return geocode.LoadGeoNames(bufio.NewReader(file))
+}
+
+// loadASNTable reads a network,asn,org CSV
+func loadASNTable(path string) (table *asn.Table, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if table, err = asn.LoadCSV(bufio.NewReader(file)); err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
+
+// importFile imports a CSV file or standard input into a file repository
+func importFile(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	db := flags.String("db", "", "file repository to import into, created if missing")
+	checkpoint := flags.String("checkpoint", "", "file persisting the import progress")
+	resume := flags.Bool("resume", false, "continue the import saved in -checkpoint")
+	geohash := flags.Int("geohash-precision", 0, "length of the geohash stored with every location, zero disables it")
+	timezones := flags.Bool("timezone", false, "resolve the IANA timezone of stored locations from their coordinates and country code")
+	f := addImportFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: geoservice import -db <file> [flags] [csv file]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *db == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	opts, rejects, closeRejects, err := f.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	defer closeRejects()
+
+	if *checkpoint != "" {
+		opts.Checkpoints = geoservice.NewFileCheckpointStore(*checkpoint)
+	}
+	opts.Resume = *resume
+
+	repository, err := filestore.Open(*db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	source, err := openSource(flags.Arg(0))
+	if err != nil {
+		repository.Close()
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer source.Close()
+
+	serviceOpts := f.serviceOptions()
+	if *geohash > 0 {
+		serviceOpts = append(serviceOpts, geoservice.WithGeohash(*geohash))
+	}
+	if *timezones {
+		serviceOpts = append(serviceOpts, geoservice.WithTimezone(nil))
+	}
+	code := runImport(geoservice.NewGeoService(repository, serviceOpts...), source, f, opts, rejects)
+	if err = repository.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return code
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// importCSV rejects two of its six rows: line 5 has no IP address and line 6 repeats line 3
+const importCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+70.95.73.73,TL,Saudi Arabia,Gradymouth,-49.16675918861615,-86.05920084416894,2559997162
+,PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+125.159.20.54,LI,Guyana,Port Karson,-78.2274228596799,-163.26218895343357,1337885276
+`
+
+// writeCSV writes importCSV to a temporary file
+func writeCSV(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "locations.csv")
+	if err := os.WriteFile(path, []byte(importCSV), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// quiet discards what the command prints to standard output and error
+func quiet(t *testing.T) {
+	t.Helper()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	})
+}
+
+func TestImportFile(t *testing.T) {
+	csv := writeCSV(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "Imported", args: []string{csv}, want: exitOK},
+		{name: "UnderMaxRejected", args: []string{"-max-rejected", "2", csv}, want: exitOK},
+		{name: "OverMaxRejected", args: []string{"-max-rejected", "1", csv}, want: exitRejected},
+		{name: "UnderMaxRejectedRatio", args: []string{"-max-rejected-ratio", "0.4", csv}, want: exitOK},
+		{name: "OverMaxRejectedRatio", args: []string{"-max-rejected-ratio", "0.3", csv}, want: exitRejected},
+		// Without deduplication the repository refuses to store the repeated IP address
+		{name: "StoreFailed", args: []string{"-dedupe", "none", csv}, want: exitError},
+		{name: "InvalidDedupe", args: []string{"-dedupe", "last", csv}, want: exitUsage},
+		{name: "InvalidExclude", args: []string{"-exclude", "public", csv}, want: exitUsage},
+		{name: "MissingSource", args: []string{filepath.Join(t.TempDir(), "missing.csv")}, want: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiet(t)
+			args := append([]string{"-db", filepath.Join(t.TempDir(), "locations.jsonl")}, tt.args...)
+			if got := importFile(args); got != tt.want {
+				t.Errorf("importFile(%v) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("MissingDB", func(t *testing.T) {
+		quiet(t)
+		if got := importFile([]string{csv}); got != exitUsage {
+			t.Errorf("importFile() without -db = %d, want %d", got, exitUsage)
+		}
+	})
+}
+
+func TestImportFile_Rejects(t *testing.T) {
+	quiet(t)
+	dir := t.TempDir()
+	rejects := filepath.Join(dir, "rejects.tsv")
+
+	if got := importFile([]string{"-db", filepath.Join(dir, "locations.jsonl"), "-rejects", rejects, writeCSV(t)}); got != exitOK {
+		t.Fatalf("importFile() = %d, want %d", got, exitOK)
+	}
+
+	data, err := os.ReadFile(rejects)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []struct {
+		prefix string
+		row    string
+	}{
+		{prefix: "5\t", row: ",PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0"},
+		{prefix: "6\tduplicate_ip_address\t", row: "160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("rejects = %q, want %d lines", data, len(want))
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, want[i].prefix) || !strings.HasSuffix(line, "\t"+want[i].row) {
+			t.Errorf("rejects line %d = %q, want %q ... %q", i, line, want[i].prefix, want[i].row)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	csv := writeCSV(t)
+	networks := filepath.Join(t.TempDir(), "networks.csv")
+	if err := os.WriteFile(networks, []byte("network,asn,org\n0.0.0.0/0,64500,Everyone\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "Valid", args: []string{csv}, want: exitOK},
+		{name: "OverMaxRejected", args: []string{"-max-rejected", "0", csv}, want: exitRejected},
+		{name: "InvalidStats", args: []string{"-stats", "xml", csv}, want: exitUsage},
+		{name: "EnrichASN", args: []string{"-enrich", "asn", "-enrich-policy", "abort", "-asn", networks, csv}, want: exitOK},
+		{name: "EnrichASNWithoutTable", args: []string{"-enrich", "asn", csv}, want: exitUsage},
+		{name: "InvalidEnrich", args: []string{"-enrich", "geohash", csv}, want: exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiet(t)
+			if got := validate(tt.args); got != tt.want {
+				t.Errorf("validate(%v) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
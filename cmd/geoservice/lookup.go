@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/filestore"
+	"net"
+	"os"
+)
+
+// lookup prints the location of every IP address as a JSON line, the exit code is 1 if any lookup failed
+func lookup(args []string) int {
+	flags := flag.NewFlagSet("lookup", flag.ExitOnError)
+	db := flags.String("db", "", "file repository to look up")
+	asnPath := flags.String("asn", "", "network,asn,org CSV merged into the results")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: geoservice lookup -db <file> <ip>...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *db == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	repository, err := filestore.Open(*db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer repository.Close()
+
+	gs := geoservice.NewGeoService(repository)
+	if *asnPath != "" {
+		if code := loadASN(gs, *asnPath); code != exitOK {
+			return code
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	code := exitOK
+	for _, value := range flags.Args() {
+		location, lookupErr := gs.RetrieveLocation(net.ParseIP(value))
+		if lookupErr != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", value, lookupErr)
+			code = exitError
+			continue
+		}
+		encoder.Encode(location)
+	}
+	return code
+}
+
+// loadASN imports the ASN CSV at path into gs
+func loadASN(gs *geoservice.GeoService, path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer file.Close()
+
+	if _, err = gs.ImportASN(bufio.NewReader(file)); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return exitError
+	}
+	return exitOK
+}
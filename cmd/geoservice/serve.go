@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/filestore"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/httpapi"
+	"github.com/aliforever/geo-service/memory"
+	"github.com/aliforever/geo-service/metrics"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// serve loads a CSV into memory or a file repository and exposes it through the HTTP API until interrupted
+func serve(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	db := flags.String("db", "", "file repository to serve, locations are kept in memory only when empty")
+	data := flags.String("data", "", "CSV file to load before serving")
+	workers := flags.Int("workers", 4, "number of goroutines parsing the CSV")
+	trusted := flags.String("trusted-proxies", "", "comma separated IP addresses or CIDR networks allowed to set X-Forwarded-For")
+	uploadToken := flags.String("upload-token", os.Getenv("GEOSERVICE_UPLOAD_TOKEN"), "bearer token enabling CSV uploads on /v1/imports, defaults to $GEOSERVICE_UPLOAD_TOKEN")
+	maxUpload := flags.Int64("max-upload-bytes", 0, "maximum size of uploaded bodies, zero means unlimited")
+	maxDecompressed := flags.Int64("max-decompressed-bytes", 0, "maximum size of gzipped uploads after decompression, defaults to 20 times -max-upload-bytes")
+	metricsPath := flags.String("metrics-path", "/metrics", "path serving Prometheus metrics, empty disables them")
+	logLevel := flags.String("log-level", "info", "minimum level of logged events: debug, info, warn or error")
+	geohash := flags.Int("geohash-precision", 0, "length of the geohash computed for stored locations, zero disables it")
+	asnPath := flags.String("asn", "", "network,asn,org CSV merged into lookup results")
+	timezones := flags.Bool("timezone", false, "resolve the IANA timezone of stored locations from their coordinates and country code")
+	flags.Parse(args)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	proxies, err := httpapi.ParseTrustedProxies(strings.Split(*trusted, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	var repository geolocation.Repository = memory.New()
+	if *db != "" {
+		store, openErr := filestore.Open(*db)
+		if openErr != nil {
+			fmt.Fprintln(os.Stderr, openErr)
+			return exitError
+		}
+		defer store.Close()
+		repository = store
+	}
+
+	collector := metrics.New()
+	opts := []geoservice.Option{geoservice.WithMetrics(collector), geoservice.WithLogger(logger, geoservice.LogOptions{})}
+	if *geohash > 0 {
+		opts = append(opts, geoservice.WithGeohash(*geohash))
+	}
+	if *timezones {
+		opts = append(opts, geoservice.WithTimezone(nil))
+	}
+	gs := geoservice.NewGeoService(repository, opts...)
+	if *asnPath != "" {
+		if code := loadASN(gs, *asnPath); code != exitOK {
+			return code
+		}
+	}
+	if *data != "" {
+		file, openErr := os.Open(*data)
+		if openErr != nil {
+			fmt.Fprintln(os.Stderr, openErr)
+			return exitError
+		}
+		stat, importErr := gs.Import(context.Background(), file, geoservice.ImportOptions{Workers: *workers})
+		file.Close()
+		if importErr != nil {
+			fmt.Fprintln(os.Stderr, importErr)
+			return exitError
+		}
+		fmt.Fprintf(os.Stderr, "loaded %d locations in %s\n", stat.StoredEntries, stat.Elapsed)
+	}
+
+	handler := httpapi.New(gs, httpapi.Options{
+		TrustedProxies: proxies,
+		Upload: httpapi.UploadOptions{
+			Token:                *uploadToken,
+			MaxBytes:             *maxUpload,
+			MaxDecompressedBytes: *maxDecompressed,
+			Import:               geoservice.ImportOptions{Workers: *workers},
+		},
+	})
+	defer handler.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	if *metricsPath != "" {
+		mux.Handle(*metricsPath, collector.Handler())
+	}
+	server := &http.Server{Addr: *addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "listening on %s\n", *addr)
+	if err = server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"os"
+)
+
+// discardDB accepts every location without keeping it
+type discardDB struct{}
+
+func (discardDB) Store(*geolocation.GeoLocation) error { return nil }
+
+func (discardDB) StoreMany([]*geolocation.GeoLocation) error { return nil }
+
+func (discardDB) Retrieve(net.IP) (*geolocation.GeoLocation, error) {
+	return nil, geolocation.ErrNotFound
+}
+
+// validate parses a CSV file or standard input without storing it and prints its Statistics
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	f := addImportFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: geoservice validate [flags] [csv file]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	opts, rejects, closeRejects, err := f.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	defer closeRejects()
+
+	source, err := openSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer source.Close()
+
+	return runImport(geoservice.NewGeoService(discardDB{}, f.serviceOptions()...), source, f, opts, rejects)
+}
@@ -0,0 +1,234 @@
+package geoservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// FieldChange is a column whose value differs, values are formatted as in the CSV
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Change is an IP address whose location differs between two datasets, Old is nil for additions and New for removals
+type Change struct {
+	Type      ChangeType               `json:"type"`
+	IPAddress net.IP                   `json:"ip_address"`
+	Old       *geolocation.GeoLocation `json:"old,omitempty"`
+	New       *geolocation.GeoLocation `json:"new,omitempty"`
+	Fields    []FieldChange            `json:"fields,omitempty"`
+}
+
+// DiffOptions configures Diff and DiffCSV
+type DiffOptions struct {
+	// Import configures how the CSVs are read, checkpoints are ignored
+	Import ImportOptions
+	// MaxChanges limits the changes listed in the report, zero lists all of them. The counts are always complete.
+	MaxChanges int
+	// Apply writes the changes to the Repository once the diff is complete: additions and modifications are upserted
+	// and removals deleted. The Repository has to implement geolocation.Upserter and geolocation.Deleter.
+	Apply bool
+}
+
+// DiffReport lists the changes sorted by IP address
+type DiffReport struct {
+	Added     int         `json:"added"`
+	Removed   int         `json:"removed"`
+	Modified  int         `json:"modified"`
+	Unchanged int         `json:"unchanged"`
+	Changes   []Change    `json:"changes"`
+	Stat      *Statistics `json:"statistics,omitempty"`
+	// Applied and ApplyFailed count the changes written with DiffOptions.Apply
+	Applied     int `json:"applied,omitempty"`
+	ApplyFailed int `json:"apply_failed,omitempty"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// fieldChanges compares the columns of two locations of the same IP address
+func fieldChanges(old, new *geolocation.GeoLocation) (changes []FieldChange) {
+	for _, field := range []FieldChange{
+		{Field: "country_code", Old: old.CountryCode, New: new.CountryCode},
+		{Field: "country", Old: old.Country, New: new.Country},
+		{Field: "city", Old: old.City, New: new.City},
+		{Field: "latitude", Old: formatFloat(old.Latitude), New: formatFloat(new.Latitude)},
+		{Field: "longitude", Old: formatFloat(old.Longitude), New: formatFloat(new.Longitude)},
+		{Field: "mystery_value", Old: strconv.FormatInt(old.MysteryValue, 10), New: strconv.FormatInt(new.MysteryValue, 10)},
+	} {
+		if field.Old != field.New {
+			changes = append(changes, field)
+		}
+	}
+	return
+}
+
+// diffTarget is the Repository a diffed CSV is imported into, it compares every location against base instead of storing it
+type diffTarget struct {
+	sync.Mutex
+	base    geolocation.Repository
+	seen    map[string]struct{}
+	changes []Change
+	report  *DiffReport
+}
+
+func (d *diffTarget) Store(location *geolocation.GeoLocation) error {
+	return d.StoreMany([]*geolocation.GeoLocation{location})
+}
+
+func (d *diffTarget) StoreMany(locations []*geolocation.GeoLocation) error {
+	for _, location := range locations {
+		old, err := d.base.Retrieve(location.IPAddress)
+		if err != nil && !errors.Is(err, geolocation.ErrNotFound) {
+			return err
+		}
+
+		d.Lock()
+		d.seen[location.IPAddress.String()] = struct{}{}
+		switch {
+		case old == nil:
+			d.report.Added++
+			d.changes = append(d.changes, Change{Type: ChangeAdded, IPAddress: location.IPAddress, New: location})
+		default:
+			if fields := fieldChanges(old, location); len(fields) > 0 {
+				d.report.Modified++
+				d.changes = append(d.changes, Change{Type: ChangeModified, IPAddress: location.IPAddress, Old: old, New: location, Fields: fields})
+			} else {
+				d.report.Unchanged++
+			}
+		}
+		d.Unlock()
+	}
+	return nil
+}
+
+func (d *diffTarget) Retrieve(net.IP) (*geolocation.GeoLocation, error) {
+	return nil, geolocation.ErrNotFound
+}
+
+// Diff compares the CSV read from source with the active dataset. The Repository has to implement geolocation.Iterable
+// to find removed IP addresses.
+func (g *GeoService) Diff(ctx context.Context, source io.Reader, opts DiffOptions) (report *DiffReport, err error) {
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	return g.diff(ctx, dataset.Repository, dataset, source, opts)
+}
+
+// DiffCSV compares the CSV read from new with the one read from old, DiffOptions.Apply writes the changes to the active dataset
+func (g *GeoService) DiffCSV(ctx context.Context, old, new io.Reader, opts DiffOptions) (report *DiffReport, err error) {
+	opts.Import.Checkpoints = nil
+
+	base := memory.New()
+	if _, err = g.importInto(ctx, base, old, opts.Import); err != nil {
+		return
+	}
+
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	report, err = g.diff(ctx, base, dataset, new, opts)
+	return
+}
+
+// diff compares source with base, changes are applied to dataset which isn't necessarily base. The caller holds dataset
+// for the whole diff, so the changes go to the dataset they were checked against even if another version is activated.
+func (g *GeoService) diff(ctx context.Context, base geolocation.Repository, dataset *Dataset, source io.Reader, opts DiffOptions) (report *DiffReport, err error) {
+	iterable, ok := geolocation.As[geolocation.Iterable](base)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+
+	var (
+		upserter geolocation.Upserter
+		deleter  geolocation.Deleter
+	)
+	if opts.Apply {
+		var canUpsert, canDelete bool
+		upserter, canUpsert = geolocation.As[geolocation.Upserter](dataset.Repository)
+		deleter, canDelete = geolocation.As[geolocation.Deleter](dataset.Repository)
+		if !canUpsert || !canDelete {
+			err = geolocation.ErrUnsupported
+			return
+		}
+	}
+
+	opts.Import.Checkpoints = nil
+	report = &DiffReport{}
+	target := &diffTarget{base: base, seen: map[string]struct{}{}, report: report}
+	if report.Stat, err = g.importInto(ctx, target, source, opts.Import); err != nil {
+		return
+	}
+
+	err = iterable.Each(func(location *geolocation.GeoLocation) bool {
+		if _, ok := target.seen[location.IPAddress.String()]; !ok {
+			report.Removed++
+			target.changes = append(target.changes, Change{Type: ChangeRemoved, IPAddress: location.IPAddress, Old: location})
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	changes := target.changes
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].IPAddress.To16(), changes[j].IPAddress.To16()) < 0
+	})
+
+	report.Changes = changes
+	if opts.MaxChanges > 0 && len(report.Changes) > opts.MaxChanges {
+		report.Changes = report.Changes[:opts.MaxChanges]
+	}
+	if opts.Apply {
+		err = g.applyChanges(report, changes, upserter, deleter)
+	}
+	return
+}
+
+// applyChanges deletes removed IP addresses with deleter and upserts added and modified locations with upserter
+func (g *GeoService) applyChanges(report *DiffReport, changes []Change, upserter geolocation.Upserter, deleter geolocation.Deleter) (err error) {
+	defer g.written()
+
+	var firstErr error
+	for _, change := range changes {
+		var applyErr error
+		if change.Type == ChangeRemoved {
+			applyErr = deleter.Delete(change.IPAddress)
+		} else {
+			applyErr = upserter.Upsert(change.New)
+		}
+		if applyErr != nil {
+			report.ApplyFailed++
+			if firstErr == nil {
+				firstErr = applyErr
+			}
+			continue
+		}
+		report.Applied++
+	}
+
+	if firstErr != nil {
+		err = fmt.Errorf("%w: %d of %d changes failed, first: %v", ErrIncompleteStore, report.ApplyFailed, len(changes), firstErr)
+	}
+	return
+}
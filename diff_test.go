@@ -0,0 +1,157 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/cache"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// iterableDB can list its locations but neither upsert nor delete them
+type iterableDB struct {
+	geolocation.Repository
+	geolocation.Iterable
+}
+
+// diffCSV removes 70.95.73.73, moves 160.103.7.140 and adds 1.2.3.4 compared to importCSV
+const diffCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
+160.103.7.140,CZ,Nicaragua,Old Neva,-68.31023296602508,-37.5,7301823115
+125.159.20.54,LI,Guyana,Port Karson,-78.2274228596799,-163.26218895343357,1337885276
+1.2.3.4,NL,Netherlands,Amsterdam,52.37,4.89,1
+`
+
+func TestGeoService_Diff(t *testing.T) {
+	readOnly := memory.New()
+	tests := []struct {
+		name        string
+		db          geolocation.Repository
+		opts        DiffOptions
+		wantErr     error
+		wantChanges []ChangeType
+	}{
+		{
+			name:        "Report",
+			db:          memory.New(),
+			wantChanges: []ChangeType{ChangeAdded, ChangeRemoved, ChangeModified},
+		},
+		{
+			name:        "MaxChanges",
+			db:          memory.New(),
+			opts:        DiffOptions{MaxChanges: 1},
+			wantChanges: []ChangeType{ChangeAdded},
+		},
+		{
+			name:        "Apply",
+			db:          memory.New(),
+			opts:        DiffOptions{Apply: true, MaxChanges: 1},
+			wantChanges: []ChangeType{ChangeAdded},
+		},
+		{
+			// The cache has Upsert and Delete methods, the repository it wraps doesn't
+			name:    "ApplyUnsupported",
+			db:      cache.New(iterableDB{Repository: readOnly, Iterable: readOnly}, cache.Options{}),
+			opts:    DiffOptions{Apply: true},
+			wantErr: geolocation.ErrUnsupported,
+		},
+		{
+			name:    "NotIterable",
+			db:      newTestDB(),
+			wantErr: geolocation.ErrUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGeoService(tt.db)
+			if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			report, err := g.Diff(context.Background(), strings.NewReader(diffCSV), tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Diff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if report.Added != 1 || report.Removed != 1 || report.Modified != 1 || report.Unchanged != 2 {
+				t.Errorf("Diff() counts = %+v", report)
+			}
+			if len(report.Changes) != len(tt.wantChanges) {
+				t.Fatalf("Diff() changes = %+v, want %v", report.Changes, tt.wantChanges)
+			}
+			for index, change := range report.Changes {
+				if change.Type != tt.wantChanges[index] {
+					t.Errorf("Diff() change %d = %s, want %s", index, change.Type, tt.wantChanges[index])
+				}
+				if change.Type == ChangeModified && (len(change.Fields) != 2 || change.Fields[0] != (FieldChange{Field: "city", Old: "New Neva", New: "Old Neva"})) {
+					t.Errorf("Diff() modified fields = %+v", change.Fields)
+				}
+			}
+
+			_, removedErr := g.RetrieveLocation(net.ParseIP("70.95.73.73"))
+			moved, _ := g.RetrieveLocation(net.ParseIP("160.103.7.140"))
+			if tt.opts.Apply {
+				if report.Applied != 3 || !errors.Is(removedErr, geolocation.ErrNotFound) || moved.City != "Old Neva" {
+					t.Errorf("Diff() applied = %d, removed error = %v, moved = %+v", report.Applied, removedErr, moved)
+				}
+			} else if removedErr != nil || moved.City != "New Neva" {
+				t.Errorf("Diff() changed the repository without Apply")
+			}
+		})
+	}
+}
+
+func TestGeoService_DiffCSV(t *testing.T) {
+	g := NewGeoService(memory.New())
+	report, err := g.DiffCSV(context.Background(), strings.NewReader(importCSV), strings.NewReader(diffCSV), DiffOptions{Apply: true})
+	if err != nil {
+		t.Fatalf("DiffCSV() error = %v", err)
+	}
+	if report.Added != 1 || report.Removed != 1 || report.Modified != 1 || len(report.Changes) != 3 {
+		t.Errorf("DiffCSV() = %+v", report)
+	}
+	if location, err := g.RetrieveLocation(net.ParseIP("1.2.3.4")); err != nil || location.City != "Amsterdam" {
+		t.Errorf("RetrieveLocation() after DiffCSV() = %+v, error = %v", location, err)
+	}
+}
+
+func TestGeoService_DiffApplyDuringActivation(t *testing.T) {
+	factory := func(version string) (geolocation.Repository, error) {
+		return newTestDB(), nil
+	}
+	db := memory.New()
+	g := NewGeoService(db, WithVersioning(factory, 1))
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if _, err := g.PrepareVersion(context.Background(), strings.NewReader(importCSV), VersionOptions{Version: "v1"}); err != nil {
+		t.Fatalf("PrepareVersion() error = %v", err)
+	}
+
+	// The activated dataset can neither upsert nor delete, the changes still go to the dataset they were computed for
+	var once sync.Once
+	opts := DiffOptions{Apply: true, Import: ImportOptions{OnProgress: func(Progress) {
+		once.Do(func() {
+			if err := g.ActivateVersion("v1"); err != nil {
+				t.Errorf("ActivateVersion() error = %v", err)
+			}
+		})
+	}}}
+	report, err := g.Diff(context.Background(), strings.NewReader(diffCSV), opts)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if g.ActiveVersion() != "v1" || report.Applied != 3 {
+		t.Fatalf("Diff() applied = %d with version %q active, want 3 with v1", report.Applied, g.ActiveVersion())
+	}
+	if location, err := db.Retrieve(net.ParseIP("1.2.3.4")); err != nil || location.City != "Amsterdam" {
+		t.Errorf("Retrieve() from the diffed dataset = %+v, error = %v", location, err)
+	}
+}
@@ -0,0 +1,175 @@
+package geoservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"sync"
+	"time"
+)
+
+var ErrEnrichment = errors.New("enrichment_failed")
+
+// EnrichPolicy decides what Import does with a row whose enrichment failed
+type EnrichPolicy int
+
+const (
+	// EnrichIgnore stores the row with the fields set by the enrichers that succeeded
+	EnrichIgnore EnrichPolicy = iota
+	// EnrichReject discards the row with the reason "enrichment_failed"
+	EnrichReject
+	// EnrichAbort stops the import and returns the error, wrapping ErrEnrichment
+	EnrichAbort
+)
+
+func (p EnrichPolicy) String() string {
+	switch p {
+	case EnrichIgnore:
+		return "ignore"
+	case EnrichReject:
+		return "reject"
+	case EnrichAbort:
+		return "abort"
+	default:
+		return fmt.Sprintf("EnrichPolicy(%d)", int(p))
+	}
+}
+
+// EnrichStage is an enricher of ImportOptions.Enrichers along with what to do when it fails
+type EnrichStage struct {
+	Enricher geolocation.Enricher
+	OnError  EnrichPolicy
+}
+
+// setEnricher adds enricher to the ones run on every location imported or stored through the GeoService, replacing
+// the one of the same name
+func (g *GeoService) setEnricher(enricher geolocation.Enricher) {
+	for i, existing := range g.enrichers {
+		if existing.Name() == enricher.Name() {
+			g.enrichers[i] = enricher
+			return
+		}
+	}
+	g.enrichers = append(g.enrichers, enricher)
+}
+
+// enrich runs the enrichers of the GeoService on location, a failing enricher leaves its fields empty
+func (g *GeoService) enrich(location *geolocation.GeoLocation) {
+	for _, enricher := range g.enrichers {
+		enricher.Enrich(location)
+	}
+}
+
+// enrichStages returns the stages of an Import: the enrichers of the GeoService, whose failures are ignored, followed
+// by stages
+func (g *GeoService) enrichStages(stages []EnrichStage) []EnrichStage {
+	if len(g.enrichers) == 0 {
+		return stages
+	}
+	all := make([]EnrichStage, 0, len(g.enrichers)+len(stages))
+	for _, enricher := range g.enrichers {
+		all = append(all, EnrichStage{Enricher: enricher, OnError: EnrichIgnore})
+	}
+	return append(all, stages...)
+}
+
+// EnrichmentStat describes an enricher of an Import run
+type EnrichmentStat struct {
+	Enriched int `json:"enriched" yaml:"enriched"`
+	Failed   int `json:"failed" yaml:"failed"`
+	// Elapsed is the time spent in the enricher summed over the parse workers
+	Elapsed time.Duration `json:"elapsed" yaml:"elapsed"`
+}
+
+// enrichmentStatText is EnrichmentStat with Elapsed as a string like Statistics
+type enrichmentStatText struct {
+	Enriched int    `json:"enriched" yaml:"enriched"`
+	Failed   int    `json:"failed" yaml:"failed"`
+	Elapsed  string `json:"elapsed" yaml:"elapsed"`
+}
+
+func (s EnrichmentStat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(enrichmentStatText{Enriched: s.Enriched, Failed: s.Failed, Elapsed: s.Elapsed.String()})
+}
+
+func (s *EnrichmentStat) UnmarshalJSON(data []byte) (err error) {
+	var text enrichmentStatText
+	if err = json.Unmarshal(data, &text); err != nil {
+		return
+	}
+
+	*s = EnrichmentStat{Enriched: text.Enriched, Failed: text.Failed}
+	if text.Elapsed != "" {
+		s.Elapsed, err = time.ParseDuration(text.Elapsed)
+	}
+	return
+}
+
+// MarshalYAML writes Elapsed as a string, see Statistics.MarshalYAML
+func (s EnrichmentStat) MarshalYAML() (interface{}, error) {
+	return enrichmentStatText{Enriched: s.Enriched, Failed: s.Failed, Elapsed: s.Elapsed.String()}, nil
+}
+
+// enrichPipeline runs the enrichers of an import, every parse worker counts into its own stats which are merged when it is done
+type enrichPipeline struct {
+	stages []EnrichStage
+	mu     sync.Mutex
+	stats  []EnrichmentStat
+}
+
+func newEnrichPipeline(stages []EnrichStage) *enrichPipeline {
+	return &enrichPipeline{stages: stages, stats: make([]EnrichmentStat, len(stages))}
+}
+
+// workerStats returns the stats a worker passes to run
+func (p *enrichPipeline) workerStats() []EnrichmentStat {
+	return make([]EnrichmentStat, len(p.stages))
+}
+
+// run enriches location with every stage in order. It stops at the first failing stage whose policy isn't EnrichIgnore
+// and returns its error along with the policy.
+func (p *enrichPipeline) run(location *geolocation.GeoLocation, stats []EnrichmentStat) (policy EnrichPolicy, err error) {
+	for i, stage := range p.stages {
+		begin := time.Now()
+		enrichErr := stage.Enricher.Enrich(location)
+		stats[i].Elapsed += time.Now().Sub(begin)
+		if enrichErr == nil {
+			stats[i].Enriched++
+			continue
+		}
+
+		stats[i].Failed++
+		if stage.OnError != EnrichIgnore {
+			return stage.OnError, fmt.Errorf("%w: %s: %v", ErrEnrichment, stage.Enricher.Name(), enrichErr)
+		}
+	}
+	return
+}
+
+// merge adds the stats of a worker
+func (p *enrichPipeline) merge(stats []EnrichmentStat) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, stat := range stats {
+		p.stats[i].Enriched += stat.Enriched
+		p.stats[i].Failed += stat.Failed
+		p.stats[i].Elapsed += stat.Elapsed
+	}
+}
+
+// fill sets Statistics.Enrichment by enricher name
+func (p *enrichPipeline) fill(stat *Statistics) {
+	if len(p.stages) == 0 {
+		return
+	}
+	stat.Enrichment = make(map[string]EnrichmentStat, len(p.stages))
+	for i, stage := range p.stages {
+		name := stage.Enricher.Name()
+		merged := stat.Enrichment[name]
+		merged.Enriched += p.stats[i].Enriched
+		merged.Failed += p.stats[i].Failed
+		merged.Elapsed += p.stats[i].Elapsed
+		stat.Enrichment[name] = merged
+	}
+}
@@ -0,0 +1,105 @@
+package enrich
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/asn"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"github.com/aliforever/geo-service/timezone"
+	"strings"
+)
+
+var (
+	ErrUnknownCountry = errors.New("unknown_country_code")
+	ErrUnknownNetwork = errors.New("unknown_network")
+)
+
+var (
+	// ContinentField holds the continent code set by Continent: AF, AN, AS, EU, NA, OC or SA
+	ContinentField = geolocation.ExtraField[string]("continent")
+	// EUMemberField is set by EUMembership
+	EUMemberField = geolocation.ExtraField[bool]("eu_member")
+	// ASNField holds the autonomous system number set by ASN
+	ASNField = geolocation.ExtraField[uint32]("asn")
+	// ASOrganizationField holds the organization owning the autonomous system, set by ASN
+	ASOrganizationField = geolocation.ExtraField[string]("as_organization")
+)
+
+type funcEnricher struct {
+	name   string
+	enrich func(location *geolocation.GeoLocation) error
+}
+
+func (f funcEnricher) Name() string {
+	return f.name
+}
+
+func (f funcEnricher) Enrich(location *geolocation.GeoLocation) error {
+	return f.enrich(location)
+}
+
+// Func turns fn into an Enricher called name
+func Func(name string, fn func(location *geolocation.GeoLocation) error) geolocation.Enricher {
+	return funcEnricher{name: name, enrich: fn}
+}
+
+// Geohash sets GeoLocation.Geohash with precision characters, out of range precisions are clamped
+func Geohash(precision int) geolocation.Enricher {
+	return Func("geohash", func(location *geolocation.GeoLocation) error {
+		location.Geohash = spatial.EncodeGeohash(location.Latitude, location.Longitude, precision)
+		return nil
+	})
+}
+
+// Timezone sets GeoLocation.Timezone with resolver, a nil resolver uses timezone.Embedded
+func Timezone(resolver *timezone.Resolver) geolocation.Enricher {
+	if resolver == nil {
+		resolver = timezone.Embedded()
+	}
+	return Func("timezone", func(location *geolocation.GeoLocation) (err error) {
+		zone, err := resolver.Resolve(location.Latitude, location.Longitude, location.CountryCode)
+		if err != nil {
+			return
+		}
+		location.Timezone = zone.ID
+		return
+	})
+}
+
+// Continent sets ContinentField from the country code, it fails with ErrUnknownCountry for unknown codes
+func Continent() geolocation.Enricher {
+	return Func("continent", func(location *geolocation.GeoLocation) error {
+		continent, ok := continents[strings.ToUpper(location.CountryCode)]
+		if !ok {
+			return ErrUnknownCountry
+		}
+		return ContinentField.Set(location, continent)
+	})
+}
+
+// EUMembership sets EUMemberField from the country code, it fails with ErrUnknownCountry for unknown codes
+func EUMembership() geolocation.Enricher {
+	return Func("eu_member", func(location *geolocation.GeoLocation) error {
+		code := strings.ToUpper(location.CountryCode)
+		if _, ok := continents[code]; !ok {
+			return ErrUnknownCountry
+		}
+		_, member := euMembers[code]
+		return EUMemberField.Set(location, member)
+	})
+}
+
+// ASN sets ASNField and ASOrganizationField from the most specific network of table containing the IP address,
+// it fails with ErrUnknownNetwork for addresses outside every network
+func ASN(table *asn.Table) geolocation.Enricher {
+	return Func("asn", func(location *geolocation.GeoLocation) (err error) {
+		network, ok := table.Lookup(location.IPAddress)
+		if !ok {
+			return ErrUnknownNetwork
+		}
+		if err = ASNField.Set(location, network.ASN); err != nil {
+			return
+		}
+		return ASOrganizationField.Set(location, network.Organization)
+	})
+}
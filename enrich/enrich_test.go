@@ -0,0 +1,98 @@
+package enrich
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/asn"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCountryEnrichers(t *testing.T) {
+	tests := []struct {
+		countryCode   string
+		wantContinent string
+		wantMember    bool
+		wantErr       error
+	}{
+		{countryCode: "AT", wantContinent: "EU", wantMember: true},
+		{countryCode: "ch", wantContinent: "EU"},
+		{countryCode: "TL", wantContinent: "OC"},
+		{countryCode: "US", wantContinent: "NA"},
+		{countryCode: "AQ", wantContinent: "AN"},
+		{countryCode: "", wantErr: ErrUnknownCountry},
+		{countryCode: "XX", wantErr: ErrUnknownCountry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.countryCode, func(t *testing.T) {
+			location := &geolocation.GeoLocation{CountryCode: tt.countryCode}
+			if err := Continent().Enrich(location); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Continent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := EUMembership().Enrich(location); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EUMembership() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(location.Extra) != 0 {
+					t.Errorf("Extra = %s, want none", location.Extra)
+				}
+				return
+			}
+
+			if continent, _ := ContinentField.Get(location); continent != tt.wantContinent {
+				t.Errorf("ContinentField = %s, want %s", continent, tt.wantContinent)
+			}
+			if member, ok := EUMemberField.Get(location); !ok || member != tt.wantMember {
+				t.Errorf("EUMemberField = %v, want %v", member, tt.wantMember)
+			}
+		})
+	}
+}
+
+func TestLocationEnrichers(t *testing.T) {
+	location := &geolocation.GeoLocation{CountryCode: "AT", Latitude: 48.2, Longitude: 16.37}
+	for _, enricher := range []geolocation.Enricher{Geohash(5), Timezone(nil)} {
+		if err := enricher.Enrich(location); err != nil {
+			t.Fatalf("%s error = %v", enricher.Name(), err)
+		}
+	}
+	if location.Geohash != "u2edh" || location.Timezone != "Europe/Vienna" {
+		t.Errorf("Enrich() = %s, %s, want u2edh, Europe/Vienna", location.Geohash, location.Timezone)
+	}
+
+	if err := Timezone(nil).Enrich(&geolocation.GeoLocation{Latitude: 95}); err == nil {
+		t.Errorf("Timezone() error = nil, want an error for unusable coordinates")
+	}
+}
+
+func TestASN(t *testing.T) {
+	table, err := asn.LoadCSV(strings.NewReader("network,asn,org\n1.1.0.0/16,64501,Sixteen\n1.1.1.0/24,13335,Cloudflare\n"))
+	if err != nil {
+		t.Fatalf("LoadCSV() error = %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		wantASN uint32
+		wantOrg string
+		wantErr error
+	}{
+		{ip: "1.1.1.1", wantASN: 13335, wantOrg: "Cloudflare"},
+		{ip: "1.1.2.1", wantASN: 64501, wantOrg: "Sixteen"},
+		{ip: "8.8.8.8", wantErr: ErrUnknownNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			location := &geolocation.GeoLocation{IPAddress: net.ParseIP(tt.ip)}
+			if err := ASN(table).Enrich(location); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ASN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			number, _ := ASNField.Get(location)
+			organization, _ := ASOrganizationField.Get(location)
+			if number != tt.wantASN || organization != tt.wantOrg {
+				t.Errorf("ASN() = %d, %s, want %d, %s", number, organization, tt.wantASN, tt.wantOrg)
+			}
+		})
+	}
+}
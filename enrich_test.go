@@ -0,0 +1,127 @@
+package geoservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aliforever/geo-service/enrich"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"testing"
+)
+
+var taggedField = geolocation.ExtraField[string]("tag")
+
+// tagger tags every location but those of TL
+var tagger = enrich.Func("tag", func(location *geolocation.GeoLocation) error {
+	if location.CountryCode == "TL" {
+		return errors.New("untaggable")
+	}
+	return taggedField.Set(location, location.CountryCode+"-"+location.City)
+})
+
+func TestGeoService_ImportEnrichers(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        EnrichPolicy
+		wantErr       error
+		wantAccepted  int
+		wantDiscarded int
+		wantStored    []string
+		wantMissing   []string
+	}{
+		{
+			name:          "Ignore",
+			policy:        EnrichIgnore,
+			wantAccepted:  4,
+			wantDiscarded: 1,
+			wantStored:    []string{"200.106.141.15", "160.103.7.140", "70.95.73.73", "125.159.20.54"},
+		},
+		{
+			name:          "Reject",
+			policy:        EnrichReject,
+			wantAccepted:  3,
+			wantDiscarded: 2,
+			wantStored:    []string{"200.106.141.15", "160.103.7.140", "125.159.20.54"},
+			wantMissing:   []string{"70.95.73.73"},
+		},
+		{
+			name:    "Abort",
+			policy:  EnrichAbort,
+			wantErr: ErrEnrichment,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			g := NewGeoService(db)
+			stat, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{
+				Workers: 2,
+				Enrichers: []EnrichStage{
+					{Enricher: enrich.Continent(), OnError: EnrichAbort},
+					{Enricher: tagger, OnError: tt.policy},
+				},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if stat.AcceptedEntries != tt.wantAccepted || stat.DiscardedEntries != tt.wantDiscarded {
+				t.Errorf("Import() accepted %d, discarded %d, want %d, %d", stat.AcceptedEntries, stat.DiscardedEntries, tt.wantAccepted, tt.wantDiscarded)
+			}
+			if tt.policy == EnrichReject && stat.DiscardReasons["enrichment_failed"] != 1 {
+				t.Errorf("Import() DiscardReasons = %v, want enrichment_failed", stat.DiscardReasons)
+			}
+
+			// the duplicate row is enriched too since enrichers run before deduplication
+			if got := stat.Enrichment["continent"]; got.Enriched != 5 || got.Failed != 0 {
+				t.Errorf("Import() Enrichment[continent] = %+v, want 5 enriched", got)
+			}
+			if got := stat.Enrichment["tag"]; got.Enriched != 4 || got.Failed != 1 {
+				t.Errorf("Import() Enrichment[tag] = %+v, want 4 enriched and 1 failed", got)
+			}
+
+			for _, ip := range tt.wantStored {
+				location, err := db.Retrieve(net.ParseIP(ip))
+				if err != nil {
+					t.Fatalf("Retrieve(%s) error = %v", ip, err)
+				}
+				if continent, ok := enrich.ContinentField.Get(location); !ok || continent == "" {
+					t.Errorf("Retrieve(%s) continent = %q, want one", ip, continent)
+				}
+				tag, tagged := taggedField.Get(location)
+				if tagged != (location.CountryCode != "TL") || (tagged && tag != location.CountryCode+"-"+location.City) {
+					t.Errorf("Retrieve(%s) tag = %q, %v", ip, tag, tagged)
+				}
+			}
+			for _, ip := range tt.wantMissing {
+				if _, err := db.Retrieve(net.ParseIP(ip)); !errors.Is(err, geolocation.ErrNotFound) {
+					t.Errorf("Retrieve(%s) error = %v, want %v", ip, err, geolocation.ErrNotFound)
+				}
+			}
+		})
+	}
+}
+
+func TestEnrichmentStat_JSON(t *testing.T) {
+	stat := Statistics{Enrichment: map[string]EnrichmentStat{"tag": {Enriched: 4, Failed: 1, Elapsed: 1500000}}}
+	data, err := json.Marshal(stat)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"tag":{"enriched":4,"failed":1,"elapsed":"1.5ms"}`) {
+		t.Fatalf("Marshal() = %s, want the enrichment with a string duration", data)
+	}
+
+	var decoded Statistics
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Enrichment["tag"] != stat.Enrichment["tag"] {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded.Enrichment, stat.Enrichment)
+	}
+}
@@ -0,0 +1,108 @@
+package geoservice
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownFormat = errors.New("unknown_format")
+
+type ExportFormat string
+
+const (
+	// ExportCSV writes the same CSV format ParseCSV and Import read
+	ExportCSV ExportFormat = "csv"
+	// ExportJSON writes a JSON array of locations
+	ExportJSON ExportFormat = "json"
+	// ExportJSONLines writes a location JSON per line
+	ExportJSONLines ExportFormat = "jsonl"
+)
+
+// csvField quotes values containing commas the way getColumns expects them
+func csvField(value string) string {
+	if strings.Contains(value, ",") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func csvRow(location *geolocation.GeoLocation) string {
+	return strings.Join([]string{
+		location.IPAddress.String(),
+		location.CountryCode,
+		csvField(location.Country),
+		csvField(location.City),
+		strconv.FormatFloat(location.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(location.Longitude, 'f', -1, 64),
+		strconv.FormatInt(location.MysteryValue, 10),
+	}, ",")
+}
+
+// Export writes every location of the active dataset to w, the Repository has to implement geolocation.Iterable
+func (g *GeoService) Export(w io.Writer, format ExportFormat) (count int, err error) {
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	iterable, ok := geolocation.As[geolocation.Iterable](dataset.Repository)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+
+	bw := bufio.NewWriter(w)
+	var write func(*geolocation.GeoLocation) error
+	switch format {
+	case ExportCSV:
+		bw.WriteString("ip_address,country_code,country,city,latitude,longitude,mystery_value\n")
+		write = func(location *geolocation.GeoLocation) (err error) {
+			_, err = bw.WriteString(csvRow(location) + "\n")
+			return
+		}
+	case ExportJSONLines:
+		encoder := json.NewEncoder(bw)
+		write = func(location *geolocation.GeoLocation) error {
+			return encoder.Encode(location)
+		}
+	case ExportJSON:
+		bw.WriteString("[")
+		write = func(location *geolocation.GeoLocation) (err error) {
+			if count > 0 {
+				bw.WriteString(",")
+			}
+			var data []byte
+			if data, err = json.Marshal(location); err != nil {
+				return
+			}
+			_, err = bw.Write(data)
+			return
+		}
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+		return
+	}
+
+	eachErr := iterable.Each(func(location *geolocation.GeoLocation) bool {
+		if err = write(location); err != nil {
+			return false
+		}
+		count++
+		return true
+	})
+	if err == nil {
+		err = eachErr
+	}
+	if err != nil {
+		return
+	}
+
+	if format == ExportJSON {
+		bw.WriteString("]\n")
+	}
+	err = bw.Flush()
+	return
+}
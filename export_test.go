@@ -0,0 +1,89 @@
+package geoservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestGeoService_Export(t *testing.T) {
+	source := NewGeoService(memory.New())
+	if _, err := source.Import(context.Background(), strings.NewReader(importCSV+
+		`152.159.31.208,GA,"Virgin Islands, British",Lake Wavatown,12.964804277773922,-56.656208830174734,1878158074`+"\n"), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		format  ExportFormat
+		check   func(t *testing.T, data []byte)
+		wantErr error
+	}{
+		{
+			name:   "CSV",
+			format: ExportCSV,
+			check: func(t *testing.T, data []byte) {
+				// The export imports back into the same locations
+				g := NewGeoService(memory.New())
+				stat, err := g.Import(context.Background(), bytes.NewReader(data), ImportOptions{})
+				if err != nil || stat.StoredEntries != 5 || stat.DiscardedEntries != 0 {
+					t.Fatalf("Import() exported CSV stat = %+v, error = %v", stat, err)
+				}
+				location, _ := g.RetrieveLocation(net.ParseIP("152.159.31.208"))
+				if location == nil || location.Country != "Virgin Islands, British" || location.Longitude != -56.656208830174734 {
+					t.Errorf("RetrieveLocation() = %+v", location)
+				}
+			},
+		},
+		{
+			name:   "JSON",
+			format: ExportJSON,
+			check: func(t *testing.T, data []byte) {
+				var locations []*geolocation.GeoLocation
+				if err := json.Unmarshal(data, &locations); err != nil || len(locations) != 5 {
+					t.Errorf("Export() JSON = %s, error = %v", data, err)
+				}
+			},
+		},
+		{
+			name:   "JSONLines",
+			format: ExportJSONLines,
+			check: func(t *testing.T, data []byte) {
+				if lines := strings.Count(string(data), "\n"); lines != 5 {
+					t.Errorf("Export() JSON lines = %d, want 5", lines)
+				}
+			},
+		},
+		{
+			name:    "Unknown",
+			format:  "xml",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			count, err := source.Export(&b, tt.format)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check == nil {
+				return
+			}
+			if count != 5 {
+				t.Errorf("Export() count = %d, want 5", count)
+			}
+			tt.check(t, b.Bytes())
+		})
+	}
+
+	if _, err := NewGeoService(newTestDB()).Export(&bytes.Buffer{}, ExportCSV); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("Export() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+}
@@ -0,0 +1,401 @@
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+var (
+	// ErrCorrupt is returned by Open for a log with an invalid record before its last line
+	ErrCorrupt = errors.New("corrupt log")
+
+	errExists = errors.New("data exists")
+	errClosed = errors.New("repository closed")
+)
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// record is a line of the log
+type record struct {
+	Op        string                   `json:"op"`
+	Location  *geolocation.GeoLocation `json:"location,omitempty"`
+	IPAddress net.IP                   `json:"ip_address,omitempty"`
+}
+
+// Repository persists locations in an append-only JSON lines log which is replayed into memory on Open.
+// Every write is flushed to the log before it becomes visible, Sync makes it durable.
+type Repository struct {
+	// mu serializes writes so the log and the memory state change in the same order
+	mu   sync.Mutex
+	db   *memory.Repository
+	path string
+	file *os.File
+	// size is the offset where the next record is written, the end of the last complete record
+	size int64
+	// failed is set when a failed write couldn't be undone, every later write returns it
+	failed error
+}
+
+// Open loads the log at path, creating it if it doesn't exist.
+// A partially written last line without a line break, left by a crash in the middle of a write, is truncated.
+// An invalid record anywhere else fails with ErrCorrupt along with its offset.
+func Open(path string) (r *Repository, err error) {
+	r = &Repository{db: memory.New(), path: path}
+
+	r.file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+
+	if r.size, err = r.replay(); err != nil {
+		r.file.Close()
+		return
+	}
+	if err = r.file.Truncate(r.size); err != nil {
+		r.file.Close()
+		return
+	}
+	if _, err = r.file.Seek(r.size, io.SeekStart); err != nil {
+		r.file.Close()
+		return
+	}
+	return
+}
+
+// replay applies every complete record of the log and returns the offset where they end, which is before a partially
+// written last line
+func (r *Repository) replay() (valid int64, err error) {
+	reader := bufio.NewReader(r.file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+			}
+			return
+		}
+
+		var rec record
+		if unmarshalErr := json.Unmarshal(bytes.TrimSpace(line), &rec); unmarshalErr != nil {
+			err = fmt.Errorf("%w: record at offset %d: %v", ErrCorrupt, valid, unmarshalErr)
+			return
+		}
+		switch {
+		case rec.Op == opPut && rec.Location != nil:
+			r.db.Upsert(rec.Location)
+		case rec.Op == opDelete:
+			r.db.Delete(rec.IPAddress)
+		default:
+			err = fmt.Errorf("%w: record at offset %d: unknown op %q", ErrCorrupt, valid, rec.Op)
+			return
+		}
+		valid += int64(len(line))
+	}
+}
+
+// append writes records to the log at once. When the write fails the log is cut back to its previous size, so the
+// next record doesn't follow a partial one.
+func (r *Repository) append(records ...record) (err error) {
+	if r.file == nil {
+		err = errClosed
+		return
+	}
+	if r.failed != nil {
+		err = r.failed
+		return
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err = encoder.Encode(rec); err != nil {
+			return
+		}
+	}
+	if _, err = r.file.Write(buf.Bytes()); err != nil {
+		if undoErr := r.truncate(); undoErr != nil {
+			r.failed = fmt.Errorf("log left with a partial record: %w", undoErr)
+		}
+		return
+	}
+	r.size += int64(buf.Len())
+	return
+}
+
+// truncate cuts the log back to size
+func (r *Repository) truncate() (err error) {
+	if err = r.file.Truncate(r.size); err != nil {
+		return
+	}
+	_, err = r.file.Seek(r.size, io.SeekStart)
+	return
+}
+
+func (r *Repository) Store(g *geolocation.GeoLocation) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, retrieveErr := r.db.Retrieve(g.IPAddress); retrieveErr == nil {
+		err = errExists
+		return
+	}
+	if err = r.append(record{Op: opPut, Location: g}); err != nil {
+		return
+	}
+	err = r.db.Store(g)
+	return
+}
+
+// StoreMany stores every location it can and reports the existing ones in a geolocation.BatchError
+func (r *Repository) StoreMany(gs []*geolocation.GeoLocation) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batchErr := &geolocation.BatchError{Errors: map[int]error{}}
+	seen := map[string]struct{}{}
+	var records []record
+	var stored []*geolocation.GeoLocation
+	for index, g := range gs {
+		key := g.IPAddress.String()
+		_, pending := seen[key]
+		if _, retrieveErr := r.db.Retrieve(g.IPAddress); retrieveErr == nil || pending {
+			batchErr.Errors[index] = errExists
+			continue
+		}
+		seen[key] = struct{}{}
+		records = append(records, record{Op: opPut, Location: g})
+		stored = append(stored, g)
+	}
+
+	if err = r.append(records...); err != nil {
+		return
+	}
+	for _, g := range stored {
+		r.db.Upsert(g)
+	}
+
+	if len(batchErr.Errors) > 0 {
+		err = batchErr
+	}
+	return
+}
+
+func (r *Repository) Retrieve(ip net.IP) (*geolocation.GeoLocation, error) {
+	return r.db.Retrieve(ip)
+}
+
+func (r *Repository) Upsert(g *geolocation.GeoLocation) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.append(record{Op: opPut, Location: g}); err != nil {
+		return
+	}
+	err = r.db.Upsert(g)
+	return
+}
+
+func (r *Repository) Delete(ip net.IP) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.append(record{Op: opDelete, IPAddress: ip}); err != nil {
+		return
+	}
+	err = r.db.Delete(ip)
+	return
+}
+
+// Each calls fn for every stored location in no particular order, fn must not write to the repository
+func (r *Repository) Each(fn func(*geolocation.GeoLocation) bool) error {
+	return r.db.Each(fn)
+}
+
+// Len returns the number of stored locations
+func (r *Repository) Len() int {
+	return r.db.Len()
+}
+
+// FindBy queries the secondary indexes, which are built from the replayed log on the first query
+func (r *Repository) FindBy(field geolocation.IndexField, key string, page geolocation.Page) ([]*geolocation.GeoLocation, int, error) {
+	return r.db.FindBy(field, key, page)
+}
+
+// CountBy returns the number of locations per key of a secondary index
+func (r *Repository) CountBy(field geolocation.IndexField) ([]geolocation.KeyCount, error) {
+	return r.db.CountBy(field)
+}
+
+// Sync commits the log to stable storage
+func (r *Repository) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return errClosed
+	}
+	return r.file.Sync()
+}
+
+// Compact rewrites the log with a single record per stored location, dropping replaced and deleted ones
+func (r *Repository) Compact() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		err = errClosed
+		return
+	}
+
+	var tmp *os.File
+	if tmp, err = os.Create(r.path + ".compact"); err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	r.db.Each(func(g *geolocation.GeoLocation) bool {
+		err = encoder.Encode(record{Op: opPut, Location: g})
+		return err == nil
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+
+	if err = os.Rename(tmp.Name(), r.path); err != nil {
+		return
+	}
+
+	r.file.Close()
+	if r.file, err = os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		r.file = nil
+		return
+	}
+	var info os.FileInfo
+	if info, err = r.file.Stat(); err != nil {
+		r.file.Close()
+		r.file = nil
+		return
+	}
+	r.size, r.failed = info.Size(), nil
+	return
+}
+
+// Close syncs and closes the log
+func (r *Repository) Close() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return
+	}
+
+	err = r.file.Sync()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return
+}
+
+// Begin starts a transaction buffering locations until Commit, which appends them to the log at once
+func (r *Repository) Begin() (geolocation.Transaction, error) {
+	return &transaction{db: r, data: map[string]*geolocation.GeoLocation{}}, nil
+}
+
+type transaction struct {
+	sync.Mutex
+	db *Repository
+	// data is nil once the transaction is done
+	data map[string]*geolocation.GeoLocation
+}
+
+func (t *transaction) StoreMany(gs []*geolocation.GeoLocation) (err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.data == nil {
+		err = geolocation.ErrTransactionDone
+		return
+	}
+
+	batchErr := &geolocation.BatchError{Errors: map[int]error{}}
+	for index, g := range gs {
+		key := g.IPAddress.String()
+		_, pending := t.data[key]
+		if _, retrieveErr := t.db.Retrieve(g.IPAddress); retrieveErr == nil || pending {
+			batchErr.Errors[index] = errExists
+			continue
+		}
+		t.data[key] = g
+	}
+
+	if len(batchErr.Errors) > 0 {
+		err = batchErr
+	}
+	return
+}
+
+func (t *transaction) Commit() (err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.data == nil {
+		err = geolocation.ErrTransactionDone
+		return
+	}
+
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	records := make([]record, 0, len(t.data))
+	for _, g := range t.data {
+		if _, retrieveErr := t.db.db.Retrieve(g.IPAddress); retrieveErr == nil {
+			err = errExists
+			return
+		}
+		records = append(records, record{Op: opPut, Location: g})
+	}
+
+	if err = t.db.append(records...); err != nil {
+		return
+	}
+	for _, g := range t.data {
+		t.db.db.Upsert(g)
+	}
+	t.data = nil
+	return
+}
+
+func (t *transaction) Rollback() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.data == nil {
+		return geolocation.ErrTransactionDone
+	}
+	t.data = nil
+	return nil
+}
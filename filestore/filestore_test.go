@@ -0,0 +1,208 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func location(ip, city string) *geolocation.GeoLocation {
+	return &geolocation.GeoLocation{IPAddress: net.ParseIP(ip), CountryCode: "CZ", Country: "Nicaragua", City: city}
+}
+
+func TestRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.jsonl")
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err = r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.1", "A"), location("10.0.0.2", "B"), location("10.0.0.3", "C")}); err != nil {
+		t.Fatalf("StoreMany() error = %v", err)
+	}
+	var batchErr *geolocation.BatchError
+	if err = r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.1", "A"), location("10.0.0.4", "D")}); !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 {
+		t.Fatalf("StoreMany() error = %v, want a BatchError for the existing location", err)
+	}
+	r.Upsert(location("10.0.0.2", "B2"))
+	r.Delete(net.ParseIP("10.0.0.3"))
+	if err = r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// A crash in the middle of a write leaves a partial line behind
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString(`{"op":"put","location":{"ip_addr`)
+	file.Close()
+
+	tests := []struct {
+		name string
+		open func() (*Repository, error)
+	}{
+		{name: "Replayed", open: func() (*Repository, error) { return Open(path) }},
+		{name: "Compacted", open: func() (*Repository, error) {
+			r, err := Open(path)
+			if err != nil {
+				return nil, err
+			}
+			if err = r.Compact(); err != nil {
+				return nil, err
+			}
+			r.Close()
+			return Open(path)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.open()
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer r.Close()
+
+			if r.Len() != 3 {
+				t.Errorf("Len() = %d, want 3", r.Len())
+			}
+			if got, _ := r.Retrieve(net.ParseIP("10.0.0.2")); got == nil || got.City != "B2" {
+				t.Errorf("Retrieve() upserted location = %v", got)
+			}
+			if _, err = r.Retrieve(net.ParseIP("10.0.0.3")); !errors.Is(err, geolocation.ErrNotFound) {
+				t.Errorf("Retrieve() deleted location error = %v", err)
+			}
+
+			// The log keeps accepting writes after the partial line was dropped
+			if err = r.Store(location("10.0.0.5", "E")); err != nil {
+				t.Errorf("Store() error = %v", err)
+			}
+			r.Delete(net.ParseIP("10.0.0.5"))
+		})
+	}
+}
+
+func TestOpen_Corrupt(t *testing.T) {
+	put := `{"op":"put","location":{"ip_address":"10.0.0.1","country_code":"CZ"}}` + "\n"
+	tests := []struct {
+		name       string
+		log        string
+		wantOffset string
+	}{
+		{name: "InvalidJSON", log: put + "{garbage\n" + put, wantOffset: fmt.Sprintf("offset %d", len(put))},
+		{name: "UnknownOp", log: put + `{"op":"move"}` + "\n", wantOffset: fmt.Sprintf("offset %d", len(put))},
+		{name: "FirstLine", log: "\n" + put, wantOffset: "offset 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "locations.jsonl")
+			if err := os.WriteFile(path, []byte(tt.log), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			_, err := Open(path)
+			if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), tt.wantOffset) {
+				t.Fatalf("Open() error = %v, want %v at %s", err, ErrCorrupt, tt.wantOffset)
+			}
+			// The records after the corrupt one are kept
+			if data, _ := os.ReadFile(path); string(data) != tt.log {
+				t.Errorf("Open() changed the log to %q", data)
+			}
+		})
+	}
+}
+
+func TestRepository_WriteFailure(t *testing.T) {
+	r, err := Open(filepath.Join(t.TempDir(), "locations.jsonl"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	r.Store(location("10.0.0.1", "A"))
+
+	// The log can neither be written nor cut back, so the repository refuses every further write
+	r.file.Close()
+	if err = r.Store(location("10.0.0.2", "B")); err == nil {
+		t.Fatalf("Store() error = nil, want the write error")
+	}
+	if _, err = r.Retrieve(net.ParseIP("10.0.0.2")); !errors.Is(err, geolocation.ErrNotFound) {
+		t.Errorf("Retrieve() of the failed location error = %v, want %v", err, geolocation.ErrNotFound)
+	}
+	if r.failed == nil {
+		t.Fatalf("failed = nil after a write that couldn't be undone")
+	}
+	if err = r.Delete(net.ParseIP("10.0.0.1")); !errors.Is(err, r.failed) {
+		t.Errorf("Delete() error = %v, want %v", err, r.failed)
+	}
+	if got, _ := r.Retrieve(net.ParseIP("10.0.0.1")); got == nil {
+		t.Errorf("Retrieve() = nil, the failed Delete removed the location")
+	}
+}
+
+func TestRepository_FindBy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.jsonl")
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.3", "Zürich"), location("10.0.0.1", "Zurich"), location("10.0.0.2", "Bern")})
+	r.Close()
+
+	if r, err = Open(path); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+
+	locations, total, err := r.FindBy(geolocation.FieldCity, "ZURICH", geolocation.Page{Limit: 1})
+	if err != nil || total != 2 || len(locations) != 1 || locations[0].IPAddress.String() != "10.0.0.1" {
+		t.Fatalf("FindBy() = %v, total = %d, error = %v", locations, total, err)
+	}
+
+	// writes after the indexes were built keep them up to date
+	r.Upsert(location("10.0.0.1", "Basel"))
+	r.Delete(net.ParseIP("10.0.0.2"))
+	counts, err := r.CountBy(geolocation.FieldCity)
+	want := []geolocation.KeyCount{{Key: "basel", Value: "Basel", Count: 1}, {Key: "zurich", Value: "Zürich", Count: 1}}
+	if err != nil || len(counts) != len(want) || counts[0] != want[0] || counts[1] != want[1] {
+		t.Errorf("CountBy() = %+v, error = %v, want %+v", counts, err, want)
+	}
+
+	if _, _, err = r.FindBy("asn", "1", geolocation.Page{}); !errors.Is(err, geolocation.ErrUnknownField) {
+		t.Errorf("FindBy() error = %v, want %v", err, geolocation.ErrUnknownField)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.jsonl")
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	tx, _ := r.Begin()
+	tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.1", "A"), location("10.0.0.2", "B")})
+	if r.Len() != 0 {
+		t.Errorf("Len() = %d before Commit(), want 0", r.Len())
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err = tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.3", "C")}); !errors.Is(err, geolocation.ErrTransactionDone) {
+		t.Errorf("StoreMany() after Commit() error = %v, want %v", err, geolocation.ErrTransactionDone)
+	}
+	if err = tx.Rollback(); !errors.Is(err, geolocation.ErrTransactionDone) {
+		t.Errorf("Rollback() after Commit() error = %v, want %v", err, geolocation.ErrTransactionDone)
+	}
+	r.Close()
+
+	// Committed locations are in the log
+	if r, err = Open(path); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+	if r.Len() != 2 {
+		t.Errorf("Len() after reopening = %d, want 2", r.Len())
+	}
+}
@@ -0,0 +1,108 @@
+package geoservice
+
+import (
+	"github.com/aliforever/geo-service/geocode"
+	"github.com/aliforever/geo-service/geolocation"
+	"strings"
+)
+
+// DefaultFillCityDistanceKm is the default FillCityOptions.MaxDistanceKm
+const DefaultFillCityDistanceKm = 100
+
+// FillCityOptions fills the empty City of imported rows with the closest place of a gazetteer.
+// Country and CountryCode are filled too when empty, a row whose CountryCode differs from the place's is left as is.
+type FillCityOptions struct {
+	// Gazetteer defaults to the one given to WithGazetteer, then to geocode.Embedded
+	Gazetteer *geocode.Gazetteer
+	// MaxDistanceKm leaves the city empty when the closest place is farther, defaults to DefaultFillCityDistanceKm
+	MaxDistanceKm float64
+}
+
+// WithGazetteer sets the gazetteer of ReverseGeocode and FillCityOptions, geocode.Embedded is used otherwise
+func WithGazetteer(gazetteer *geocode.Gazetteer) Option {
+	return func(g *GeoService) {
+		g.gazetteer = gazetteer
+	}
+}
+
+func (g *GeoService) geocoder() *geocode.Gazetteer {
+	if g.gazetteer == nil {
+		return geocode.Embedded()
+	}
+	return g.gazetteer
+}
+
+// cityFiller fills empty cities during an import
+type cityFiller struct {
+	gazetteer     *geocode.Gazetteer
+	maxDistanceKm float64
+}
+
+// newCityFiller returns nil when opts is nil
+func (g *GeoService) newCityFiller(opts *FillCityOptions) *cityFiller {
+	if opts == nil {
+		return nil
+	}
+	filler := &cityFiller{gazetteer: opts.Gazetteer, maxDistanceKm: opts.MaxDistanceKm}
+	if filler.gazetteer == nil {
+		filler.gazetteer = g.geocoder()
+	}
+	if filler.maxDistanceKm <= 0 {
+		filler.maxDistanceKm = DefaultFillCityDistanceKm
+	}
+	return filler
+}
+
+// fill sets the city of location when it is empty and reports whether it did
+func (f *cityFiller) fill(location *geolocation.GeoLocation) bool {
+	if f == nil || location.City != "" {
+		return false
+	}
+
+	match, err := f.gazetteer.Reverse(location.Latitude, location.Longitude)
+	if err != nil || match.DistanceKm > f.maxDistanceKm {
+		return false
+	}
+	if location.CountryCode != "" && !strings.EqualFold(location.CountryCode, match.Place.CountryCode) {
+		return false
+	}
+
+	location.City = match.Place.Name
+	if location.CountryCode == "" {
+		location.CountryCode = match.Place.CountryCode
+	}
+	if location.Country == "" {
+		location.Country = match.Place.Country
+	}
+	return true
+}
+
+// ReverseGeocode returns the place of the gazetteer given to WithGazetteer, or of geocode.Embedded, closest to lat, lng
+func (g *GeoService) ReverseGeocode(lat, lng float64) (match geocode.Match, err error) {
+	return g.geocoder().Reverse(lat, lng)
+}
+
+// DatasetGazetteer returns a gazetteer of the cities of the active dataset, see geocode.FromLocations.
+// The repository must be geolocation.Iterable, the gazetteer is rebuilt after writes like the spatial indexes.
+func (g *GeoService) DatasetGazetteer() (gazetteer *geocode.Gazetteer, err error) {
+	g.spatialMu.Lock()
+	defer g.spatialMu.Unlock()
+
+	cache, err := g.spatialCache()
+	if err != nil {
+		return
+	}
+	if cache.gazetteer == nil {
+		cache.gazetteer = geocode.FromLocations(cache.locations)
+	}
+	return cache.gazetteer, nil
+}
+
+// ReverseGeocodeDataset returns the city of the active dataset closest to lat, lng
+func (g *GeoService) ReverseGeocodeDataset(lat, lng float64) (match geocode.Match, err error) {
+	gazetteer, err := g.DatasetGazetteer()
+	if err != nil {
+		return
+	}
+	return gazetteer.Reverse(lat, lng)
+}
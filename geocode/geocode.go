@@ -0,0 +1,223 @@
+package geocode
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrEmptyGazetteer = errors.New("empty_gazetteer")
+
+//go:embed cities.csv
+var embeddedCities string
+
+// Place is a named point of a gazetteer
+type Place struct {
+	Name        string  `json:"name"`
+	CountryCode string  `json:"country_code"`
+	Country     string  `json:"country"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Population  int64   `json:"population,omitempty"`
+}
+
+// Match is the place closest to a reverse geocoded point
+type Match struct {
+	Place      Place   `json:"place"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// Gazetteer answers reverse geocoding queries with a k-d tree of its places, it is immutable and safe for concurrent use
+type Gazetteer struct {
+	places []Place
+	tree   *spatial.Tree[Place]
+}
+
+func placePosition(place Place) (lat, lng float64) {
+	return place.Latitude, place.Longitude
+}
+
+// New builds a gazetteer over places, places with invalid coordinates are skipped
+func New(places []Place) *Gazetteer {
+	return &Gazetteer{places: places, tree: spatial.NewTree(places, placePosition)}
+}
+
+var embedded = sync.OnceValue(func() *Gazetteer {
+	gazetteer, err := LoadCSV(strings.NewReader(embeddedCities))
+	if err != nil {
+		panic(fmt.Sprintf("embedded gazetteer: %v", err))
+	}
+	return gazetteer
+})
+
+// Embedded returns the built-in gazetteer of about 500 capitals and major cities.
+// It resolves points to the closest large city, load a GeoNames dump for town level results.
+func Embedded() *Gazetteer {
+	return embedded()
+}
+
+// Len returns the number of places
+func (g *Gazetteer) Len() int {
+	return g.tree.Len()
+}
+
+// Places returns every place of the gazetteer, e.g. to combine gazetteers with New
+func (g *Gazetteer) Places() []Place {
+	return g.places
+}
+
+// Reverse returns the place closest to lat, lng
+func (g *Gazetteer) Reverse(lat, lng float64) (match Match, err error) {
+	neighbors, err := g.tree.Nearest(lat, lng, 1)
+	if err != nil {
+		return
+	}
+	if len(neighbors) == 0 {
+		err = ErrEmptyGazetteer
+		return
+	}
+	match = Match{Place: neighbors[0].Value, DistanceKm: neighbors[0].DistanceKm}
+	return
+}
+
+// Nearest returns the k places closest to lat, lng ordered by distance
+func (g *Gazetteer) Nearest(lat, lng float64, k int) (matches []Match, err error) {
+	neighbors, err := g.tree.Nearest(lat, lng, k)
+	for _, neighbor := range neighbors {
+		matches = append(matches, Match{Place: neighbor.Value, DistanceKm: neighbor.DistanceKm})
+	}
+	return
+}
+
+// LoadCSV reads places from a CSV with the header name,country_code,country,latitude,longitude,population
+func LoadCSV(r io.Reader) (gazetteer *Gazetteer, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 6
+
+	if _, err = reader.Read(); err != nil {
+		return
+	}
+
+	var places []Place
+	for {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			err = readErr
+			return
+		}
+
+		place := Place{Name: record[0], CountryCode: record[1], Country: record[2]}
+		if place.Latitude, err = strconv.ParseFloat(record[3], 64); err != nil {
+			return
+		}
+		if place.Longitude, err = strconv.ParseFloat(record[4], 64); err != nil {
+			return
+		}
+		if record[5] != "" {
+			if place.Population, err = strconv.ParseInt(record[5], 10, 64); err != nil {
+				return
+			}
+		}
+		places = append(places, place)
+	}
+	gazetteer = New(places)
+	return
+}
+
+// geoNames columns of the tab-separated GeoNames dumps like cities1000.txt
+const (
+	geoNamesName        = 1
+	geoNamesLatitude    = 4
+	geoNamesLongitude   = 5
+	geoNamesCountryCode = 8
+	geoNamesPopulation  = 14
+	geoNamesColumns     = 19
+)
+
+// LoadGeoNames reads a GeoNames dump (https://download.geonames.org/export/dump/), e.g. cities1000.txt.
+// GeoNames has no country names, they are taken from the embedded gazetteer.
+func LoadGeoNames(r io.Reader) (gazetteer *Gazetteer, err error) {
+	countries := map[string]string{}
+	for _, place := range Embedded().places {
+		countries[place.CountryCode] = place.Country
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var places []Place
+	for line := 1; scanner.Scan(); line++ {
+		columns := strings.Split(scanner.Text(), "\t")
+		if len(columns) < geoNamesColumns {
+			err = fmt.Errorf("line %d: %d columns, want %d", line, len(columns), geoNamesColumns)
+			return
+		}
+
+		place := Place{
+			Name:        columns[geoNamesName],
+			CountryCode: columns[geoNamesCountryCode],
+			Country:     countries[columns[geoNamesCountryCode]],
+		}
+		if place.Latitude, err = strconv.ParseFloat(columns[geoNamesLatitude], 64); err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			return
+		}
+		if place.Longitude, err = strconv.ParseFloat(columns[geoNamesLongitude], 64); err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			return
+		}
+		place.Population, _ = strconv.ParseInt(columns[geoNamesPopulation], 10, 64)
+		places = append(places, place)
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	gazetteer = New(places)
+	return
+}
+
+// FromLocations builds a gazetteer out of a dataset: every city becomes a place at the mean position of its locations,
+// with the number of locations as its population. Cities are grouped per country code regardless of case and accents.
+func FromLocations(locations []*geolocation.GeoLocation) *Gazetteer {
+	type city struct {
+		place    Place
+		lat, lng float64
+	}
+
+	cities := map[string]*city{}
+	var order []string
+	for _, location := range locations {
+		if location.City == "" || !spatial.ValidCoordinates(location.Latitude, location.Longitude) {
+			continue
+		}
+		key := geolocation.NormalizeKey(location.CountryCode) + "\x00" + geolocation.NormalizeKey(location.City)
+		c := cities[key]
+		if c == nil {
+			c = &city{place: Place{Name: location.City, CountryCode: location.CountryCode, Country: location.Country}}
+			cities[key] = c
+			order = append(order, key)
+		}
+		c.place.Population++
+		c.lat += location.Latitude
+		c.lng += location.Longitude
+	}
+
+	places := make([]Place, 0, len(order))
+	for _, key := range order {
+		c := cities[key]
+		c.place.Latitude = c.lat / float64(c.place.Population)
+		c.place.Longitude = c.lng / float64(c.place.Population)
+		places = append(places, c.place)
+	}
+	return New(places)
+}
@@ -0,0 +1,114 @@
+package geocode
+
+import (
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestEmbedded_Reverse(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     string
+		wantErr  bool
+	}{
+		{name: "Vienna", lat: 48.2, lng: 16.37, want: "Vienna"},
+		{name: "Bratislava", lat: 48.14, lng: 17.1, want: "Bratislava"},
+		{name: "Prague", lat: 50.1, lng: 14.4, want: "Prague"},
+		{name: "InvalidLatitude", lat: 91, lng: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := Embedded().Reverse(tt.lat, tt.lng)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reverse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && match.Place.Name != tt.want {
+				t.Fatalf("Reverse() = %+v, want %s", match, tt.want)
+			}
+		})
+	}
+}
+
+func TestGazetteer_Empty(t *testing.T) {
+	if _, err := New(nil).Reverse(0, 0); err != ErrEmptyGazetteer {
+		t.Fatalf("Reverse() error = %v, want %v", err, ErrEmptyGazetteer)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "Valid", csv: "name,country_code,country,latitude,longitude,population\nGraz,AT,Austria,47.07,15.44,\nLinz,AT,Austria,48.31,14.29,207247\n", wantLen: 2},
+		{name: "InvalidLatitude", csv: "name,country_code,country,latitude,longitude,population\nGraz,AT,Austria,north,15.44,\n", wantErr: true},
+		{name: "MissingColumn", csv: "name,country_code,country,latitude,longitude,population\nGraz,AT,47.07,15.44,1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gazetteer, err := LoadCSV(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && gazetteer.Len() != tt.wantLen {
+				t.Fatalf("Len() = %d, want %d", gazetteer.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestLoadGeoNames(t *testing.T) {
+	row := func(columns ...string) string {
+		return strings.Join(append(columns, make([]string, 19-len(columns))...), "\t")
+	}
+	dump := row("2778067", "Graz", "Graz", "", "47.06667", "15.45", "P", "PPLA", "AT", "", "", "", "", "", "222326") + "\n" +
+		row("2772400", "Linz", "Linz", "", "48.30639", "14.28611", "P", "PPLA", "AT", "", "", "", "", "", "181162") + "\n"
+
+	gazetteer, err := LoadGeoNames(strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("LoadGeoNames() error = %v", err)
+	}
+	match, err := gazetteer.Reverse(47.1, 15.4)
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	want := Place{Name: "Graz", CountryCode: "AT", Country: "Austria", Latitude: 47.06667, Longitude: 15.45, Population: 222326}
+	if match.Place != want {
+		t.Fatalf("Reverse() = %+v, want %+v", match.Place, want)
+	}
+
+	if _, err = LoadGeoNames(strings.NewReader("2778067\tGraz\n")); err == nil {
+		t.Fatalf("LoadGeoNames() error = nil, want an error for missing columns")
+	}
+}
+
+func TestFromLocations(t *testing.T) {
+	gazetteer := FromLocations([]*geolocation.GeoLocation{
+		{IPAddress: net.ParseIP("1.1.1.1"), CountryCode: "AT", Country: "Austria", City: "Wien", Latitude: 48.2, Longitude: 16.3},
+		{IPAddress: net.ParseIP("1.1.1.2"), CountryCode: "at", Country: "Austria", City: "WIEN", Latitude: 48.4, Longitude: 16.5},
+		{IPAddress: net.ParseIP("2.2.2.2"), CountryCode: "CZ", Country: "Czechia", City: "Praha", Latitude: 50.08, Longitude: 14.43},
+		{IPAddress: net.ParseIP("3.3.3.3"), CountryCode: "CZ", Country: "Czechia", Latitude: 49.19, Longitude: 16.6},
+	})
+	if gazetteer.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", gazetteer.Len())
+	}
+
+	match, err := gazetteer.Reverse(48, 16)
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	want := Place{Name: "Wien", CountryCode: "AT", Country: "Austria", Latitude: 48.3, Longitude: 16.4, Population: 2}
+	got := match.Place
+	if got.Name != want.Name || got.Population != want.Population || !near(got.Latitude, want.Latitude) || !near(got.Longitude, want.Longitude) {
+		t.Fatalf("Reverse() = %+v, want %+v", got, want)
+	}
+}
+
+func near(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -0,0 +1,127 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geocode"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"testing"
+)
+
+const fillCityCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+1.1.1.1,,,,48.2,16.37,1
+2.2.2.2,AT,Österreich,,48.2,16.37,2
+3.3.3.3,SK,Slovakia,,48.2,16.37,3
+4.4.4.4,AT,Austria,Wien,48.2,16.37,4
+5.5.5.5,,,,0,-160,5
+`
+
+func TestGeoService_ImportFillCity(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       *FillCityOptions
+		wantFilled int
+		want       map[string]geolocation.GeoLocation
+	}{
+		{
+			name: "Disabled",
+			want: map[string]geolocation.GeoLocation{"1.1.1.1": {}},
+		},
+		{
+			name:       "Embedded",
+			opts:       &FillCityOptions{},
+			wantFilled: 2,
+			want: map[string]geolocation.GeoLocation{
+				"1.1.1.1": {CountryCode: "AT", Country: "Austria", City: "Vienna"},
+				"2.2.2.2": {CountryCode: "AT", Country: "Österreich", City: "Vienna"},
+				"3.3.3.3": {CountryCode: "SK", Country: "Slovakia"},
+				"4.4.4.4": {CountryCode: "AT", Country: "Austria", City: "Wien"},
+				"5.5.5.5": {},
+			},
+		},
+		{
+			name: "Gazetteer",
+			opts: &FillCityOptions{Gazetteer: geocode.New([]geocode.Place{
+				{Name: "Bratislava", CountryCode: "SK", Country: "Slovakia", Latitude: 48.15, Longitude: 17.11},
+			})},
+			wantFilled: 2,
+			want: map[string]geolocation.GeoLocation{
+				"1.1.1.1": {CountryCode: "SK", Country: "Slovakia", City: "Bratislava"},
+				"3.3.3.3": {CountryCode: "SK", Country: "Slovakia", City: "Bratislava"},
+			},
+		},
+		{
+			name: "TooFar",
+			opts: &FillCityOptions{MaxDistanceKm: 1},
+			want: map[string]geolocation.GeoLocation{"1.1.1.1": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			g := NewGeoService(db)
+			stat, err := g.Import(context.Background(), strings.NewReader(fillCityCSV), ImportOptions{FillCity: tt.opts})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if stat.FilledCities != tt.wantFilled {
+				t.Errorf("Import() FilledCities = %d, want %d", stat.FilledCities, tt.wantFilled)
+			}
+
+			for ip, want := range tt.want {
+				location, err := db.Retrieve(net.ParseIP(ip))
+				if err != nil {
+					t.Fatalf("Retrieve(%s) error = %v", ip, err)
+				}
+				if location.CountryCode != want.CountryCode || location.Country != want.Country || location.City != want.City {
+					t.Errorf("Retrieve(%s) = %s, %s, %s, want %s, %s, %s", ip, location.CountryCode, location.Country, location.City,
+						want.CountryCode, want.Country, want.City)
+				}
+			}
+		})
+	}
+}
+
+func TestGeoService_ReverseGeocode(t *testing.T) {
+	g := NewGeoService(memory.New())
+	match, err := g.ReverseGeocode(48.2, 16.37)
+	if err != nil || match.Place.Name != "Vienna" {
+		t.Fatalf("ReverseGeocode() = %+v, error = %v, want Vienna", match, err)
+	}
+
+	g = NewGeoService(memory.New(), WithGazetteer(geocode.New(nil)))
+	if _, err = g.ReverseGeocode(48.2, 16.37); !errors.Is(err, geocode.ErrEmptyGazetteer) {
+		t.Fatalf("ReverseGeocode() error = %v, want %v", err, geocode.ErrEmptyGazetteer)
+	}
+}
+
+func TestGeoService_ReverseGeocodeDataset(t *testing.T) {
+	g := NewGeoService(memory.New())
+	if _, err := g.ReverseGeocodeDataset(0, 0); !errors.Is(err, geocode.ErrEmptyGazetteer) {
+		t.Fatalf("ReverseGeocodeDataset() error = %v, want %v", err, geocode.ErrEmptyGazetteer)
+	}
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	match, err := g.ReverseGeocodeDataset(-70, -40)
+	if err != nil || match.Place.Name != "New Neva" || match.Place.CountryCode != "CZ" {
+		t.Fatalf("ReverseGeocodeDataset() = %+v, error = %v, want New Neva", match, err)
+	}
+
+	// the gazetteer must be rebuilt after writes through the GeoService
+	closer := &geolocation.GeoLocation{IPAddress: net.ParseIP("10.0.0.1"), City: "Closer", Latitude: -70, Longitude: -40}
+	if err = g.StoreLocations([]*geolocation.GeoLocation{closer}); err != nil {
+		t.Fatalf("StoreLocations() error = %v", err)
+	}
+	if match, err = g.ReverseGeocodeDataset(-70, -40); err != nil || match.Place.Name != "Closer" {
+		t.Errorf("ReverseGeocodeDataset() = %+v, error = %v, want Closer", match, err)
+	}
+
+	if _, err = NewGeoService(newTestDB()).ReverseGeocodeDataset(0, 0); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("ReverseGeocodeDataset() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+}
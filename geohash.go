@@ -0,0 +1,88 @@
+package geoservice
+
+import (
+	"fmt"
+	"github.com/aliforever/geo-service/enrich"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"sort"
+	"strings"
+)
+
+// DefaultGeohashPrecision is used by WithGeohash for precisions out of range, 7 characters are about 153m by 153m
+const DefaultGeohashPrecision = 7
+
+// GeohashCell is the number of locations inside a geohash cell
+type GeohashCell struct {
+	Geohash string `json:"geohash"`
+	Count   int    `json:"count"`
+	// Latitude and Longitude are the center of the cell
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// WithGeohash sets the Geohash of every location imported or stored through the GeoService with precision characters,
+// running enrich.Geohash. Imports report it as the "geohash" enricher.
+func WithGeohash(precision int) Option {
+	return func(g *GeoService) {
+		if precision <= 0 || precision > spatial.MaxGeohashPrecision {
+			precision = DefaultGeohashPrecision
+		}
+		g.setEnricher(enrich.Geohash(precision))
+	}
+}
+
+// LocationsByGeohash returns the locations of the active dataset whose geohash starts with prefix, sorted by IP address.
+// The geohash is computed from the coordinates so the precision stored by WithGeohash doesn't matter.
+func (g *GeoService) LocationsByGeohash(prefix string) (locations []*geolocation.GeoLocation, err error) {
+	prefix = strings.ToLower(prefix)
+	box, err := spatial.DecodeGeohash(prefix)
+	if err != nil || prefix == "" {
+		err = spatial.ErrInvalidGeohash
+		return
+	}
+	return g.searchBoxes([]geolocation.BoundingBox{box}, func(lat, lng float64) bool {
+		return spatial.EncodeGeohash(lat, lng, len(prefix)) == prefix
+	})
+}
+
+// GeohashCells counts the locations of the active dataset per geohash cell of precision characters, only cells
+// starting with prefix are counted. Cells are sorted by descending count, then by geohash.
+func (g *GeoService) GeohashCells(prefix string, precision int) (cells []GeohashCell, err error) {
+	prefix = strings.ToLower(prefix)
+	box, err := spatial.DecodeGeohash(prefix)
+	if err != nil {
+		return
+	}
+	if precision < max(1, len(prefix)) || precision > spatial.MaxGeohashPrecision {
+		err = fmt.Errorf("%w: precision must be between %d and %d", spatial.ErrInvalidGeohash, max(1, len(prefix)), spatial.MaxGeohashPrecision)
+		return
+	}
+
+	counts := map[string]int{}
+	err = g.eachInBoxes([]geolocation.BoundingBox{box}, func(location *geolocation.GeoLocation) {
+		if hash := spatial.EncodeGeohash(location.Latitude, location.Longitude, precision); strings.HasPrefix(hash, prefix) {
+			counts[hash]++
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	for hash, count := range counts {
+		cell, _ := spatial.DecodeGeohash(hash)
+		cells = append(cells, GeohashCell{
+			Geohash:   hash,
+			Count:     count,
+			Latitude:  (cell.MinLatitude + cell.MaxLatitude) / 2,
+			Longitude: (cell.MinLongitude + cell.MaxLongitude) / 2,
+		})
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Count != cells[j].Count {
+			return cells[i].Count > cells[j].Count
+		}
+		return cells[i].Geohash < cells[j].Geohash
+	})
+	return
+}
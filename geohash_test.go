@@ -0,0 +1,105 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"github.com/aliforever/geo-service/spatial"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestGeoService_WithGeohash(t *testing.T) {
+	g := NewGeoService(memory.New(), WithGeohash(5))
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	stored := &geolocation.GeoLocation{IPAddress: net.ParseIP("10.0.0.1"), Latitude: -68, Longitude: -37}
+	if err := g.StoreLocations([]*geolocation.GeoLocation{stored}); err != nil {
+		t.Fatalf("StoreLocations() error = %v", err)
+	}
+
+	for ip, want := range map[string]string{"200.106.141.15": "h0vj5", "160.103.7.140": "55v5r", "10.0.0.1": "55vtn"} {
+		location, err := g.RetrieveLocation(net.ParseIP(ip))
+		if err != nil || location.Geohash != want {
+			t.Errorf("RetrieveLocation(%s) geohash = %v, error = %v, want %s", ip, location.Geohash, err, want)
+		}
+	}
+}
+
+func TestGeoService_LocationsByGeohash(t *testing.T) {
+	g := NewGeoService(memory.New())
+	g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{})
+
+	tests := []struct {
+		name    string
+		prefix  string
+		want    []string
+		wantErr error
+	}{
+		{name: "Cell", prefix: "55v", want: []string{"160.103.7.140"}},
+		{name: "UpperCase", prefix: "H0VJ5UZ", want: []string{"200.106.141.15"}},
+		{name: "Empty", prefix: "7", want: nil},
+		{name: "Invalid", prefix: "a", wantErr: spatial.ErrInvalidGeohash},
+		{name: "Whole world", prefix: "", wantErr: spatial.ErrInvalidGeohash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, err := g.LocationsByGeohash(tt.prefix)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LocationsByGeohash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(locations) != len(tt.want) {
+				t.Fatalf("LocationsByGeohash() = %d locations, want %v", len(locations), tt.want)
+			}
+			for i, location := range locations {
+				if location.IPAddress.String() != tt.want[i] {
+					t.Errorf("LocationsByGeohash()[%d] = %v, want %s", i, location.IPAddress, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGeoService_GeohashCells(t *testing.T) {
+	g := NewGeoService(memory.New())
+	g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{})
+	g.StoreLocations([]*geolocation.GeoLocation{{IPAddress: net.ParseIP("10.0.0.1"), Latitude: -68, Longitude: -37}})
+
+	tests := []struct {
+		name      string
+		prefix    string
+		precision int
+		want      []GeohashCell
+		wantErr   error
+	}{
+		{
+			name: "World", precision: 1,
+			want: []GeohashCell{{Geohash: "5", Count: 2}, {Geohash: "0", Count: 1}, {Geohash: "4", Count: 1}, {Geohash: "h", Count: 1}},
+		},
+		{name: "Zoomed", prefix: "55v", precision: 5, want: []GeohashCell{{Geohash: "55v5r", Count: 1}, {Geohash: "55vtn", Count: 1}}},
+		{name: "PrecisionBelowPrefix", prefix: "55v", precision: 2, wantErr: spatial.ErrInvalidGeohash},
+		{name: "PrecisionTooLarge", precision: 13, wantErr: spatial.ErrInvalidGeohash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cells, err := g.GeohashCells(tt.prefix, tt.precision)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GeohashCells() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(cells) != len(tt.want) {
+				t.Fatalf("GeohashCells() = %+v, want %+v", cells, tt.want)
+			}
+			for i, cell := range cells {
+				if cell.Geohash != tt.want[i].Geohash || cell.Count != tt.want[i].Count {
+					t.Errorf("GeohashCells()[%d] = %+v, want %+v", i, cell, tt.want[i])
+				}
+				if box, _ := spatial.DecodeGeohash(cell.Geohash); !box.Contains(cell.Latitude, cell.Longitude) {
+					t.Errorf("GeohashCells()[%d] center is outside of the cell", i)
+				}
+			}
+		})
+	}
+}
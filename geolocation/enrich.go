@@ -0,0 +1,42 @@
+package geolocation
+
+import (
+	"encoding/json"
+)
+
+// Enricher adds derived data to a parsed location before it is stored.
+// Import calls it from several workers at once, each with a different location.
+type Enricher interface {
+	// Name identifies the enricher in statistics and errors, e.g. "timezone"
+	Name() string
+	Enrich(location *GeoLocation) error
+}
+
+// ExtraField is a typed key of GeoLocation.Extra, values are stored as JSON so they survive file repositories
+type ExtraField[T any] string
+
+// Set stores value under the field on g
+func (f ExtraField[T]) Set(g *GeoLocation, value T) (err error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if g.Extra == nil {
+		g.Extra = map[string]json.RawMessage{}
+	}
+	g.Extra[string(f)] = data
+	return
+}
+
+// Get returns the value of the field on g, ok is false when it is missing or doesn't decode into T
+func (f ExtraField[T]) Get(g *GeoLocation) (value T, ok bool) {
+	data, found := g.Extra[string(f)]
+	if !found {
+		return
+	}
+	if json.Unmarshal(data, &value) != nil {
+		var zero T
+		return zero, false
+	}
+	return value, true
+}
@@ -0,0 +1,40 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtraField(t *testing.T) {
+	asn := ExtraField[int]("asn")
+	member := ExtraField[bool]("eu_member")
+	location := &GeoLocation{}
+	if _, ok := asn.Get(location); ok {
+		t.Fatalf("Get() ok = true on a location without extra fields")
+	}
+	if err := asn.Set(location, 13335); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := member.Set(location, true); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// values keep their type through JSON, as in file repositories
+	data, err := json.Marshal(location)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	decoded := &GeoLocation{}
+	if err = json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if value, ok := asn.Get(decoded); !ok || value != 13335 {
+		t.Errorf("Get() = %v, %v, want 13335", value, ok)
+	}
+	if value, ok := member.Get(decoded); !ok || !value {
+		t.Errorf("Get() = %v, %v, want true", value, ok)
+	}
+	if value, ok := ExtraField[string]("asn").Get(decoded); ok {
+		t.Errorf("Get() = %q, want ok = false for a mismatched type", value)
+	}
+}
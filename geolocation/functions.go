@@ -16,6 +16,17 @@ var (
 	emptyLong        = errors.New("empty_longitude")
 )
 
+// RowErrorReason returns the reason of the errors NewGeoLocationFromBytes reports for malformed rows like
+// "empty_ip_address", ok is false for any other error
+func RowErrorReason(err error) (reason string, ok bool) {
+	for _, rowErr := range []error{invalidDataError, emptyIPAddress, invalidIPAddress, emptyLat, emptyLong} {
+		if errors.Is(err, rowErr) {
+			return rowErr.Error(), true
+		}
+	}
+	return
+}
+
 // escapeCommas replaces commas inside double-quotes or single-quotes with dashes
 // later on these dashes are replaced back with commas
 func escapeCommas(data string) string {
@@ -30,8 +41,8 @@ func escapeCommas(data string) string {
 }
 
 // getColumns splits a CSV row by comma and checks if there are double quote escaped commas inside city & country columns.
-func getColumns(data string) (columns []string) {
-	trimmed := strings.TrimSpace(data)
+func getColumns(data []byte) (columns []string) {
+	trimmed := strings.TrimSpace(string(data))
 	columns = strings.Split(trimmed, ",")
 	for index := range columns {
 		columns[index] = strings.TrimSpace(columns[index])
@@ -1,7 +1,10 @@
 package geolocation
 
 import (
+	"encoding/json"
+	"github.com/aliforever/geo-service/ipclass"
 	"net"
+	"net/netip"
 )
 
 // GeoLocation ip_address,country_code,country,city,latitude,longitude,mystery_value
@@ -13,9 +16,33 @@ type GeoLocation struct {
 	Latitude     float64 `json:"latitude"`
 	Longitude    float64 `json:"longitude"`
 	MysteryValue int64   `json:"mystery_value"`
+	// DatasetVersion is set on lookup results when datasets are versioned
+	DatasetVersion string `json:"dataset_version,omitempty"`
+	// Geohash is computed when the location is stored by a GeoService configured with a geohash precision
+	Geohash string `json:"geohash,omitempty"`
+	// Timezone is the IANA timezone, e.g. Europe/Vienna, resolved when the location is stored by a GeoService configured with timezones
+	Timezone string `json:"timezone,omitempty"`
+	// Extra holds the fields attached by enrichers as JSON, read and written through ExtraField
+	Extra map[string]json.RawMessage `json:"extra,omitempty"`
+	// Network is set on lookup results when the GeoService has ASN data for the IP address, it isn't stored
+	Network *Network `json:"network,omitempty"`
+	// Class is set on lookup results of special-purpose IP addresses like private or documentation ones, it isn't stored
+	Class ipclass.Class `json:"class,omitempty"`
+}
+
+// Network is an announced network along with the autonomous system and organization owning it
+type Network struct {
+	Prefix       netip.Prefix `json:"prefix"`
+	ASN          uint32       `json:"asn"`
+	Organization string       `json:"organization"`
 }
 
 func NewGeoLocationFromString(data string) (g *GeoLocation, err error) {
+	return NewGeoLocationFromBytes([]byte(data))
+}
+
+// NewGeoLocationFromBytes is NewGeoLocationFromString for rows read as bytes, it avoids a string copy per row
+func NewGeoLocationFromBytes(data []byte) (g *GeoLocation, err error) {
 	var (
 		ipAddr                     net.IP
 		countryCode, country, city string
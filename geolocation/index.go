@@ -0,0 +1,90 @@
+package geolocation
+
+import (
+	"errors"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+var ErrUnknownField = errors.New("unknown_index_field")
+
+// IndexField is a GeoLocation field with a secondary index
+type IndexField string
+
+const (
+	FieldCountryCode IndexField = "country_code"
+	FieldCountry     IndexField = "country"
+	FieldCity        IndexField = "city"
+)
+
+// IndexFields lists every indexed field
+var IndexFields = []IndexField{FieldCountryCode, FieldCountry, FieldCity}
+
+// Valid reports whether the field is indexed
+func (f IndexField) Valid() bool {
+	return f == FieldCountryCode || f == FieldCountry || f == FieldCity
+}
+
+// Value returns the field of g
+func (f IndexField) Value(g *GeoLocation) string {
+	switch f {
+	case FieldCountryCode:
+		return g.CountryCode
+	case FieldCountry:
+		return g.Country
+	case FieldCity:
+		return g.City
+	}
+	return ""
+}
+
+// folds spells the lower case letters without a decomposition into a base letter and an accent in plain Latin letters
+var folds = strings.NewReplacer(
+	"æ", "ae", "ð", "d", "đ", "d", "ħ", "h", "ı", "i", "ł", "l", "ŀ", "l",
+	"ø", "o", "œ", "oe", "ß", "ss", "þ", "th", "ŧ", "t",
+)
+
+// NormalizeKey makes index keys case and accent insensitive, "Zürich" and "ZURICH " are both "zurich".
+// Letters like ł, ø and ß which aren't accented base letters in Unicode are folded too, "Łódź" is "lodz".
+func NormalizeKey(value string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), value)
+	if err != nil {
+		stripped = value
+	}
+	return folds.Replace(strings.ToLower(strings.TrimSpace(stripped)))
+}
+
+// Page selects a range of ordered results, a Limit of zero or less returns every result from Offset
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// Apply returns the part of n results selected by the page as [begin, end)
+func (p Page) Apply(n int) (begin, end int) {
+	begin = min(max(p.Offset, 0), n)
+	end = n
+	if p.Limit > 0 {
+		end = min(begin+p.Limit, n)
+	}
+	return
+}
+
+// KeyCount is the number of locations sharing a key of a secondary index
+type KeyCount struct {
+	// Key is the normalized key and Value the smallest original spelling of the field among its locations, so it
+	// doesn't depend on the order locations were indexed in
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Indexer is implemented by repositories keeping secondary indexes on CountryCode, Country and City.
+// Keys match after NormalizeKey, FindBy orders locations by IP address and CountBy by descending count.
+type Indexer interface {
+	FindBy(field IndexField, key string, page Page) (locations []*GeoLocation, total int, err error)
+	CountBy(field IndexField) (counts []KeyCount, err error)
+}
@@ -0,0 +1,52 @@
+package geolocation
+
+import "testing"
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "Zürich", want: "zurich"},
+		{value: " ZURICH ", want: "zurich"},
+		{value: "São Tomé and Príncipe", want: "sao tome and principe"},
+		{value: "Curaçao", want: "curacao"},
+		{value: "CZ", want: "cz"},
+		{value: "Łódź", want: "lodz"},
+		{value: "Tromsø", want: "tromso"},
+		{value: "STRAẞE", want: "strasse"},
+		{value: "Ærøskøbing", want: "aeroskobing"},
+		{value: "Þórshöfn", want: "thorshofn"},
+		{value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := NormalizeKey(tt.value); got != tt.want {
+				t.Errorf("NormalizeKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPage_Apply(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      Page
+		n         int
+		wantBegin int
+		wantEnd   int
+	}{
+		{name: "Everything", page: Page{}, n: 5, wantBegin: 0, wantEnd: 5},
+		{name: "Middle", page: Page{Offset: 1, Limit: 2}, n: 5, wantBegin: 1, wantEnd: 3},
+		{name: "LastPage", page: Page{Offset: 4, Limit: 2}, n: 5, wantBegin: 4, wantEnd: 5},
+		{name: "PastTheEnd", page: Page{Offset: 9, Limit: 2}, n: 5, wantBegin: 5, wantEnd: 5},
+		{name: "NegativeOffset", page: Page{Offset: -1, Limit: 2}, n: 5, wantBegin: 0, wantEnd: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if begin, end := tt.page.Apply(tt.n); begin != tt.wantBegin || end != tt.wantEnd {
+				t.Errorf("Apply() = [%d, %d), want [%d, %d)", begin, end, tt.wantBegin, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package geolocation
+
+// BoundingBox is a latitude/longitude rectangle in degrees, edges included.
+// A box whose MinLongitude is greater than its MaxLongitude crosses the antimeridian,
+// e.g. MinLongitude 170 and MaxLongitude -170 spans 20 degrees around longitude 180.
+type BoundingBox struct {
+	MinLatitude  float64 `json:"min_latitude" yaml:"min_latitude"`
+	MaxLatitude  float64 `json:"max_latitude" yaml:"max_latitude"`
+	MinLongitude float64 `json:"min_longitude" yaml:"min_longitude"`
+	MaxLongitude float64 `json:"max_longitude" yaml:"max_longitude"`
+}
+
+// CrossesAntimeridian reports whether the box wraps around longitude 180
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLongitude > b.MaxLongitude
+}
+
+// Split returns the box itself, or its eastern and western halves when it crosses the antimeridian
+func (b BoundingBox) Split() []BoundingBox {
+	if !b.CrossesAntimeridian() {
+		return []BoundingBox{b}
+	}
+	east, west := b, b
+	east.MaxLongitude, west.MinLongitude = 180, -180
+	return []BoundingBox{east, west}
+}
+
+// Contains reports whether the point is inside the box
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	if lat < b.MinLatitude || lat > b.MaxLatitude {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return lng >= b.MinLongitude || lng <= b.MaxLongitude
+	}
+	return lng >= b.MinLongitude && lng <= b.MaxLongitude
+}
+
+// RegionSearcher is implemented by repositories that can filter locations by coordinates themselves,
+// e.g. SQL backends turning the box into a WHERE clause on indexed latitude and longitude columns.
+// The box never crosses the antimeridian, GeoService splits such boxes before calling SearchBox.
+type RegionSearcher interface {
+	SearchBox(box BoundingBox) ([]*GeoLocation, error)
+}
@@ -0,0 +1,31 @@
+package geolocation
+
+import "testing"
+
+func TestBoundingBox_Contains(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      BoundingBox
+		lat, lng float64
+		want     bool
+	}{
+		{name: "Inside", box: BoundingBox{MinLatitude: 0, MaxLatitude: 10, MinLongitude: 0, MaxLongitude: 10}, lat: 5, lng: 5, want: true},
+		{name: "Edge", box: BoundingBox{MinLatitude: 0, MaxLatitude: 10, MinLongitude: 0, MaxLongitude: 10}, lat: 10, lng: 0, want: true},
+		{name: "Outside", box: BoundingBox{MinLatitude: 0, MaxLatitude: 10, MinLongitude: 0, MaxLongitude: 10}, lat: 5, lng: 11, want: false},
+		{name: "AntimeridianEast", box: BoundingBox{MinLatitude: -10, MaxLatitude: 10, MinLongitude: 170, MaxLongitude: -170}, lat: 0, lng: 175, want: true},
+		{name: "AntimeridianWest", box: BoundingBox{MinLatitude: -10, MaxLatitude: 10, MinLongitude: 170, MaxLongitude: -170}, lat: 0, lng: -175, want: true},
+		{name: "AntimeridianOutside", box: BoundingBox{MinLatitude: -10, MaxLatitude: 10, MinLongitude: 170, MaxLongitude: -170}, lat: 0, lng: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.box.Contains(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+			for _, part := range tt.box.Split() {
+				if part.CrossesAntimeridian() {
+					t.Errorf("Split() part %+v crosses the antimeridian", part)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,85 @@
 package geolocation
 
-import "net"
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+var (
+	// ErrNotFound should be returned (or wrapped) by Retrieve when there is no location for the IP address
+	ErrNotFound = errors.New("location_not_found")
+	// ErrUnsupported is returned by decorators when the wrapped repository lacks an optional capability
+	ErrUnsupported = errors.New("unsupported_operation")
+	// ErrTransactionsUnsupported is returned when locations have to be stored atomically in a repository which isn't
+	// a Transactor, decorators return it from Begin when the wrapped repository isn't one
+	ErrTransactionsUnsupported = errors.New("transactions_unsupported")
+	// ErrTransactionDone is returned by a Transaction used after Commit or Rollback
+	ErrTransactionDone = errors.New("transaction_done")
+)
 
 type Repository interface {
 	Store(*GeoLocation) error
 	StoreMany([]*GeoLocation) error
 	Retrieve(ipAddress net.IP) (*GeoLocation, error)
 }
+
+// Upserter is implemented by repositories that can replace the location of an existing IP address
+type Upserter interface {
+	Upsert(*GeoLocation) error
+}
+
+// Deleter is implemented by repositories that can remove the location of an IP address
+type Deleter interface {
+	Delete(ipAddress net.IP) error
+}
+
+// Iterable is implemented by repositories that can list every stored location.
+// Each stops when fn returns false.
+type Iterable interface {
+	Each(fn func(*GeoLocation) bool) error
+}
+
+// Transactor is implemented by repositories that can store locations atomically
+type Transactor interface {
+	Begin() (Transaction, error)
+}
+
+// Transaction stores locations that only become visible on Commit and are discarded on Rollback
+type Transaction interface {
+	StoreMany([]*GeoLocation) error
+	Commit() error
+	Rollback() error
+}
+
+// Wrapper is implemented by decorators like cache.Repository. They implement every optional interface and return
+// ErrUnsupported when the repository they wrap lacks it, so capabilities have to be checked with As.
+type Wrapper interface {
+	Unwrap() Repository
+}
+
+// As returns db as the optional interface T, e.g. Upserter, when db and every repository it wraps implement it
+func As[T any](db Repository) (capability T, ok bool) {
+	for inner := db; ; {
+		if _, ok = inner.(T); !ok {
+			return
+		}
+		wrapper, wraps := inner.(Wrapper)
+		if !wraps {
+			break
+		}
+		inner = wrapper.Unwrap()
+	}
+	capability = db.(T)
+	return
+}
+
+// BatchError can be returned by StoreMany to report exactly which locations of the batch were not stored.
+// Errors is keyed by the index of the location inside the slice passed to StoreMany, every other location is considered stored.
+type BatchError struct {
+	Errors map[int]error
+}
+
+func (b *BatchError) Error() string {
+	return fmt.Sprintf("%d locations of the batch were not stored", len(b.Errors))
+}
@@ -2,27 +2,87 @@ package geoservice
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/asn"
+	"github.com/aliforever/geo-service/enrich"
+	"github.com/aliforever/geo-service/geocode"
 	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/ipclass"
+	"github.com/aliforever/geo-service/timezone"
 	"io"
+	"log/slog"
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var ErrInvalidIP = errors.New("invalid_ip_address")
+
 type GeoService struct {
-	db geolocation.Repository
+	// active holds the *Dataset lookups and writes go to, it is swapped atomically when a new version is activated
+	active   atomic.Value
+	versions *versionStore
+	metrics  Metrics
+	tracer   Tracer
+	logger   *slog.Logger
+	logOpts  LogOptions
+	// writes counts writes to the active dataset through the GeoService, it invalidates the spatial index
+	writes    atomic.Uint64
+	spatialMu sync.Mutex
+	spatial   *spatialCache
+	// enrichers derive fields of every location imported or stored through the GeoService, see WithGeohash
+	enrichers []geolocation.Enricher
+	gazetteer *geocode.Gazetteer
+	// timezones resolves the Timezone of stored locations, nil disables it
+	timezones *timezone.Resolver
+	// networks are merged into lookup results, it is swapped by ImportASN
+	networks atomic.Pointer[asn.Table]
+	// heapInterval is the period of the heap size samples of ParseCSV and Import, zero disables sampling
+	heapInterval time.Duration
+}
+
+// Option configures optional GeoService features
+type Option func(*GeoService)
+
+func NewGeoService(db geolocation.Repository, opts ...Option) (gs *GeoService) {
+	gs = &GeoService{}
+	gs.active.Store(&Dataset{Repository: db})
+	for _, opt := range opts {
+		opt(gs)
+	}
+	return
+}
+
+// dataset returns the active dataset
+func (g *GeoService) dataset() *Dataset {
+	if dataset, ok := g.active.Load().(*Dataset); ok {
+		return dataset
+	}
+	return &Dataset{}
 }
 
-func NewGeoService(db geolocation.Repository) (gs *GeoService) {
-	return &GeoService{db: db}
+// acquireDataset returns the active dataset, which stays open until it is released even if another version is
+// activated meanwhile
+func (g *GeoService) acquireDataset() (dataset *Dataset) {
+	for dataset = g.dataset(); !dataset.acquire(); dataset = g.dataset() {
+	}
+	return
 }
 
 // initializeWorker receives number of workers defining number of goroutines for initializing GeoLocation from rows
-// And writing the results to the ch channel
-func (g *GeoService) initializeWorker(workers int, rows []string, ch chan *geolocation.GeoLocation) {
+// And writing the results to the ch channel, discarded rows are counted by reason
+func (g *GeoService) initializeWorker(workers int, rows []string, ch chan *geolocation.GeoLocation) (reasons map[string]int) {
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reasons = map[string]int{}
 
+	if len(rows) == 0 {
+		close(ch)
+		return
+	}
 	if workers > len(rows) {
 		workers = len(rows)
 	}
@@ -44,6 +104,11 @@ func (g *GeoService) initializeWorker(workers int, rows []string, ch chan *geolo
 			for _, row := range rows {
 				loc, locErr := geolocation.NewGeoLocationFromString(row)
 				if locErr != nil || loc == nil {
+					reason := rejectReason(locErr)
+					g.meter().RowRejected(reason)
+					mu.Lock()
+					reasons[reason]++
+					mu.Unlock()
 					continue
 				}
 				ch <- loc
@@ -53,6 +118,7 @@ func (g *GeoService) initializeWorker(workers int, rows []string, ch chan *geolo
 
 	wg.Wait()
 	close(ch)
+	return
 }
 
 func (g *GeoService) ParseCSV(path string, workers int) (locations []*geolocation.GeoLocation, stat *Statistics, err error) {
@@ -67,11 +133,15 @@ func (g *GeoService) ParseCSV(path string, workers int) (locations []*geolocatio
 
 	r := bufio.NewReader(file)
 
-	r.ReadLine() // This is to skip header row
+	header, _, _ := r.ReadLine() // This is to skip header row
 
 	var rowChan = make(chan *geolocation.GeoLocation)
 
+	heap := g.sampleHeap()
+	defer heap.stop()
+
 	var rows []string
+	bytesRead := int64(len(header)) + 1
 	for {
 		line, _, lineErr := r.ReadLine()
 		if lineErr != nil {
@@ -81,27 +151,34 @@ func (g *GeoService) ParseCSV(path string, workers int) (locations []*geolocatio
 			err = lineErr
 			return
 		}
+		bytesRead += int64(len(line)) + 1
 		rows = append(rows, string(line))
 	}
 
 	appendBegin := time.Now()
 	var appendElapsed time.Duration
 
-	var duplicates int
+	var duplicates, conflicts int
+	collector := newStatsCollector()
 
 	var wg sync.WaitGroup
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 
 		var storage = map[string]*geolocation.GeoLocation{}
 
 		for location := range rowChan {
-			if storage[location.IPAddress.String()] != nil {
+			if kept := storage[location.IPAddress.String()]; kept != nil {
 				duplicates++
+				if hashContent(kept) != hashContent(location) {
+					conflicts++
+				}
+				g.meter().RowRejected(rejectReason(ErrDuplicate))
 				continue
 			}
 			storage[location.IPAddress.String()] = location
+			collector.accept(location)
 		}
 
 		for _, location := range storage {
@@ -112,40 +189,165 @@ func (g *GeoService) ParseCSV(path string, workers int) (locations []*geolocatio
 	}()
 
 	parsedBegin := time.Now()
-	g.initializeWorker(workers, rows, rowChan)
+	reasons := g.initializeWorker(workers, rows, rowChan)
 	parsedElapsed := time.Now().Sub(parsedBegin)
 
 	wg.Wait()
 
 	end := time.Now()
+	g.meter().RowsAccepted(len(locations))
+	g.meter().ObserveParse(parsedElapsed)
+
+	discarded := 0
+	for reason, count := range reasons {
+		collector.reasons[reason] = count
+		discarded += count
+	}
 
 	stat = &Statistics{
-		Elapsed:          end.Sub(begin),
-		ElapsedParsed:    parsedElapsed,
-		ElapsedAppend:    appendElapsed,
-		Duplicates:       duplicates,
-		AcceptedEntries:  len(locations),
-		DiscardedEntries: len(rows) - len(locations) - duplicates,
+		Elapsed:               end.Sub(begin),
+		ElapsedParsed:         parsedElapsed,
+		ElapsedAppend:         appendElapsed,
+		BytesRead:             bytesRead,
+		Duplicates:            duplicates,
+		ConflictingDuplicates: conflicts,
+		AcceptedEntries:       len(locations),
+		DiscardedEntries:      discarded,
+		PeakHeapBytes:         heap.stop(),
 	}
+	collector.fill(stat)
+	stat.finish()
 	return
 }
 
+// StoreLocations stores locations one by one, a failing location doesn't stop the rest from being stored.
+// err wraps ErrIncompleteStore if any location failed, StoreLocationsResult reports which.
 func (g *GeoService) StoreLocations(locations []*geolocation.GeoLocation) (err error) {
+	_, err = g.StoreLocationsResult(locations)
+	return
+}
+
+// StoreLocationsResult stores locations like StoreLocations, the result reports every stored and failed location
+func (g *GeoService) StoreLocationsResult(locations []*geolocation.GeoLocation) (result *BatchResult, err error) {
+	begin := time.Now()
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	db := dataset.Repository
+
+	result = &BatchResult{}
 	for _, location := range locations {
-		err = g.db.Store(location)
-		if err != nil {
-			return
+		g.enrich(location)
+		storeBegin := time.Now()
+		storeErr := db.Store(location)
+		if storeErr != nil {
+			g.meter().ObserveStore(time.Now().Sub(storeBegin), 0, 1)
+			result.Failed = append(result.Failed, FailedLocation{Location: location, Err: storeErr})
+			continue
 		}
+		g.meter().ObserveStore(time.Now().Sub(storeBegin), 1, 0)
+		result.Stored = append(result.Stored, location)
 	}
+
+	result.Elapsed = time.Now().Sub(begin)
+	g.written()
+	err = incompleteError(result)
+	g.logIncompleteStore(result, err)
 	return
 }
 
-func (g *GeoService) StoreLocationsBatch(locations []*geolocation.GeoLocation) (err error) {
-	err = g.db.StoreMany(locations)
+// StoreLocationsBatch stores locations in chunks of opts.ChunkSize using opts.Writers concurrent StoreMany calls.
+// With opts.Transactional every chunk is written inside one transaction and nothing is stored if a chunk fails.
+// The result reports every stored and failed location, err wraps ErrIncompleteStore if any location failed.
+func (g *GeoService) StoreLocationsBatch(locations []*geolocation.GeoLocation, opts BatchOptions) (result *BatchResult, err error) {
+	begin := time.Now()
+	opts = opts.withDefaults()
+
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	db := dataset.Repository
+	for _, location := range locations {
+		g.enrich(location)
+	}
+	chunks := splitChunks(locations, opts.ChunkSize)
+	defer g.written()
+
+	var results []chunkResult
+	if opts.Transactional {
+		results, err = storeChunksTx(db, chunks)
+		if err != nil {
+			return
+		}
+	} else {
+		results = storeChunks(db, chunks, opts.Writers)
+	}
+
+	for _, chunk := range results {
+		g.meter().ObserveStore(chunk.elapsed, len(chunk.stored), len(chunk.failed))
+	}
+
+	result = mergeChunks(results)
+	result.Elapsed = time.Now().Sub(begin)
+	err = incompleteError(result)
+	g.logIncompleteStore(result, err)
 	return
 }
 
+// RetrieveLocation looks the IP address up in the active dataset.
+// It returns ErrInvalidIP for malformed addresses and the Repository's error (geolocation.ErrNotFound) for unknown ones.
+// When datasets are versioned the returned location is a copy carrying the active DatasetVersion, with WithTimezone
+// locations stored without a Timezone are returned as a copy carrying the resolved one.
+// With ASN data (WithASN or ImportASN) the copy carries the Network of ip as well, special-purpose addresses like
+// private or documentation ones carry their ipclass.Class.
 func (g *GeoService) RetrieveLocation(ip net.IP) (location *geolocation.GeoLocation, err error) {
-	location, err = g.db.Retrieve(ip)
+	begin := time.Now()
+	_, span := g.trace().Start(context.Background(), SpanRetrieve)
+	defer func() {
+		elapsed := time.Now().Sub(begin)
+		g.meter().ObserveLookup(elapsed, err)
+		g.logLookup(ip, elapsed, err)
+		span.SetAttributes(Attribute{Key: "found", Value: err == nil})
+		if err != nil && !errors.Is(err, geolocation.ErrNotFound) {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		err = ErrInvalidIP
+		return
+	}
+
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	span.SetAttributes(Attribute{Key: "repository", Value: repositoryType(dataset.Repository)})
+	location, err = dataset.Repository.Retrieve(ip)
+	if err != nil || location == nil {
+		return
+	}
+	location = g.lookupResult(dataset, ip, location)
 	return
 }
+
+// lookupResult returns location, or a copy of it carrying the DatasetVersion, the resolved Timezone and the Network and
+// Class of ip
+func (g *GeoService) lookupResult(dataset *Dataset, ip net.IP, location *geolocation.GeoLocation) *geolocation.GeoLocation {
+	network, owned := g.network(ip)
+	resolve := g.timezones != nil && location.Timezone == ""
+	class := ipclass.Classify(ip)
+	if dataset.Version == "" && !resolve && !owned && class == 0 {
+		return location
+	}
+
+	copied := *location
+	if dataset.Version != "" {
+		copied.DatasetVersion = dataset.Version
+	}
+	if resolve {
+		enrich.Timezone(g.timezones).Enrich(&copied)
+	}
+	if owned {
+		copied.Network = &network
+	}
+	copied.Class = class
+	return &copied
+}
@@ -0,0 +1,1181 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: geoservice/v1/geoservice.proto
+
+package geoservicepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DedupePolicy int32
+
+const (
+	DedupePolicy_DEDUPE_POLICY_KEEP_FIRST DedupePolicy = 0
+	DedupePolicy_DEDUPE_POLICY_DISABLED   DedupePolicy = 1
+)
+
+// Enum value maps for DedupePolicy.
+var (
+	DedupePolicy_name = map[int32]string{
+		0: "DEDUPE_POLICY_KEEP_FIRST",
+		1: "DEDUPE_POLICY_DISABLED",
+	}
+	DedupePolicy_value = map[string]int32{
+		"DEDUPE_POLICY_KEEP_FIRST": 0,
+		"DEDUPE_POLICY_DISABLED":   1,
+	}
+)
+
+func (x DedupePolicy) Enum() *DedupePolicy {
+	p := new(DedupePolicy)
+	*p = x
+	return p
+}
+
+func (x DedupePolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DedupePolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_geoservice_v1_geoservice_proto_enumTypes[0].Descriptor()
+}
+
+func (DedupePolicy) Type() protoreflect.EnumType {
+	return &file_geoservice_v1_geoservice_proto_enumTypes[0]
+}
+
+func (x DedupePolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DedupePolicy.Descriptor instead.
+func (DedupePolicy) EnumDescriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{0}
+}
+
+// GeoLocation mirrors geolocation.GeoLocation
+type GeoLocation struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress      string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	CountryCode    string                 `protobuf:"bytes,2,opt,name=country_code,json=countryCode,proto3" json:"country_code,omitempty"`
+	Country        string                 `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	City           string                 `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`
+	Latitude       float64                `protobuf:"fixed64,5,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude      float64                `protobuf:"fixed64,6,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	MysteryValue   int64                  `protobuf:"varint,7,opt,name=mystery_value,json=mysteryValue,proto3" json:"mystery_value,omitempty"`
+	DatasetVersion string                 `protobuf:"bytes,8,opt,name=dataset_version,json=datasetVersion,proto3" json:"dataset_version,omitempty"`
+	Geohash        string                 `protobuf:"bytes,9,opt,name=geohash,proto3" json:"geohash,omitempty"`
+	Timezone       string                 `protobuf:"bytes,10,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	// extra holds the fields attached by enrichers as JSON values
+	Extra map[string]string `protobuf:"bytes,11,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// network is set when the service has ASN data for the IP address
+	Network *Network `protobuf:"bytes,12,opt,name=network,proto3" json:"network,omitempty"`
+	// classes are the ipclass flags of special-purpose IP addresses, such as "private" or "bogon"
+	Classes       []string `protobuf:"bytes,13,rep,name=classes,proto3" json:"classes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GeoLocation) Reset() {
+	*x = GeoLocation{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GeoLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeoLocation) ProtoMessage() {}
+
+func (x *GeoLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeoLocation.ProtoReflect.Descriptor instead.
+func (*GeoLocation) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GeoLocation) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetCountryCode() string {
+	if x != nil {
+		return x.CountryCode
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *GeoLocation) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *GeoLocation) GetMysteryValue() int64 {
+	if x != nil {
+		return x.MysteryValue
+	}
+	return 0
+}
+
+func (x *GeoLocation) GetDatasetVersion() string {
+	if x != nil {
+		return x.DatasetVersion
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetGeohash() string {
+	if x != nil {
+		return x.Geohash
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetExtra() map[string]string {
+	if x != nil {
+		return x.Extra
+	}
+	return nil
+}
+
+func (x *GeoLocation) GetNetwork() *Network {
+	if x != nil {
+		return x.Network
+	}
+	return nil
+}
+
+func (x *GeoLocation) GetClasses() []string {
+	if x != nil {
+		return x.Classes
+	}
+	return nil
+}
+
+// Network mirrors geolocation.Network
+type Network struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Asn           uint32                 `protobuf:"varint,2,opt,name=asn,proto3" json:"asn,omitempty"`
+	Organization  string                 `protobuf:"bytes,3,opt,name=organization,proto3" json:"organization,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Network) Reset() {
+	*x = Network{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Network) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Network) ProtoMessage() {}
+
+func (x *Network) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Network.ProtoReflect.Descriptor instead.
+func (*Network) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Network) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Network) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *Network) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+// Statistics mirrors geoservice.Statistics
+type Statistics struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Elapsed               *durationpb.Duration   `protobuf:"bytes,1,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	ElapsedParsed         *durationpb.Duration   `protobuf:"bytes,2,opt,name=elapsed_parsed,json=elapsedParsed,proto3" json:"elapsed_parsed,omitempty"`
+	ElapsedAppend         *durationpb.Duration   `protobuf:"bytes,3,opt,name=elapsed_append,json=elapsedAppend,proto3" json:"elapsed_append,omitempty"`
+	ElapsedStore          *durationpb.Duration   `protobuf:"bytes,4,opt,name=elapsed_store,json=elapsedStore,proto3" json:"elapsed_store,omitempty"`
+	Duplicates            int64                  `protobuf:"varint,5,opt,name=duplicates,proto3" json:"duplicates,omitempty"`
+	AcceptedEntries       int64                  `protobuf:"varint,6,opt,name=accepted_entries,json=acceptedEntries,proto3" json:"accepted_entries,omitempty"`
+	DiscardedEntries      int64                  `protobuf:"varint,7,opt,name=discarded_entries,json=discardedEntries,proto3" json:"discarded_entries,omitempty"`
+	StoredEntries         int64                  `protobuf:"varint,8,opt,name=stored_entries,json=storedEntries,proto3" json:"stored_entries,omitempty"`
+	FailedEntries         int64                  `protobuf:"varint,9,opt,name=failed_entries,json=failedEntries,proto3" json:"failed_entries,omitempty"`
+	BytesRead             int64                  `protobuf:"varint,10,opt,name=bytes_read,json=bytesRead,proto3" json:"bytes_read,omitempty"`
+	RowsPerSecond         float64                `protobuf:"fixed64,11,opt,name=rows_per_second,json=rowsPerSecond,proto3" json:"rows_per_second,omitempty"`
+	ConflictingDuplicates int64                  `protobuf:"varint,12,opt,name=conflicting_duplicates,json=conflictingDuplicates,proto3" json:"conflicting_duplicates,omitempty"`
+	// discard_reasons counts discarded rows by reason
+	DiscardReasons    map[string]int64 `protobuf:"bytes,13,rep,name=discard_reasons,json=discardReasons,proto3" json:"discard_reasons,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	DistinctCountries int64            `protobuf:"varint,14,opt,name=distinct_countries,json=distinctCountries,proto3" json:"distinct_countries,omitempty"`
+	Coordinates       *CoordinateStats `protobuf:"bytes,15,opt,name=coordinates,proto3" json:"coordinates,omitempty"`
+	PeakHeapBytes     uint64           `protobuf:"varint,16,opt,name=peak_heap_bytes,json=peakHeapBytes,proto3" json:"peak_heap_bytes,omitempty"`
+	FilledCities      int64            `protobuf:"varint,17,opt,name=filled_cities,json=filledCities,proto3" json:"filled_cities,omitempty"`
+	// enrichment describes the enrichers of the import by name
+	Enrichment map[string]*EnrichmentStats `protobuf:"bytes,18,rep,name=enrichment,proto3" json:"enrichment,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// classes counts the rows with special-purpose IP addresses by ipclass flag
+	Classes       map[string]int64 `protobuf:"bytes,19,rep,name=classes,proto3" json:"classes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Statistics) Reset() {
+	*x = Statistics{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Statistics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Statistics) ProtoMessage() {}
+
+func (x *Statistics) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Statistics.ProtoReflect.Descriptor instead.
+func (*Statistics) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Statistics) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+func (x *Statistics) GetElapsedParsed() *durationpb.Duration {
+	if x != nil {
+		return x.ElapsedParsed
+	}
+	return nil
+}
+
+func (x *Statistics) GetElapsedAppend() *durationpb.Duration {
+	if x != nil {
+		return x.ElapsedAppend
+	}
+	return nil
+}
+
+func (x *Statistics) GetElapsedStore() *durationpb.Duration {
+	if x != nil {
+		return x.ElapsedStore
+	}
+	return nil
+}
+
+func (x *Statistics) GetDuplicates() int64 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *Statistics) GetAcceptedEntries() int64 {
+	if x != nil {
+		return x.AcceptedEntries
+	}
+	return 0
+}
+
+func (x *Statistics) GetDiscardedEntries() int64 {
+	if x != nil {
+		return x.DiscardedEntries
+	}
+	return 0
+}
+
+func (x *Statistics) GetStoredEntries() int64 {
+	if x != nil {
+		return x.StoredEntries
+	}
+	return 0
+}
+
+func (x *Statistics) GetFailedEntries() int64 {
+	if x != nil {
+		return x.FailedEntries
+	}
+	return 0
+}
+
+func (x *Statistics) GetBytesRead() int64 {
+	if x != nil {
+		return x.BytesRead
+	}
+	return 0
+}
+
+func (x *Statistics) GetRowsPerSecond() float64 {
+	if x != nil {
+		return x.RowsPerSecond
+	}
+	return 0
+}
+
+func (x *Statistics) GetConflictingDuplicates() int64 {
+	if x != nil {
+		return x.ConflictingDuplicates
+	}
+	return 0
+}
+
+func (x *Statistics) GetDiscardReasons() map[string]int64 {
+	if x != nil {
+		return x.DiscardReasons
+	}
+	return nil
+}
+
+func (x *Statistics) GetDistinctCountries() int64 {
+	if x != nil {
+		return x.DistinctCountries
+	}
+	return 0
+}
+
+func (x *Statistics) GetCoordinates() *CoordinateStats {
+	if x != nil {
+		return x.Coordinates
+	}
+	return nil
+}
+
+func (x *Statistics) GetPeakHeapBytes() uint64 {
+	if x != nil {
+		return x.PeakHeapBytes
+	}
+	return 0
+}
+
+func (x *Statistics) GetFilledCities() int64 {
+	if x != nil {
+		return x.FilledCities
+	}
+	return 0
+}
+
+func (x *Statistics) GetEnrichment() map[string]*EnrichmentStats {
+	if x != nil {
+		return x.Enrichment
+	}
+	return nil
+}
+
+func (x *Statistics) GetClasses() map[string]int64 {
+	if x != nil {
+		return x.Classes
+	}
+	return nil
+}
+
+type EnrichmentStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enriched      int64                  `protobuf:"varint,1,opt,name=enriched,proto3" json:"enriched,omitempty"`
+	Failed        int64                  `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`
+	Elapsed       *durationpb.Duration   `protobuf:"bytes,3,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnrichmentStats) Reset() {
+	*x = EnrichmentStats{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrichmentStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrichmentStats) ProtoMessage() {}
+
+func (x *EnrichmentStats) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrichmentStats.ProtoReflect.Descriptor instead.
+func (*EnrichmentStats) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EnrichmentStats) GetEnriched() int64 {
+	if x != nil {
+		return x.Enriched
+	}
+	return 0
+}
+
+func (x *EnrichmentStats) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *EnrichmentStats) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+type CoordinateStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinLatitude   float64                `protobuf:"fixed64,1,opt,name=min_latitude,json=minLatitude,proto3" json:"min_latitude,omitempty"`
+	MaxLatitude   float64                `protobuf:"fixed64,2,opt,name=max_latitude,json=maxLatitude,proto3" json:"max_latitude,omitempty"`
+	MeanLatitude  float64                `protobuf:"fixed64,3,opt,name=mean_latitude,json=meanLatitude,proto3" json:"mean_latitude,omitempty"`
+	MinLongitude  float64                `protobuf:"fixed64,4,opt,name=min_longitude,json=minLongitude,proto3" json:"min_longitude,omitempty"`
+	MaxLongitude  float64                `protobuf:"fixed64,5,opt,name=max_longitude,json=maxLongitude,proto3" json:"max_longitude,omitempty"`
+	MeanLongitude float64                `protobuf:"fixed64,6,opt,name=mean_longitude,json=meanLongitude,proto3" json:"mean_longitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoordinateStats) Reset() {
+	*x = CoordinateStats{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoordinateStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoordinateStats) ProtoMessage() {}
+
+func (x *CoordinateStats) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoordinateStats.ProtoReflect.Descriptor instead.
+func (*CoordinateStats) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CoordinateStats) GetMinLatitude() float64 {
+	if x != nil {
+		return x.MinLatitude
+	}
+	return 0
+}
+
+func (x *CoordinateStats) GetMaxLatitude() float64 {
+	if x != nil {
+		return x.MaxLatitude
+	}
+	return 0
+}
+
+func (x *CoordinateStats) GetMeanLatitude() float64 {
+	if x != nil {
+		return x.MeanLatitude
+	}
+	return 0
+}
+
+func (x *CoordinateStats) GetMinLongitude() float64 {
+	if x != nil {
+		return x.MinLongitude
+	}
+	return 0
+}
+
+func (x *CoordinateStats) GetMaxLongitude() float64 {
+	if x != nil {
+		return x.MaxLongitude
+	}
+	return 0
+}
+
+func (x *CoordinateStats) GetMeanLongitude() float64 {
+	if x != nil {
+		return x.MeanLongitude
+	}
+	return 0
+}
+
+type LookupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress     string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LookupRequest) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+type LookupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Location      *GeoLocation           `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupResponse) Reset() {
+	*x = LookupResponse{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupResponse) ProtoMessage() {}
+
+func (x *LookupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupResponse.ProtoReflect.Descriptor instead.
+func (*LookupResponse) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LookupResponse) GetLocation() *GeoLocation {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+type BulkLookupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress     string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkLookupRequest) Reset() {
+	*x = BulkLookupRequest{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkLookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkLookupRequest) ProtoMessage() {}
+
+func (x *BulkLookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkLookupRequest.ProtoReflect.Descriptor instead.
+func (*BulkLookupRequest) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BulkLookupRequest) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+// BulkLookupResponse is sent for every BulkLookupRequest in the same order, either location or code and error are set
+type BulkLookupResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Location  *GeoLocation           `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	// code is a google.golang.org/grpc/codes value, OK when the location was found
+	Code          int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkLookupResponse) Reset() {
+	*x = BulkLookupResponse{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkLookupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkLookupResponse) ProtoMessage() {}
+
+func (x *BulkLookupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkLookupResponse.ProtoReflect.Descriptor instead.
+func (*BulkLookupResponse) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BulkLookupResponse) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *BulkLookupResponse) GetLocation() *GeoLocation {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *BulkLookupResponse) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BulkLookupResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ImportOptions mirrors geoservice.ImportOptions, zero values fall back to the defaults
+type ImportOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Workers       int32                  `protobuf:"varint,1,opt,name=workers,proto3" json:"workers,omitempty"`
+	Dedupe        DedupePolicy           `protobuf:"varint,2,opt,name=dedupe,proto3,enum=geoservice.v1.DedupePolicy" json:"dedupe,omitempty"`
+	ChunkSize     int32                  `protobuf:"varint,3,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	Writers       int32                  `protobuf:"varint,4,opt,name=writers,proto3" json:"writers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportOptions) Reset() {
+	*x = ImportOptions{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportOptions) ProtoMessage() {}
+
+func (x *ImportOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportOptions.ProtoReflect.Descriptor instead.
+func (*ImportOptions) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ImportOptions) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *ImportOptions) GetDedupe() DedupePolicy {
+	if x != nil {
+		return x.Dedupe
+	}
+	return DedupePolicy_DEDUPE_POLICY_KEEP_FIRST
+}
+
+func (x *ImportOptions) GetChunkSize() int32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *ImportOptions) GetWriters() int32 {
+	if x != nil {
+		return x.Writers
+	}
+	return 0
+}
+
+// ImportRequest carries CSV rows without the header row, options are only read from the first message
+type ImportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Options       *ImportOptions         `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	Rows          []string               `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ImportRequest) GetOptions() *ImportOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *ImportRequest) GetRows() []string {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type ImportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Statistics    *Statistics            `protobuf:"bytes,1,opt,name=statistics,proto3" json:"statistics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResponse) ProtoMessage() {}
+
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geoservice_v1_geoservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_geoservice_v1_geoservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ImportResponse) GetStatistics() *Statistics {
+	if x != nil {
+		return x.Statistics
+	}
+	return nil
+}
+
+var File_geoservice_v1_geoservice_proto protoreflect.FileDescriptor
+
+const file_geoservice_v1_geoservice_proto_rawDesc = "" +
+	"\n" +
+	"\x1egeoservice/v1/geoservice.proto\x12\rgeoservice.v1\x1a\x1egoogle/protobuf/duration.proto\"\xfe\x03\n" +
+	"\vGeoLocation\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12!\n" +
+	"\fcountry_code\x18\x02 \x01(\tR\vcountryCode\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x12\n" +
+	"\x04city\x18\x04 \x01(\tR\x04city\x12\x1a\n" +
+	"\blatitude\x18\x05 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x06 \x01(\x01R\tlongitude\x12#\n" +
+	"\rmystery_value\x18\a \x01(\x03R\fmysteryValue\x12'\n" +
+	"\x0fdataset_version\x18\b \x01(\tR\x0edatasetVersion\x12\x18\n" +
+	"\ageohash\x18\t \x01(\tR\ageohash\x12\x1a\n" +
+	"\btimezone\x18\n" +
+	" \x01(\tR\btimezone\x12;\n" +
+	"\x05extra\x18\v \x03(\v2%.geoservice.v1.GeoLocation.ExtraEntryR\x05extra\x120\n" +
+	"\anetwork\x18\f \x01(\v2\x16.geoservice.v1.NetworkR\anetwork\x12\x18\n" +
+	"\aclasses\x18\r \x03(\tR\aclasses\x1a8\n" +
+	"\n" +
+	"ExtraEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"W\n" +
+	"\aNetwork\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x10\n" +
+	"\x03asn\x18\x02 \x01(\rR\x03asn\x12\"\n" +
+	"\forganization\x18\x03 \x01(\tR\forganization\"\xca\t\n" +
+	"\n" +
+	"Statistics\x123\n" +
+	"\aelapsed\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\x12@\n" +
+	"\x0eelapsed_parsed\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\relapsedParsed\x12@\n" +
+	"\x0eelapsed_append\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\relapsedAppend\x12>\n" +
+	"\relapsed_store\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\felapsedStore\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x05 \x01(\x03R\n" +
+	"duplicates\x12)\n" +
+	"\x10accepted_entries\x18\x06 \x01(\x03R\x0facceptedEntries\x12+\n" +
+	"\x11discarded_entries\x18\a \x01(\x03R\x10discardedEntries\x12%\n" +
+	"\x0estored_entries\x18\b \x01(\x03R\rstoredEntries\x12%\n" +
+	"\x0efailed_entries\x18\t \x01(\x03R\rfailedEntries\x12\x1d\n" +
+	"\n" +
+	"bytes_read\x18\n" +
+	" \x01(\x03R\tbytesRead\x12&\n" +
+	"\x0frows_per_second\x18\v \x01(\x01R\rrowsPerSecond\x125\n" +
+	"\x16conflicting_duplicates\x18\f \x01(\x03R\x15conflictingDuplicates\x12V\n" +
+	"\x0fdiscard_reasons\x18\r \x03(\v2-.geoservice.v1.Statistics.DiscardReasonsEntryR\x0ediscardReasons\x12-\n" +
+	"\x12distinct_countries\x18\x0e \x01(\x03R\x11distinctCountries\x12@\n" +
+	"\vcoordinates\x18\x0f \x01(\v2\x1e.geoservice.v1.CoordinateStatsR\vcoordinates\x12&\n" +
+	"\x0fpeak_heap_bytes\x18\x10 \x01(\x04R\rpeakHeapBytes\x12#\n" +
+	"\rfilled_cities\x18\x11 \x01(\x03R\ffilledCities\x12I\n" +
+	"\n" +
+	"enrichment\x18\x12 \x03(\v2).geoservice.v1.Statistics.EnrichmentEntryR\n" +
+	"enrichment\x12@\n" +
+	"\aclasses\x18\x13 \x03(\v2&.geoservice.v1.Statistics.ClassesEntryR\aclasses\x1aA\n" +
+	"\x13DiscardReasonsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a]\n" +
+	"\x0fEnrichmentEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x124\n" +
+	"\x05value\x18\x02 \x01(\v2\x1e.geoservice.v1.EnrichmentStatsR\x05value:\x028\x01\x1a:\n" +
+	"\fClassesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"z\n" +
+	"\x0fEnrichmentStats\x12\x1a\n" +
+	"\benriched\x18\x01 \x01(\x03R\benriched\x12\x16\n" +
+	"\x06failed\x18\x02 \x01(\x03R\x06failed\x123\n" +
+	"\aelapsed\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\"\xed\x01\n" +
+	"\x0fCoordinateStats\x12!\n" +
+	"\fmin_latitude\x18\x01 \x01(\x01R\vminLatitude\x12!\n" +
+	"\fmax_latitude\x18\x02 \x01(\x01R\vmaxLatitude\x12#\n" +
+	"\rmean_latitude\x18\x03 \x01(\x01R\fmeanLatitude\x12#\n" +
+	"\rmin_longitude\x18\x04 \x01(\x01R\fminLongitude\x12#\n" +
+	"\rmax_longitude\x18\x05 \x01(\x01R\fmaxLongitude\x12%\n" +
+	"\x0emean_longitude\x18\x06 \x01(\x01R\rmeanLongitude\".\n" +
+	"\rLookupRequest\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\"H\n" +
+	"\x0eLookupResponse\x126\n" +
+	"\blocation\x18\x01 \x01(\v2\x1a.geoservice.v1.GeoLocationR\blocation\"2\n" +
+	"\x11BulkLookupRequest\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\"\x95\x01\n" +
+	"\x12BulkLookupResponse\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x126\n" +
+	"\blocation\x18\x02 \x01(\v2\x1a.geoservice.v1.GeoLocationR\blocation\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x97\x01\n" +
+	"\rImportOptions\x12\x18\n" +
+	"\aworkers\x18\x01 \x01(\x05R\aworkers\x123\n" +
+	"\x06dedupe\x18\x02 \x01(\x0e2\x1b.geoservice.v1.DedupePolicyR\x06dedupe\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x03 \x01(\x05R\tchunkSize\x12\x18\n" +
+	"\awriters\x18\x04 \x01(\x05R\awriters\"[\n" +
+	"\rImportRequest\x126\n" +
+	"\aoptions\x18\x01 \x01(\v2\x1c.geoservice.v1.ImportOptionsR\aoptions\x12\x12\n" +
+	"\x04rows\x18\x02 \x03(\tR\x04rows\"K\n" +
+	"\x0eImportResponse\x129\n" +
+	"\n" +
+	"statistics\x18\x01 \x01(\v2\x19.geoservice.v1.StatisticsR\n" +
+	"statistics*H\n" +
+	"\fDedupePolicy\x12\x1c\n" +
+	"\x18DEDUPE_POLICY_KEEP_FIRST\x10\x00\x12\x1a\n" +
+	"\x16DEDUPE_POLICY_DISABLED\x10\x012\xf3\x01\n" +
+	"\n" +
+	"GeoService\x12E\n" +
+	"\x06Lookup\x12\x1c.geoservice.v1.LookupRequest\x1a\x1d.geoservice.v1.LookupResponse\x12U\n" +
+	"\n" +
+	"BulkLookup\x12 .geoservice.v1.BulkLookupRequest\x1a!.geoservice.v1.BulkLookupResponse(\x010\x01\x12G\n" +
+	"\x06Import\x12\x1c.geoservice.v1.ImportRequest\x1a\x1d.geoservice.v1.ImportResponse(\x01B8Z6github.com/aliforever/geo-service/grpcapi/geoservicepbb\x06proto3"
+
+var (
+	file_geoservice_v1_geoservice_proto_rawDescOnce sync.Once
+	file_geoservice_v1_geoservice_proto_rawDescData []byte
+)
+
+func file_geoservice_v1_geoservice_proto_rawDescGZIP() []byte {
+	file_geoservice_v1_geoservice_proto_rawDescOnce.Do(func() {
+		file_geoservice_v1_geoservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_geoservice_v1_geoservice_proto_rawDesc), len(file_geoservice_v1_geoservice_proto_rawDesc)))
+	})
+	return file_geoservice_v1_geoservice_proto_rawDescData
+}
+
+var file_geoservice_v1_geoservice_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_geoservice_v1_geoservice_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_geoservice_v1_geoservice_proto_goTypes = []any{
+	(DedupePolicy)(0),           // 0: geoservice.v1.DedupePolicy
+	(*GeoLocation)(nil),         // 1: geoservice.v1.GeoLocation
+	(*Network)(nil),             // 2: geoservice.v1.Network
+	(*Statistics)(nil),          // 3: geoservice.v1.Statistics
+	(*EnrichmentStats)(nil),     // 4: geoservice.v1.EnrichmentStats
+	(*CoordinateStats)(nil),     // 5: geoservice.v1.CoordinateStats
+	(*LookupRequest)(nil),       // 6: geoservice.v1.LookupRequest
+	(*LookupResponse)(nil),      // 7: geoservice.v1.LookupResponse
+	(*BulkLookupRequest)(nil),   // 8: geoservice.v1.BulkLookupRequest
+	(*BulkLookupResponse)(nil),  // 9: geoservice.v1.BulkLookupResponse
+	(*ImportOptions)(nil),       // 10: geoservice.v1.ImportOptions
+	(*ImportRequest)(nil),       // 11: geoservice.v1.ImportRequest
+	(*ImportResponse)(nil),      // 12: geoservice.v1.ImportResponse
+	nil,                         // 13: geoservice.v1.GeoLocation.ExtraEntry
+	nil,                         // 14: geoservice.v1.Statistics.DiscardReasonsEntry
+	nil,                         // 15: geoservice.v1.Statistics.EnrichmentEntry
+	nil,                         // 16: geoservice.v1.Statistics.ClassesEntry
+	(*durationpb.Duration)(nil), // 17: google.protobuf.Duration
+}
+var file_geoservice_v1_geoservice_proto_depIdxs = []int32{
+	13, // 0: geoservice.v1.GeoLocation.extra:type_name -> geoservice.v1.GeoLocation.ExtraEntry
+	2,  // 1: geoservice.v1.GeoLocation.network:type_name -> geoservice.v1.Network
+	17, // 2: geoservice.v1.Statistics.elapsed:type_name -> google.protobuf.Duration
+	17, // 3: geoservice.v1.Statistics.elapsed_parsed:type_name -> google.protobuf.Duration
+	17, // 4: geoservice.v1.Statistics.elapsed_append:type_name -> google.protobuf.Duration
+	17, // 5: geoservice.v1.Statistics.elapsed_store:type_name -> google.protobuf.Duration
+	14, // 6: geoservice.v1.Statistics.discard_reasons:type_name -> geoservice.v1.Statistics.DiscardReasonsEntry
+	5,  // 7: geoservice.v1.Statistics.coordinates:type_name -> geoservice.v1.CoordinateStats
+	15, // 8: geoservice.v1.Statistics.enrichment:type_name -> geoservice.v1.Statistics.EnrichmentEntry
+	16, // 9: geoservice.v1.Statistics.classes:type_name -> geoservice.v1.Statistics.ClassesEntry
+	17, // 10: geoservice.v1.EnrichmentStats.elapsed:type_name -> google.protobuf.Duration
+	1,  // 11: geoservice.v1.LookupResponse.location:type_name -> geoservice.v1.GeoLocation
+	1,  // 12: geoservice.v1.BulkLookupResponse.location:type_name -> geoservice.v1.GeoLocation
+	0,  // 13: geoservice.v1.ImportOptions.dedupe:type_name -> geoservice.v1.DedupePolicy
+	10, // 14: geoservice.v1.ImportRequest.options:type_name -> geoservice.v1.ImportOptions
+	3,  // 15: geoservice.v1.ImportResponse.statistics:type_name -> geoservice.v1.Statistics
+	4,  // 16: geoservice.v1.Statistics.EnrichmentEntry.value:type_name -> geoservice.v1.EnrichmentStats
+	6,  // 17: geoservice.v1.GeoService.Lookup:input_type -> geoservice.v1.LookupRequest
+	8,  // 18: geoservice.v1.GeoService.BulkLookup:input_type -> geoservice.v1.BulkLookupRequest
+	11, // 19: geoservice.v1.GeoService.Import:input_type -> geoservice.v1.ImportRequest
+	7,  // 20: geoservice.v1.GeoService.Lookup:output_type -> geoservice.v1.LookupResponse
+	9,  // 21: geoservice.v1.GeoService.BulkLookup:output_type -> geoservice.v1.BulkLookupResponse
+	12, // 22: geoservice.v1.GeoService.Import:output_type -> geoservice.v1.ImportResponse
+	20, // [20:23] is the sub-list for method output_type
+	17, // [17:20] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_geoservice_v1_geoservice_proto_init() }
+func file_geoservice_v1_geoservice_proto_init() {
+	if File_geoservice_v1_geoservice_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_geoservice_v1_geoservice_proto_rawDesc), len(file_geoservice_v1_geoservice_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_geoservice_v1_geoservice_proto_goTypes,
+		DependencyIndexes: file_geoservice_v1_geoservice_proto_depIdxs,
+		EnumInfos:         file_geoservice_v1_geoservice_proto_enumTypes,
+		MessageInfos:      file_geoservice_v1_geoservice_proto_msgTypes,
+	}.Build()
+	File_geoservice_v1_geoservice_proto = out.File
+	file_geoservice_v1_geoservice_proto_goTypes = nil
+	file_geoservice_v1_geoservice_proto_depIdxs = nil
+}
@@ -0,0 +1,256 @@
+package grpcapi
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/grpcapi/geoservicepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"io"
+	"net"
+	"strings"
+)
+
+// csvHeader is prepended to imported rows since Import skips the first line
+const csvHeader = "ip_address,country_code,country,city,latitude,longitude,mystery_value\n"
+
+// Server implements geoservicepb.GeoServiceServer on top of a GeoService
+type Server struct {
+	geoservicepb.UnimplementedGeoServiceServer
+	gs *geoservice.GeoService
+}
+
+func NewServer(gs *geoservice.GeoService) *Server {
+	return &Server{gs: gs}
+}
+
+// codeFromError maps RetrieveLocation errors to gRPC codes
+func codeFromError(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, geoservice.ErrInvalidIP):
+		return codes.InvalidArgument
+	case errors.Is(err, geolocation.ErrNotFound):
+		return codes.NotFound
+	default:
+		return codes.Internal
+	}
+}
+
+func toProtoLocation(location *geolocation.GeoLocation) *geoservicepb.GeoLocation {
+	if location == nil {
+		return nil
+	}
+
+	var extra map[string]string
+	if len(location.Extra) > 0 {
+		extra = make(map[string]string, len(location.Extra))
+		for key, value := range location.Extra {
+			extra[key] = string(value)
+		}
+	}
+	var network *geoservicepb.Network
+	if location.Network != nil {
+		network = &geoservicepb.Network{
+			Prefix:       location.Network.Prefix.String(),
+			Asn:          location.Network.ASN,
+			Organization: location.Network.Organization,
+		}
+	}
+	return &geoservicepb.GeoLocation{
+		IpAddress:      location.IPAddress.String(),
+		CountryCode:    location.CountryCode,
+		Country:        location.Country,
+		City:           location.City,
+		Latitude:       location.Latitude,
+		Longitude:      location.Longitude,
+		MysteryValue:   location.MysteryValue,
+		DatasetVersion: location.DatasetVersion,
+		Geohash:        location.Geohash,
+		Timezone:       location.Timezone,
+		Extra:          extra,
+		Network:        network,
+		Classes:        location.Class.Names(),
+	}
+}
+
+func toProtoStatistics(stat *geoservice.Statistics) *geoservicepb.Statistics {
+	if stat == nil {
+		return nil
+	}
+
+	reasons := make(map[string]int64, len(stat.DiscardReasons))
+	for reason, count := range stat.DiscardReasons {
+		reasons[reason] = int64(count)
+	}
+	var classes map[string]int64
+	if len(stat.Classes) > 0 {
+		classes = make(map[string]int64, len(stat.Classes))
+		for class, count := range stat.Classes {
+			classes[class] = int64(count)
+		}
+	}
+	var enrichment map[string]*geoservicepb.EnrichmentStats
+	if len(stat.Enrichment) > 0 {
+		enrichment = make(map[string]*geoservicepb.EnrichmentStats, len(stat.Enrichment))
+		for name, enricher := range stat.Enrichment {
+			enrichment[name] = &geoservicepb.EnrichmentStats{
+				Enriched: int64(enricher.Enriched),
+				Failed:   int64(enricher.Failed),
+				Elapsed:  durationpb.New(enricher.Elapsed),
+			}
+		}
+	}
+	return &geoservicepb.Statistics{
+		Elapsed:          durationpb.New(stat.Elapsed),
+		ElapsedParsed:    durationpb.New(stat.ElapsedParsed),
+		ElapsedAppend:    durationpb.New(stat.ElapsedAppend),
+		ElapsedStore:     durationpb.New(stat.ElapsedStore),
+		Duplicates:       int64(stat.Duplicates),
+		AcceptedEntries:  int64(stat.AcceptedEntries),
+		DiscardedEntries: int64(stat.DiscardedEntries),
+		StoredEntries:    int64(stat.StoredEntries),
+		FailedEntries:    int64(stat.FailedEntries),
+
+		BytesRead:             stat.BytesRead,
+		RowsPerSecond:         stat.RowsPerSecond,
+		ConflictingDuplicates: int64(stat.ConflictingDuplicates),
+		DiscardReasons:        reasons,
+		DistinctCountries:     int64(stat.DistinctCountries),
+		Coordinates: &geoservicepb.CoordinateStats{
+			MinLatitude:   stat.Coordinates.MinLatitude,
+			MaxLatitude:   stat.Coordinates.MaxLatitude,
+			MeanLatitude:  stat.Coordinates.MeanLatitude,
+			MinLongitude:  stat.Coordinates.MinLongitude,
+			MaxLongitude:  stat.Coordinates.MaxLongitude,
+			MeanLongitude: stat.Coordinates.MeanLongitude,
+		},
+		PeakHeapBytes: stat.PeakHeapBytes,
+		FilledCities:  int64(stat.FilledCities),
+		Enrichment:    enrichment,
+		Classes:       classes,
+	}
+}
+
+func toImportOptions(opts *geoservicepb.ImportOptions) (importOpts geoservice.ImportOptions) {
+	if opts == nil {
+		return
+	}
+	importOpts.Workers = int(opts.GetWorkers())
+	importOpts.Batch.ChunkSize = int(opts.GetChunkSize())
+	importOpts.Batch.Writers = int(opts.GetWriters())
+	if opts.GetDedupe() == geoservicepb.DedupePolicy_DEDUPE_POLICY_DISABLED {
+		importOpts.Dedupe = geoservice.DedupeDisabled
+	}
+	return
+}
+
+func (s *Server) Lookup(_ context.Context, request *geoservicepb.LookupRequest) (*geoservicepb.LookupResponse, error) {
+	location, err := s.gs.RetrieveLocation(net.ParseIP(request.GetIpAddress()))
+	if err != nil {
+		return nil, status.Error(codeFromError(err), err.Error())
+	}
+	return &geoservicepb.LookupResponse{Location: toProtoLocation(location)}, nil
+}
+
+// BulkLookup answers every received IP address in order, lookup errors are reported per response
+func (s *Server) BulkLookup(stream geoservicepb.GeoService_BulkLookupServer) error {
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		location, lookupErr := s.gs.RetrieveLocation(net.ParseIP(request.GetIpAddress()))
+		response := &geoservicepb.BulkLookupResponse{
+			IpAddress: request.GetIpAddress(),
+			Location:  toProtoLocation(location),
+			Code:      int32(codeFromError(lookupErr)),
+		}
+		if lookupErr != nil {
+			response.Error = lookupErr.Error()
+		}
+		if err = stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// Import streams the received rows into GeoService.Import and returns its Statistics once the client closes the stream.
+// Partially stored imports still return their Statistics, other failures are reported as errors.
+func (s *Server) Import(stream geoservicepb.GeoService_ImportServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no rows received")
+	}
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+
+	type importResult struct {
+		stat *geoservice.Statistics
+		err  error
+	}
+	done := make(chan importResult, 1)
+	go func() {
+		stat, importErr := s.gs.Import(stream.Context(), reader, toImportOptions(first.GetOptions()))
+		reader.CloseWithError(importErr)
+		done <- importResult{stat: stat, err: importErr}
+	}()
+
+	writeRows := func(rows []string) (err error) {
+		for _, row := range rows {
+			if _, err = io.WriteString(writer, strings.TrimRight(row, "\r\n")+"\n"); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	// Writing stops early when Import fails since the reader is closed with its error
+	var recvErr error
+	writeErr := func() (err error) {
+		if _, err = io.WriteString(writer, csvHeader); err != nil {
+			return
+		}
+		if err = writeRows(first.GetRows()); err != nil {
+			return
+		}
+		for {
+			var request *geoservicepb.ImportRequest
+			if request, recvErr = stream.Recv(); recvErr != nil {
+				if recvErr == io.EOF {
+					recvErr = nil
+				}
+				return recvErr
+			}
+			if err = writeRows(request.GetRows()); err != nil {
+				return
+			}
+		}
+	}()
+	writer.CloseWithError(writeErr)
+
+	result := <-done
+	if recvErr != nil {
+		return recvErr
+	}
+	if result.err != nil && !errors.Is(result.err, geoservice.ErrIncompleteStore) {
+		if errors.Is(result.err, context.Canceled) {
+			return status.Error(codes.Canceled, result.err.Error())
+		}
+		return status.Error(codes.Internal, result.err.Error())
+	}
+	return stream.SendAndClose(&geoservicepb.ImportResponse{Statistics: toProtoStatistics(result.stat)})
+}
@@ -0,0 +1,146 @@
+package grpcapi
+
+import (
+	"context"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/grpcapi/geoservicepb"
+	"github.com/aliforever/geo-service/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+func newTestClient(t *testing.T) geoservicepb.GeoServiceClient {
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer()
+	geoservicepb.RegisterGeoServiceServer(server, NewServer(geoservice.NewGeoService(memory.New())))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return geoservicepb.NewGeoServiceClient(conn)
+}
+
+func importRows(t *testing.T, client geoservicepb.GeoServiceClient) *geoservicepb.Statistics {
+	stream, err := client.Import(context.Background())
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	requests := []*geoservicepb.ImportRequest{
+		{
+			Options: &geoservicepb.ImportOptions{Workers: 2, ChunkSize: 2},
+			Rows: []string{
+				"200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346",
+				"160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115",
+			},
+		},
+		{
+			Rows: []string{
+				",PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0",
+				"160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115",
+				`152.159.31.208,GA,"Virgin Islands, British",Lake Wavatown,12.964804277773922,-56.656208830174734,1878158074`,
+			},
+		},
+	}
+	for _, request := range requests {
+		if err = stream.Send(request); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv() error = %v", err)
+	}
+	return response.GetStatistics()
+}
+
+func TestServer_Import(t *testing.T) {
+	client := newTestClient(t)
+
+	stat := importRows(t, client)
+	if stat.GetStoredEntries() != 3 || stat.GetDuplicates() != 1 || stat.GetDiscardedEntries() != 1 {
+		t.Errorf("Import() statistics = %v", stat)
+	}
+
+	response, err := client.Lookup(context.Background(), &geoservicepb.LookupRequest{IpAddress: "152.159.31.208"})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if response.GetLocation().GetCountry() != "Virgin Islands, British" {
+		t.Errorf("Lookup() location = %v", response.GetLocation())
+	}
+}
+
+func TestServer_Lookup(t *testing.T) {
+	client := newTestClient(t)
+	importRows(t, client)
+
+	tests := []struct {
+		name     string
+		ip       string
+		wantCode codes.Code
+	}{
+		{name: "Found", ip: "200.106.141.15", wantCode: codes.OK},
+		{name: "NotFound", ip: "70.95.73.73", wantCode: codes.NotFound},
+		{name: "InvalidIP", ip: "nope", wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Lookup(context.Background(), &geoservicepb.LookupRequest{IpAddress: tt.ip})
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Lookup() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServer_BulkLookup(t *testing.T) {
+	client := newTestClient(t)
+	importRows(t, client)
+
+	stream, err := client.BulkLookup(context.Background())
+	if err != nil {
+		t.Fatalf("BulkLookup() error = %v", err)
+	}
+
+	ips := []string{"160.103.7.140", "70.95.73.73", "nope"}
+	wantCodes := []codes.Code{codes.OK, codes.NotFound, codes.InvalidArgument}
+	for _, ip := range ips {
+		if err = stream.Send(&geoservicepb.BulkLookupRequest{IpAddress: ip}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	stream.CloseSend()
+
+	for index := 0; ; index++ {
+		response, recvErr := stream.Recv()
+		if recvErr == io.EOF {
+			if index != len(ips) {
+				t.Errorf("BulkLookup() received %d responses, want %d", index, len(ips))
+			}
+			return
+		}
+		if recvErr != nil {
+			t.Fatalf("Recv() error = %v", recvErr)
+		}
+		if response.GetIpAddress() != ips[index] || codes.Code(response.GetCode()) != wantCodes[index] {
+			t.Errorf("BulkLookup() response %d = %v, want %s with %v", index, response, ips[index], wantCodes[index])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ASNResponse is the body returned by PUT /v1/asn
+type ASNResponse struct {
+	Networks int `json:"networks"`
+}
+
+func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	value := strings.TrimPrefix(r.URL.Path, "/v1/networks/")
+	if value == "" || strings.Contains(value, "/") {
+		writeError(w, http.StatusNotFound, errors.New("not_found"))
+		return
+	}
+
+	network, err := s.gs.Network(net.ParseIP(value))
+	if err != nil {
+		writeError(w, statusFromError(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, network)
+}
+
+// handleASN replaces the ASN data with the uploaded network,asn,org CSV, it requires the upload token
+func (s *Server) handleASN(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errUnauthorized)
+		return
+	}
+
+	if s.opts.Upload.MaxBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.Upload.MaxBytes)
+	}
+	networks, err := s.gs.ImportASN(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid_body: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, ASNResponse{Networks: networks})
+}
@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"encoding/json"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_ASN(t *testing.T) {
+	db := memory.New()
+	db.Store(&geolocation.GeoLocation{IPAddress: net.ParseIP("1.1.1.1"), CountryCode: "AU", City: "Sydney"})
+	s := New(geoservice.NewGeoService(db), Options{Upload: UploadOptions{Token: "secret"}})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "NotLoaded", method: http.MethodGet, path: "/v1/networks/1.1.1.1", wantStatus: http.StatusNotFound},
+		{name: "Unauthorized", method: http.MethodPut, path: "/v1/asn", token: "wrong", body: "network,asn,org\n", wantStatus: http.StatusUnauthorized},
+		{name: "InvalidCSV", method: http.MethodPut, path: "/v1/asn", token: "secret", body: "network,asn,org\n1.1.1.0/24,x,Cloudflare\n", wantStatus: http.StatusBadRequest},
+		{name: "Upload", method: http.MethodPut, path: "/v1/asn", token: "secret", body: "network,asn,org\n1.1.1.0/24,13335,Cloudflare\n", wantStatus: http.StatusOK, wantBody: `{"networks":1}`},
+		{name: "Network", method: http.MethodGet, path: "/v1/networks/1.1.1.1", wantStatus: http.StatusOK, wantBody: `{"prefix":"1.1.1.0/24","asn":13335,"organization":"Cloudflare"}`},
+		{name: "UnknownNetwork", method: http.MethodGet, path: "/v1/networks/8.8.8.8", wantStatus: http.StatusNotFound},
+		{name: "InvalidIP", method: http.MethodGet, path: "/v1/networks/invalid", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+
+	// lookups carry the network along with the location
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/locations/1.1.1.1", nil))
+	var location geolocation.GeoLocation
+	if err := json.NewDecoder(w.Body).Decode(&location); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if location.City != "Sydney" || location.Network == nil || location.Network.ASN != 13335 {
+		t.Errorf("ServeHTTP() = %+v, want Sydney with AS13335", location)
+	}
+}
@@ -0,0 +1,273 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const DefaultMaxBulk = 1000
+
+// Options configures the HTTP API
+type Options struct {
+	// TrustedProxies are the networks whose X-Forwarded-For header is honored when resolving the caller's IP address
+	TrustedProxies []*net.IPNet
+	// MaxBulk is the maximum number of IP addresses of a bulk lookup, defaults to DefaultMaxBulk
+	MaxBulk int
+	Upload  UploadOptions
+}
+
+// Server exposes GeoService lookups over HTTP:
+//   - GET  /v1/locations/{ip} looks up a single IP address
+//   - POST /v1/lookup looks up {"ips": [...]} at once
+//   - GET  /v1/me looks up the caller's own IP address
+//   - GET  /v1/nearest?lat=&lng=&k= returns the k locations closest to a point
+//   - GET  /v1/within?lat=&lng=&radius_km=&limit= returns the locations within a radius of a point
+//   - GET  /v1/box?min_lat=&min_lng=&max_lat=&max_lng=&limit= returns the locations inside a bounding box
+//   - POST /v1/polygon?limit= returns the locations inside the GeoJSON polygon of the body
+//   - GET  /v1/geohash/{prefix}?limit= returns the locations whose geohash starts with prefix
+//   - GET  /v1/heatmap?precision=&prefix=&limit= counts locations per geohash cell
+//   - GET  /v1/reverse?lat=&lng=&source= returns the closest city of the gazetteer, or of the dataset with source=dataset
+//   - GET  /v1/search?field=&key=&offset=&limit= returns the locations whose country_code, country or city matches key
+//   - GET  /v1/counts?field= counts locations per country_code, country or city
+//   - GET  /v1/networks/{ip} returns the network, autonomous system and organization of an IP address
+//   - POST /v1/imports uploads a CSV which is imported in the background, when Upload.Token is set
+//   - GET  /v1/imports/{id} returns the progress of an upload
+//   - PUT  /v1/asn replaces the ASN data with a network,asn,org CSV, when Upload.Token is set
+type Server struct {
+	gs   *geoservice.GeoService
+	opts Options
+	mux  *http.ServeMux
+	jobs *jobs
+}
+
+// BulkRequest is the body of POST /v1/lookup
+type BulkRequest struct {
+	IPs []string `json:"ips"`
+}
+
+// BulkResult is the lookup of a single IP address inside a bulk lookup, either Location or Error is set
+type BulkResult struct {
+	IP       string                   `json:"ip"`
+	Location *geolocation.GeoLocation `json:"location,omitempty"`
+	Status   int                      `json:"status"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Results []BulkResult `json:"results"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(gs *geoservice.GeoService, opts Options) (s *Server) {
+	if opts.MaxBulk <= 0 {
+		opts.MaxBulk = DefaultMaxBulk
+	}
+
+	opts.Upload = opts.Upload.withDefaults()
+
+	s = &Server{gs: gs, opts: opts, mux: http.NewServeMux(), jobs: newJobs()}
+	s.mux.HandleFunc("/v1/locations/", s.handleLocation)
+	s.mux.HandleFunc("/v1/lookup", s.handleBulk)
+	s.mux.HandleFunc("/v1/me", s.handleMe)
+	s.mux.HandleFunc("/v1/nearest", s.handleNearest)
+	s.mux.HandleFunc("/v1/within", s.handleWithin)
+	s.mux.HandleFunc("/v1/box", s.handleBox)
+	s.mux.HandleFunc("/v1/polygon", s.handlePolygon)
+	s.mux.HandleFunc("/v1/geohash/", s.handleGeohash)
+	s.mux.HandleFunc("/v1/heatmap", s.handleHeatmap)
+	s.mux.HandleFunc("/v1/reverse", s.handleReverse)
+	s.mux.HandleFunc("/v1/search", s.handleSearch)
+	s.mux.HandleFunc("/v1/counts", s.handleCounts)
+	s.mux.HandleFunc("/v1/networks/", s.handleNetwork)
+	if opts.Upload.Token != "" {
+		s.mux.HandleFunc("/v1/imports", s.handleImports)
+		s.mux.HandleFunc("/v1/imports/", s.handleImports)
+		s.mux.HandleFunc("/v1/asn", s.handleASN)
+	}
+	return
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR networks
+func ParseTrustedProxies(values []string) (networks []*net.IPNet, err error) {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				err = fmt.Errorf("invalid trusted proxy: %s", value)
+				return
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		var network *net.IPNet
+		if _, network, err = net.ParseCIDR(value); err != nil {
+			return
+		}
+		networks = append(networks, network)
+	}
+	return
+}
+
+// statusFromError maps RetrieveLocation errors to HTTP status codes
+func statusFromError(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, geoservice.ErrInvalidIP):
+		return http.StatusBadRequest
+	case errors.Is(err, geolocation.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// writeBodyError answers a request whose body couldn't be read, bodies over the limit of http.MaxBytesReader get 413
+func writeBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+		return
+	}
+	writeError(w, http.StatusBadRequest, errors.New("invalid_body"))
+}
+
+func (s *Server) isTrusted(ip net.IP) bool {
+	for _, network := range s.opts.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the caller's IP address. X-Forwarded-For is walked from the closest hop while hops are
+// trusted proxies, the first untrusted hop is the caller.
+func (s *Server) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !s.isTrusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !s.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (s *Server) lookup(w http.ResponseWriter, ip net.IP) {
+	location, err := s.gs.RetrieveLocation(ip)
+	if err != nil {
+		writeError(w, statusFromError(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, location)
+}
+
+func (s *Server) handleLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	value := strings.TrimPrefix(r.URL.Path, "/v1/locations/")
+	if value == "" || strings.Contains(value, "/") {
+		writeError(w, http.StatusNotFound, errors.New("not_found"))
+		return
+	}
+
+	s.lookup(w, net.ParseIP(value))
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	s.lookup(w, s.clientIP(r))
+}
+
+func (s *Server) handleBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	var request BulkRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(s.opts.MaxBulk)*64+1024)).Decode(&request); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(request.IPs) > s.opts.MaxBulk {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("too_many_ips: at most %d", s.opts.MaxBulk))
+		return
+	}
+
+	response := BulkResponse{Results: make([]BulkResult, len(request.IPs))}
+	for index, value := range request.IPs {
+		location, err := s.gs.RetrieveLocation(net.ParseIP(strings.TrimSpace(value)))
+		result := BulkResult{IP: value, Location: location, Status: statusFromError(err)}
+		if err != nil {
+			result.Error = err.Error()
+			if result.Status == http.StatusInternalServerError {
+				result.Error = http.StatusText(result.Status)
+			}
+		}
+		response.Results[index] = result
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"encoding/json"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T, opts Options) *Server {
+	db := memory.New()
+	for _, location := range []*geolocation.GeoLocation{
+		{IPAddress: net.ParseIP("200.106.141.15"), CountryCode: "SI", Country: "Nepal", City: "DuBuquemouth"},
+		{IPAddress: net.ParseIP("160.103.7.140"), CountryCode: "CZ", Country: "Nicaragua", City: "New Neva"},
+	} {
+		if err := db.Store(location); err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+	}
+	return New(geoservice.NewGeoService(db), opts)
+}
+
+func TestServer_Location(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantCity   string
+	}{
+		{name: "Found", method: http.MethodGet, path: "/v1/locations/200.106.141.15", wantStatus: http.StatusOK, wantCity: "DuBuquemouth"},
+		{name: "NotFound", method: http.MethodGet, path: "/v1/locations/70.95.73.73", wantStatus: http.StatusNotFound},
+		{name: "InvalidIP", method: http.MethodGet, path: "/v1/locations/300.1.1.1", wantStatus: http.StatusBadRequest},
+		{name: "MethodNotAllowed", method: http.MethodDelete, path: "/v1/locations/200.106.141.15", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCity == "" {
+				return
+			}
+
+			var location geolocation.GeoLocation
+			if err := json.NewDecoder(w.Body).Decode(&location); err != nil || location.City != tt.wantCity {
+				t.Errorf("ServeHTTP() city = %s, error = %v, want %s", location.City, err, tt.wantCity)
+			}
+		})
+	}
+}
+
+func TestServer_Bulk(t *testing.T) {
+	s := newTestServer(t, Options{MaxBulk: 3})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/lookup", strings.NewReader(`{"ips":["160.103.7.140","70.95.73.73","nope"]}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d: %s", w.Code, w.Body)
+	}
+
+	var response BulkResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("ServeHTTP() body error = %v", err)
+	}
+	wantStatuses := []int{http.StatusOK, http.StatusNotFound, http.StatusBadRequest}
+	for index, result := range response.Results {
+		if result.Status != wantStatuses[index] {
+			t.Errorf("ServeHTTP() result %d status = %d, want %d", index, result.Status, wantStatuses[index])
+		}
+	}
+	if response.Results[0].Location == nil || response.Results[0].Location.City != "New Neva" {
+		t.Errorf("ServeHTTP() result 0 = %+v", response.Results[0])
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/lookup", strings.NewReader(`{"ips":["1.1.1.1","1.1.1.2","1.1.1.3","1.1.1.4"]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ServeHTTP() status = %d for too many IPs, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/lookup", strings.NewReader(`{"ips":["`+strings.Repeat("1", 4096)+`"]}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("ServeHTTP() status = %d for a body over the limit, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/lookup", strings.NewReader(`{"ips":`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ServeHTTP() status = %d for a truncated body, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_Me(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	s := newTestServer(t, Options{TrustedProxies: proxies})
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  string
+		wantStatus    int
+		wantIPAddress string
+	}{
+		{name: "Direct", remoteAddr: "200.106.141.15:1234", wantStatus: http.StatusOK, wantIPAddress: "200.106.141.15"},
+		{name: "TrustedProxies", remoteAddr: "10.1.1.1:1234", forwardedFor: "1.2.3.4, 160.103.7.140, 192.168.1.1", wantStatus: http.StatusOK, wantIPAddress: "160.103.7.140"},
+		{name: "UntrustedProxy", remoteAddr: "70.95.73.73:1234", forwardedFor: "160.103.7.140", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/me", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantIPAddress == "" {
+				return
+			}
+
+			var location geolocation.GeoLocation
+			if err := json.NewDecoder(w.Body).Decode(&location); err != nil || location.IPAddress.String() != tt.wantIPAddress {
+				t.Errorf("ServeHTTP() ip = %s, error = %v, want %s", location.IPAddress, err, tt.wantIPAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package httpapi
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"net/http"
+	"strconv"
+)
+
+// SearchResponse is the body returned by GET /v1/search
+type SearchResponse struct {
+	Locations []*geolocation.GeoLocation `json:"locations"`
+	Total     int                        `json:"total"`
+	Offset    int                        `json:"offset"`
+}
+
+// CountsResponse is the body returned by GET /v1/counts
+type CountsResponse struct {
+	Counts []geolocation.KeyCount `json:"counts"`
+}
+
+// indexStatus maps secondary index errors to HTTP status codes
+func indexStatus(err error) int {
+	switch {
+	case errors.Is(err, geolocation.ErrUnknownField):
+		return http.StatusBadRequest
+	case errors.Is(err, geolocation.ErrUnsupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := queryLimit(query, "limit", s.opts.MaxBulk, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var offset int
+	if raw := query.Get("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid_offset"))
+			return
+		}
+	}
+
+	page := geolocation.Page{Offset: offset, Limit: limit}
+	locations, total, err := s.gs.FindLocations(geolocation.IndexField(query.Get("field")), query.Get("key"), page)
+	if err != nil {
+		writeError(w, indexStatus(err), err)
+		return
+	}
+	if locations == nil {
+		locations = []*geolocation.GeoLocation{}
+	}
+	writeJSON(w, http.StatusOK, SearchResponse{Locations: locations, Total: total, Offset: offset})
+}
+
+func (s *Server) handleCounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	counts, err := s.gs.CountLocations(geolocation.IndexField(r.URL.Query().Get("field")))
+	if err != nil {
+		writeError(w, indexStatus(err), err)
+		return
+	}
+	if counts == nil {
+		counts = []geolocation.KeyCount{}
+	}
+	writeJSON(w, http.StatusOK, CountsResponse{Counts: counts})
+}
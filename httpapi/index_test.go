@@ -0,0 +1,39 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_Search(t *testing.T) {
+	s := newTestServer(t, Options{MaxBulk: 10})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Found", path: "/v1/search?field=city&key=new%20neva", wantStatus: http.StatusOK, wantBody: `"total":1`},
+		{name: "Paged", path: "/v1/search?field=city&key=new%20neva&offset=1", wantStatus: http.StatusOK, wantBody: `{"locations":[],"total":1,"offset":1}`},
+		{name: "UnknownField", path: "/v1/search?field=asn&key=1", wantStatus: http.StatusBadRequest},
+		{name: "InvalidOffset", path: "/v1/search?field=city&key=x&offset=-1", wantStatus: http.StatusBadRequest},
+		{name: "TooLarge", path: "/v1/search?field=city&key=x&limit=11", wantStatus: http.StatusBadRequest},
+		{name: "Counts", path: "/v1/counts?field=country_code", wantStatus: http.StatusOK, wantBody: `{"key":"cz","value":"CZ","count":1}`},
+		{name: "CountsUnknownField", path: "/v1/counts?field=asn", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("ServeHTTP() body = %s, want it to contain %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
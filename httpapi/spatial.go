@@ -0,0 +1,321 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geocode"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultNearest = 10
+	// DefaultHeatmapPrecision is the geohash precision of GET /v1/heatmap without a precision parameter
+	DefaultHeatmapPrecision = 4
+	// maxPolygonBytes limits the GeoJSON body of POST /v1/polygon
+	maxPolygonBytes = 4 << 20
+)
+
+// SpatialResponse is the body returned by GET /v1/nearest and GET /v1/within
+type SpatialResponse struct {
+	Results []spatial.Result `json:"results"`
+	// Truncated is set when more locations matched than the requested limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// RegionResponse is the body returned by GET /v1/box and POST /v1/polygon
+type RegionResponse struct {
+	Locations []*geolocation.GeoLocation `json:"locations"`
+	// Truncated is set when more locations matched than the requested limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// HeatmapResponse is the body returned by GET /v1/heatmap
+type HeatmapResponse struct {
+	Cells []geoservice.GeohashCell `json:"cells"`
+	// Truncated is set when more cells matched than the requested limit, the cells with the most locations are kept
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// queryFloat parses a required float query parameter
+func queryFloat(query url.Values, name string) (value float64, err error) {
+	if value, err = strconv.ParseFloat(query.Get(name), 64); err != nil {
+		err = fmt.Errorf("invalid_%s", name)
+	}
+	return
+}
+
+// queryLimit parses an optional positive integer query parameter of at most max
+func queryLimit(query url.Values, name string, fallback, max int) (value int, err error) {
+	value = fallback
+	if raw := query.Get(name); raw != "" {
+		if value, err = strconv.Atoi(raw); err != nil || value <= 0 {
+			err = fmt.Errorf("invalid_%s", name)
+			return
+		}
+	}
+	if value > max {
+		err = fmt.Errorf("%s_too_large: at most %d", name, max)
+	}
+	return
+}
+
+// spatialStatus maps spatial query errors to HTTP status codes
+func spatialStatus(err error) int {
+	switch {
+	case errors.Is(err, spatial.ErrInvalidCoordinates), errors.Is(err, spatial.ErrInvalidGeoJSON), errors.Is(err, spatial.ErrInvalidGeohash):
+		return http.StatusBadRequest
+	case errors.Is(err, geolocation.ErrUnsupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parsePoint reads the lat and lng query parameters
+func parsePoint(query url.Values) (lat, lng float64, err error) {
+	if lat, err = queryFloat(query, "lat"); err != nil {
+		return
+	}
+	lng, err = queryFloat(query, "lng")
+	return
+}
+
+func (s *Server) handleNearest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	lat, lng, err := parsePoint(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	k, err := queryLimit(query, "k", DefaultNearest, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := s.gs.NearestLocations(lat, lng, k)
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SpatialResponse{Results: results})
+}
+
+func (s *Server) handleWithin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	lat, lng, err := parsePoint(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	radius, err := queryFloat(query, "radius_km")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := queryLimit(query, "limit", s.opts.MaxBulk, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := s.gs.LocationsWithin(lat, lng, radius)
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	response := SpatialResponse{Results: results}
+	if len(results) > limit {
+		response.Results, response.Truncated = results[:limit], true
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// writeRegion writes at most limit locations of a region search
+func writeRegion(w http.ResponseWriter, locations []*geolocation.GeoLocation, limit int) {
+	response := RegionResponse{Locations: locations}
+	if response.Locations == nil {
+		response.Locations = []*geolocation.GeoLocation{}
+	}
+	if len(locations) > limit {
+		response.Locations, response.Truncated = locations[:limit], true
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleBox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	var box geolocation.BoundingBox
+	for _, parameter := range []struct {
+		name  string
+		value *float64
+	}{
+		{"min_lat", &box.MinLatitude},
+		{"min_lng", &box.MinLongitude},
+		{"max_lat", &box.MaxLatitude},
+		{"max_lng", &box.MaxLongitude},
+	} {
+		var err error
+		if *parameter.value, err = queryFloat(query, parameter.name); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	limit, err := queryLimit(query, "limit", s.opts.MaxBulk, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	locations, err := s.gs.LocationsInBox(box)
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	writeRegion(w, locations, limit)
+}
+
+func (s *Server) handlePolygon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	limit, err := queryLimit(r.URL.Query(), "limit", s.opts.MaxBulk, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPolygonBytes))
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	shape, err := spatial.ParseGeoJSON(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	locations, err := s.gs.LocationsInPolygon(shape)
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	writeRegion(w, locations, limit)
+}
+
+func (s *Server) handleGeohash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/geohash/")
+	if prefix == "" || strings.Contains(prefix, "/") {
+		writeError(w, http.StatusNotFound, errors.New("not_found"))
+		return
+	}
+	limit, err := queryLimit(r.URL.Query(), "limit", s.opts.MaxBulk, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	locations, err := s.gs.LocationsByGeohash(prefix)
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	writeRegion(w, locations, limit)
+}
+
+func (s *Server) handleHeatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	precision, err := queryLimit(query, "precision", max(DefaultHeatmapPrecision, len(query.Get("prefix"))), spatial.MaxGeohashPrecision)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := queryLimit(query, "limit", s.opts.MaxBulk, s.opts.MaxBulk)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cells, err := s.gs.GeohashCells(query.Get("prefix"), precision)
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	response := HeatmapResponse{Cells: cells}
+	if response.Cells == nil {
+		response.Cells = []geoservice.GeohashCell{}
+	}
+	if len(cells) > limit {
+		response.Cells, response.Truncated = cells[:limit], true
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleReverse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	lat, lng, err := parsePoint(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reverse := s.gs.ReverseGeocode
+	switch query.Get("source") {
+	case "", "gazetteer":
+	case "dataset":
+		reverse = s.gs.ReverseGeocodeDataset
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("invalid_source"))
+		return
+	}
+
+	match, err := reverse(lat, lng)
+	if errors.Is(err, geocode.ErrEmptyGazetteer) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, spatialStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, match)
+}
@@ -0,0 +1,205 @@
+package httpapi
+
+import (
+	"encoding/json"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geocode"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_Spatial(t *testing.T) {
+	db := memory.New()
+	for _, location := range []*geolocation.GeoLocation{
+		{IPAddress: net.ParseIP("200.106.141.15"), City: "Ljubljana", Latitude: 46.05, Longitude: 14.51},
+		{IPAddress: net.ParseIP("160.103.7.140"), City: "Prague", Latitude: 50.08, Longitude: 14.43},
+		{IPAddress: net.ParseIP("70.95.73.73"), City: "Lima", Latitude: -12.05, Longitude: -77.04},
+	} {
+		db.Store(location)
+	}
+	s := New(geoservice.NewGeoService(db), Options{MaxBulk: 10})
+
+	tests := []struct {
+		name          string
+		path          string
+		wantStatus    int
+		wantCities    []string
+		wantTruncated bool
+	}{
+		{name: "Nearest", path: "/v1/nearest?lat=48.2&lng=16.37&k=2", wantStatus: http.StatusOK, wantCities: []string{"Prague", "Ljubljana"}},
+		{name: "NearestDefaultK", path: "/v1/nearest?lat=-12&lng=-77", wantStatus: http.StatusOK, wantCities: []string{"Lima", "Ljubljana", "Prague"}},
+		{name: "NearestTooMany", path: "/v1/nearest?lat=0&lng=0&k=11", wantStatus: http.StatusBadRequest},
+		{name: "Within", path: "/v1/within?lat=48.2&lng=16.37&radius_km=1000", wantStatus: http.StatusOK, wantCities: []string{"Prague", "Ljubljana"}},
+		{name: "WithinLimit", path: "/v1/within?lat=48.2&lng=16.37&radius_km=1000&limit=1", wantStatus: http.StatusOK, wantCities: []string{"Prague"}, wantTruncated: true},
+		{name: "MissingRadius", path: "/v1/within?lat=48.2&lng=16.37", wantStatus: http.StatusBadRequest},
+		{name: "InvalidLatitude", path: "/v1/nearest?lat=95&lng=0", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response SpatialResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(response.Results) != len(tt.wantCities) || response.Truncated != tt.wantTruncated {
+				t.Fatalf("ServeHTTP() = %+v, want cities %v", response, tt.wantCities)
+			}
+			for i, result := range response.Results {
+				if result.Location.City != tt.wantCities[i] {
+					t.Errorf("ServeHTTP() result %d = %s, want %s", i, result.Location.City, tt.wantCities[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Region(t *testing.T) {
+	db := memory.New()
+	for _, location := range []*geolocation.GeoLocation{
+		{IPAddress: net.ParseIP("200.106.141.15"), City: "Ljubljana", Latitude: 46.05, Longitude: 14.51},
+		{IPAddress: net.ParseIP("160.103.7.140"), City: "Prague", Latitude: 50.08, Longitude: 14.43},
+		{IPAddress: net.ParseIP("70.95.73.73"), City: "Suva", Latitude: -18.14, Longitude: 178.44},
+	} {
+		db.Store(location)
+	}
+	s := New(geoservice.NewGeoService(db), Options{MaxBulk: 10})
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		wantStatus    int
+		wantCities    []string
+		wantTruncated bool
+	}{
+		{name: "Box", method: http.MethodGet, path: "/v1/box?min_lat=45&min_lng=10&max_lat=51&max_lng=20", wantStatus: http.StatusOK, wantCities: []string{"Prague", "Ljubljana"}},
+		{name: "BoxLimit", method: http.MethodGet, path: "/v1/box?min_lat=45&min_lng=10&max_lat=51&max_lng=20&limit=1", wantStatus: http.StatusOK, wantCities: []string{"Prague"}, wantTruncated: true},
+		{name: "BoxAntimeridian", method: http.MethodGet, path: "/v1/box?min_lat=-20&min_lng=170&max_lat=-10&max_lng=-170", wantStatus: http.StatusOK, wantCities: []string{"Suva"}},
+		{name: "BoxEmpty", method: http.MethodGet, path: "/v1/box?min_lat=0&min_lng=0&max_lat=1&max_lng=1", wantStatus: http.StatusOK, wantCities: []string{}},
+		{name: "BoxMissing", method: http.MethodGet, path: "/v1/box?min_lat=0&min_lng=0&max_lat=1", wantStatus: http.StatusBadRequest},
+		{name: "BoxInverted", method: http.MethodGet, path: "/v1/box?min_lat=10&min_lng=0&max_lat=1&max_lng=1", wantStatus: http.StatusBadRequest},
+		{
+			name: "Polygon", method: http.MethodPost, path: "/v1/polygon",
+			body:       `{"type":"Polygon","coordinates":[[[13,45],[16,45],[16,48],[13,48],[13,45]]]}`,
+			wantStatus: http.StatusOK, wantCities: []string{"Ljubljana"},
+		},
+		{name: "PolygonInvalid", method: http.MethodPost, path: "/v1/polygon", body: `{"type":"Point","coordinates":[1,2]}`, wantStatus: http.StatusBadRequest},
+		{name: "PolygonMethod", method: http.MethodGet, path: "/v1/polygon", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response RegionResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(response.Locations) != len(tt.wantCities) || response.Truncated != tt.wantTruncated {
+				t.Fatalf("ServeHTTP() = %+v, want cities %v", response, tt.wantCities)
+			}
+			for i, location := range response.Locations {
+				if location.City != tt.wantCities[i] {
+					t.Errorf("ServeHTTP() location %d = %s, want %s", i, location.City, tt.wantCities[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Geohash(t *testing.T) {
+	db := memory.New()
+	for _, location := range []*geolocation.GeoLocation{
+		{IPAddress: net.ParseIP("200.106.141.15"), City: "Ljubljana", Latitude: 46.05, Longitude: 14.51},
+		{IPAddress: net.ParseIP("160.103.7.140"), City: "Prague", Latitude: 50.08, Longitude: 14.43},
+		{IPAddress: net.ParseIP("70.95.73.73"), City: "Suva", Latitude: -18.14, Longitude: 178.44},
+	} {
+		db.Store(location)
+	}
+	s := New(geoservice.NewGeoService(db), Options{MaxBulk: 10})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Prefix", path: "/v1/geohash/u24", wantStatus: http.StatusOK, wantBody: `"city":"Ljubljana"`},
+		{name: "InvalidPrefix", path: "/v1/geohash/ua", wantStatus: http.StatusBadRequest},
+		{name: "MissingPrefix", path: "/v1/geohash/", wantStatus: http.StatusNotFound},
+		{name: "Heatmap", path: "/v1/heatmap?precision=2", wantStatus: http.StatusOK, wantBody: `{"geohash":"u2","count":2,`},
+		{name: "HeatmapLimit", path: "/v1/heatmap?precision=1&limit=1", wantStatus: http.StatusOK, wantBody: `"truncated":true`},
+		{name: "HeatmapPrefix", path: "/v1/heatmap?prefix=u2f", wantStatus: http.StatusOK, wantBody: `{"geohash":"u2fk","count":1,`},
+		{name: "HeatmapPrecision", path: "/v1/heatmap?precision=13", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("ServeHTTP() body = %s, want it to contain %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Reverse(t *testing.T) {
+	db := memory.New()
+	db.Store(&geolocation.GeoLocation{IPAddress: net.ParseIP("160.103.7.140"), CountryCode: "CZ", City: "Praha", Latitude: 50.08, Longitude: 14.43})
+	s := New(geoservice.NewGeoService(db), Options{})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantName   string
+	}{
+		{name: "Gazetteer", path: "/v1/reverse?lat=48.2&lng=16.37", wantStatus: http.StatusOK, wantName: "Vienna"},
+		{name: "Dataset", path: "/v1/reverse?lat=48.2&lng=16.37&source=dataset", wantStatus: http.StatusOK, wantName: "Praha"},
+		{name: "InvalidSource", path: "/v1/reverse?lat=48.2&lng=16.37&source=osm", wantStatus: http.StatusBadRequest},
+		{name: "InvalidLatitude", path: "/v1/reverse?lat=95&lng=0", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var match geocode.Match
+			if err := json.NewDecoder(w.Body).Decode(&match); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if match.Place.Name != tt.wantName {
+				t.Errorf("ServeHTTP() = %+v, want %s", match, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package httpapi
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	geoservice "github.com/aliforever/geo-service"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRejectSample = 100
+	DefaultKeepJobs     = 100
+	// DefaultDecompressionRatio times UploadOptions.MaxBytes is the default limit of decompressed uploads
+	DefaultDecompressionRatio = 20
+)
+
+var (
+	errUnauthorized  = errors.New("unauthorized")
+	errImportRunning = errors.New("import_running")
+	errNoFile        = errors.New("missing_file")
+	errBodyTooLarge  = errors.New("body_too_large")
+)
+
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// UploadOptions configures POST /v1/imports, uploads are disabled when Token is empty
+type UploadOptions struct {
+	// Token has to be sent as "Authorization: Bearer <token>"
+	Token string
+	// MaxBytes limits the size of uploaded bodies before decompression, zero means unlimited
+	MaxBytes int64
+	// MaxDecompressedBytes limits the size of gzipped uploads after decompression, defaults to
+	// DefaultDecompressionRatio times MaxBytes. Zero along with an unlimited MaxBytes means unlimited.
+	MaxDecompressedBytes int64
+	// Import configures the imports, OnReject and OnProgress are set by the server
+	Import geoservice.ImportOptions
+	// Versioned imports every upload as a new dataset through ImportVersion instead of Import
+	Versioned bool
+	// RejectSample is the number of rejected rows kept per job, defaults to DefaultRejectSample
+	RejectSample int
+	// KeepJobs is the number of finished jobs kept for status queries, defaults to DefaultKeepJobs
+	KeepJobs int
+}
+
+func (o UploadOptions) withDefaults() UploadOptions {
+	if o.RejectSample <= 0 {
+		o.RejectSample = DefaultRejectSample
+	}
+	if o.KeepJobs <= 0 {
+		o.KeepJobs = DefaultKeepJobs
+	}
+	if o.MaxDecompressedBytes <= 0 && o.MaxBytes > 0 {
+		o.MaxDecompressedBytes = o.MaxBytes * DefaultDecompressionRatio
+	}
+	return o
+}
+
+type JobProgress struct {
+	Line       int `json:"line"`
+	Stored     int `json:"stored"`
+	Failed     int `json:"failed"`
+	Duplicates int `json:"duplicates"`
+	Discarded  int `json:"discarded"`
+}
+
+type JobRejection struct {
+	Line  int    `json:"line"`
+	Row   string `json:"row"`
+	Error string `json:"error"`
+}
+
+// Job is an upload being imported, returned by POST /v1/imports and GET /v1/imports/{id}
+type Job struct {
+	ID         string                 `json:"id"`
+	Status     JobStatus              `json:"status"`
+	Version    string                 `json:"version,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Progress   JobProgress            `json:"progress"`
+	Statistics *geoservice.Statistics `json:"statistics,omitempty"`
+	// Rejections holds the first rejected rows, RejectedRows counts all of them
+	Rejections   []JobRejection `json:"rejections"`
+	RejectedRows int            `json:"rejected_rows"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// jobs runs a single import at a time and keeps the finished ones, oldest first
+type jobs struct {
+	sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	byID     map[string]*Job
+	finished []string
+	running  bool
+}
+
+func newJobs() *jobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobs{ctx: ctx, cancel: cancel, byID: map[string]*Job{}}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// snapshot copies a job so it can be encoded without holding the lock
+func (j *jobs) snapshot(id string) (job Job, ok bool) {
+	j.Lock()
+	defer j.Unlock()
+
+	stored, ok := j.byID[id]
+	if !ok {
+		return
+	}
+	job = *stored
+	job.Rejections = append([]JobRejection{}, stored.Rejections...)
+	return
+}
+
+// Close cancels the running import and waits for it to stop
+func (s *Server) Close() error {
+	s.jobs.cancel()
+	s.jobs.wg.Wait()
+	return nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Upload.Token)) == 1
+}
+
+// spool copies the uploaded CSV to a temporary file, so the request can complete while the import runs.
+// Multipart bodies are read from their "file" part, gzipped data is detected and decompressed up to maxDecompressed
+// bytes, zero means unlimited. Larger data fails with errBodyTooLarge.
+func spool(r *http.Request, maxDecompressed int64) (path string, err error) {
+	var body io.Reader = r.Body
+	if mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			var part *multipart.Part
+			if part, err = reader.NextPart(); err != nil {
+				if err == io.EOF {
+					err = errNoFile
+				}
+				return
+			}
+			if part.FormName() == "file" {
+				body = part
+				break
+			}
+		}
+	}
+
+	buffered := bufio.NewReader(body)
+	var data io.Reader = buffered
+	// limit is the size of the decompressed data, zero for raw data and unlimited decompression
+	var limit int64
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(buffered); err != nil {
+			return
+		}
+		defer gz.Close()
+		data = gz
+		if maxDecompressed > 0 {
+			limit = maxDecompressed
+			data = io.LimitReader(gz, limit+1)
+		}
+	}
+
+	file, err := os.CreateTemp("", "geoservice-upload-*.csv")
+	if err != nil {
+		return
+	}
+	path = file.Name()
+
+	var written int64
+	if written, err = io.Copy(file, data); err == nil && limit > 0 && written > limit {
+		err = errBodyTooLarge
+	}
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+	if err != nil {
+		os.Remove(path)
+		path = ""
+	}
+	return
+}
+
+func (s *Server) handleImports(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/imports" {
+		s.handleJob(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errUnauthorized)
+		return
+	}
+
+	s.jobs.Lock()
+	running := s.jobs.running
+	s.jobs.running = true
+	s.jobs.Unlock()
+	if running {
+		writeError(w, http.StatusConflict, errImportRunning)
+		return
+	}
+
+	if s.opts.Upload.MaxBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.Upload.MaxBytes)
+	}
+	path, err := spool(r, s.opts.Upload.MaxDecompressedBytes)
+	if err != nil {
+		s.jobs.Lock()
+		s.jobs.running = false
+		s.jobs.Unlock()
+
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr), errors.Is(err, errBodyTooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+		case errors.Is(err, errNoFile):
+			writeError(w, http.StatusBadRequest, err)
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid_body: %v", err))
+		}
+		return
+	}
+
+	job := &Job{ID: newJobID(), Status: JobRunning, CreatedAt: time.Now(), Rejections: []JobRejection{}}
+	s.jobs.Lock()
+	s.jobs.byID[job.ID] = job
+	s.jobs.Unlock()
+
+	s.jobs.wg.Add(1)
+	go s.runJob(job, path)
+
+	snapshot, _ := s.jobs.snapshot(job.ID)
+	w.Header().Set("Location", "/v1/imports/"+job.ID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+func (s *Server) runJob(job *Job, path string) {
+	defer s.jobs.wg.Done()
+	defer os.Remove(path)
+
+	opts := s.opts.Upload.Import
+	opts.OnReject = func(rejection geoservice.Rejection) {
+		s.jobs.Lock()
+		defer s.jobs.Unlock()
+
+		job.RejectedRows++
+		if len(job.Rejections) < s.opts.Upload.RejectSample {
+			job.Rejections = append(job.Rejections, JobRejection{Line: rejection.Line, Row: rejection.Row, Error: rejection.Err.Error()})
+		}
+	}
+	opts.OnProgress = func(progress geoservice.Progress) {
+		s.jobs.Lock()
+		defer s.jobs.Unlock()
+
+		// Writers report concurrently, an older snapshot may arrive last
+		if progress.Line >= job.Progress.Line {
+			job.Progress = JobProgress(progress)
+		}
+	}
+
+	stat, err := s.importFile(path, opts, job)
+
+	s.jobs.Lock()
+	defer s.jobs.Unlock()
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = JobSucceeded
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	job.Statistics = stat
+
+	s.jobs.running = false
+	s.jobs.finished = append(s.jobs.finished, job.ID)
+	for len(s.jobs.finished) > s.opts.Upload.KeepJobs {
+		delete(s.jobs.byID, s.jobs.finished[0])
+		s.jobs.finished = s.jobs.finished[1:]
+	}
+}
+
+func (s *Server) importFile(path string, opts geoservice.ImportOptions, job *Job) (stat *geoservice.Statistics, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if !s.opts.Upload.Versioned {
+		stat, err = s.gs.Import(s.jobs.ctx, file, opts)
+		return
+	}
+
+	dataset, err := s.gs.ImportVersion(s.jobs.ctx, file, geoservice.VersionOptions{Version: job.ID, Import: opts})
+	if dataset != nil {
+		stat = dataset.Stat
+		s.jobs.Lock()
+		job.Version = dataset.Version
+		s.jobs.Unlock()
+	}
+	return
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method_not_allowed"))
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errUnauthorized)
+		return
+	}
+
+	job, ok := s.jobs.snapshot(strings.TrimPrefix(r.URL.Path, "/v1/imports/"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job_not_found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/memory"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const uploadCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+,PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+`
+
+func waitJob(t *testing.T, s *Server, id string) (job Job) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r := httptest.NewRequest(http.MethodGet, "/v1/imports/"+id, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("ServeHTTP() job status = %d: %s", w.Code, w.Body)
+		}
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+			t.Fatalf("ServeHTTP() job error = %v", err)
+		}
+		if job.Status != JobRunning {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s didn't finish", id)
+	return
+}
+
+func TestServer_Upload(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	io.WriteString(gz, uploadCSV)
+	gz.Close()
+
+	// 64 KiB of zeros compress to less than MaxBytes but decompress to more than DefaultDecompressionRatio times it
+	var bomb bytes.Buffer
+	gz = gzip.NewWriter(&bomb)
+	gz.Write(make([]byte, 64<<10))
+	gz.Close()
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("file", "dump.csv.gz")
+	part.Write(gzipped.Bytes())
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		token       string
+		wantStatus  int
+	}{
+		{name: "Raw", body: []byte(uploadCSV), contentType: "text/csv", token: "secret", wantStatus: http.StatusAccepted},
+		{name: "Gzip", body: gzipped.Bytes(), contentType: "application/gzip", token: "secret", wantStatus: http.StatusAccepted},
+		{name: "Multipart", body: form.Bytes(), contentType: mw.FormDataContentType(), token: "secret", wantStatus: http.StatusAccepted},
+		{name: "Unauthorized", body: []byte(uploadCSV), contentType: "text/csv", token: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "TooLarge", body: bytes.Repeat([]byte("x"), 2048), contentType: "text/csv", token: "secret", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "DecompressedTooLarge", body: bomb.Bytes(), contentType: "application/gzip", token: "secret", wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(geoservice.NewGeoService(memory.New()), Options{Upload: UploadOptions{Token: "secret", MaxBytes: 1024, RejectSample: 1}})
+			defer s.Close()
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/imports", bytes.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				return
+			}
+
+			var accepted Job
+			if err := json.NewDecoder(w.Body).Decode(&accepted); err != nil || accepted.ID == "" {
+				t.Fatalf("ServeHTTP() job = %+v, error = %v", accepted, err)
+			}
+
+			job := waitJob(t, s, accepted.ID)
+			if job.Status != JobSucceeded || job.Statistics == nil || job.Statistics.StoredEntries != 2 || job.Progress.Stored != 2 {
+				t.Fatalf("job = %+v, statistics = %+v", job, job.Statistics)
+			}
+			if job.RejectedRows != 2 || len(job.Rejections) != 1 || job.Rejections[0].Line != 4 {
+				t.Errorf("job rejections = %d, %+v", job.RejectedRows, job.Rejections)
+			}
+			if _, err := s.gs.RetrieveLocation(net.ParseIP("160.103.7.140")); err != nil {
+				t.Errorf("RetrieveLocation() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestServer_UploadDisabled(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/imports", strings.NewReader(uploadCSV)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ServeHTTP() status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
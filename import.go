@@ -0,0 +1,645 @@
+package geoservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/ipclass"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDuplicate       = errors.New("duplicate_ip_address")
+	ErrExcludedAddress = errors.New("excluded_ip_address")
+)
+
+// DedupePolicy decides what Import does with rows whose IP address was already seen
+type DedupePolicy int
+
+const (
+	// DedupeKeepFirst stores the first row of an IP address and counts the rest as duplicates
+	DedupeKeepFirst DedupePolicy = iota
+	// DedupeDisabled hands every row to the Repository, which decides how to handle existing IP addresses
+	DedupeDisabled
+)
+
+func (d DedupePolicy) String() string {
+	switch d {
+	case DedupeKeepFirst:
+		return "keep_first"
+	case DedupeDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("DedupePolicy(%d)", int(d))
+	}
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	// Workers is the number of goroutines parsing rows, defaults to 1
+	Workers int
+	// Dedupe defaults to DedupeKeepFirst
+	Dedupe DedupePolicy
+	// Batch configures how parsed locations are written to the Repository.
+	// With Batch.Transactional the whole import is rolled back when a chunk fails and checkpoints are not saved.
+	Batch BatchOptions
+	// Checkpoints persists the import progress every CheckpointEvery rows, nil disables checkpointing
+	Checkpoints CheckpointStore
+	// CheckpointEvery defaults to DefaultCheckpointEvery
+	CheckpointEvery int
+	// Resume continues from the checkpoint loaded from Checkpoints.
+	// The source has to be the same data as the interrupted import, positioned at its beginning.
+	Resume bool
+	// OnReject is called in source order for every row discarded by validation or deduplication
+	OnReject func(Rejection)
+	// OnProgress is called with the rows completed so far after every written chunk, possibly by several writers at once
+	OnProgress func(Progress)
+	// Profile describes the accepted rows in Statistics.Profile, nil disables profiling
+	Profile *ProfileOptions
+	// FillCity reverse geocodes rows with an empty city, nil leaves them empty
+	FillCity *FillCityOptions
+	// Enrichers run in order on every valid row in the parse workers, after FillCity and the enrichers of WithGeohash
+	// and WithTimezone. Statistics.Enrichment reports their counts and timing by name.
+	Enrichers []EnrichStage
+	// Exclude discards rows whose IP address has any of these flags, e.g. ipclass.Bogon, with the reason
+	// "excluded_ip_address". Statistics.Classes counts the special-purpose addresses either way.
+	Exclude ipclass.Class
+}
+
+// Progress is a snapshot of a running Import
+type Progress struct {
+	// Line is the last line of the contiguous prefix of completed rows
+	Line       int
+	Stored     int
+	Failed     int
+	Duplicates int
+	Discarded  int
+}
+
+// Rejection is a row Import didn't store, Err is ErrDuplicate for duplicates and the parsing error otherwise
+type Rejection struct {
+	Line int
+	Row  string
+	Err  error
+}
+
+// importRow is a raw CSV row along with its position in the source
+type importRow struct {
+	rowProgress
+	data []byte
+}
+
+// importRecord is a parsed row, location is nil and err is set for rows failing validation
+type importRecord struct {
+	rowProgress
+	data     []byte
+	location *geolocation.GeoLocation
+	// filled is set when the city of location was filled by reverse geocoding
+	filled bool
+	// class is the classification of the IP address of valid rows, excluded ones included
+	class ipclass.Class
+	// content hashes the columns of location when deduplicating, to detect conflicting duplicates
+	content uint64
+	err     error
+}
+
+// rowPipeline holds the stages between parsing and deduplication, the parse workers and the replay of a checkpoint
+// run the same ones so they reject the same rows
+type rowPipeline struct {
+	exclude   ipclass.Class
+	filler    *cityFiller
+	enrichers *enrichPipeline
+}
+
+// row classifies location, discards it when excluded, fills its city and enriches it. err rejects the row, policy is
+// the one of the failing enricher.
+func (p rowPipeline) row(location *geolocation.GeoLocation, stats []EnrichmentStat) (class ipclass.Class, filled bool, policy EnrichPolicy, err error) {
+	class = ipclass.Classify(location.IPAddress)
+	if excluded := class & p.exclude; excluded != 0 {
+		err = fmt.Errorf("%w: %s", ErrExcludedAddress, excluded)
+		return
+	}
+	filled = p.filler.fill(location)
+	policy, err = p.enrichers.run(location, stats)
+	return
+}
+
+func (o ImportOptions) withDefaults() ImportOptions {
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+	o.Batch = o.Batch.withDefaults()
+	if o.Batch.Transactional {
+		o.Batch.Writers = 1
+		o.Checkpoints = nil
+	}
+	if o.CheckpointEvery <= 0 {
+		o.CheckpointEvery = DefaultCheckpointEvery
+	}
+	return o
+}
+
+func trimRow(data []byte) []byte {
+	return bytes.TrimRight(data, "\r\n")
+}
+
+// readRows reads r line by line starting after the given line and byte offset and sends rows to the rows channel.
+// Line 1 is the header row and is skipped.
+func readRows(ctx context.Context, r *bufio.Reader, line int, offset int64, rows chan<- importRow) (read int64, err error) {
+	for {
+		data, readErr := r.ReadBytes('\n')
+		if len(data) > 0 {
+			line++
+			offset += int64(len(data))
+			read += int64(len(data))
+			if line > 1 {
+				row := importRow{
+					rowProgress: rowProgress{seq: int64(line - 2), line: line, end: offset},
+					data:        trimRow(data),
+				}
+				select {
+				case rows <- row:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+			}
+			return
+		}
+	}
+}
+
+// Import streams CSV rows from source through parsing, deduplication and storage without holding the dataset in memory.
+// Every stage is connected by bounded channels so a slow Repository slows down reading instead of buffering rows.
+// Rows are deduplicated in source order, so DedupeKeepFirst keeps the first row of the file.
+// The returned Statistics covers both parsing and storage, err wraps ErrIncompleteStore if any location failed to store.
+func (g *GeoService) Import(ctx context.Context, source io.Reader, opts ImportOptions) (stat *Statistics, err error) {
+	defer g.written()
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	return g.importInto(ctx, dataset.Repository, source, opts)
+}
+
+// importInto runs the Import pipeline against the given repository
+func (g *GeoService) importInto(ctx context.Context, db geolocation.Repository, source io.Reader, opts ImportOptions) (stat *Statistics, err error) {
+	begin := time.Now()
+	opts = opts.withDefaults()
+
+	ctx, span := g.trace().Start(ctx, SpanImport,
+		Attribute{Key: "repository", Value: repositoryType(db)},
+		Attribute{Key: "workers", Value: opts.Workers},
+		Attribute{Key: "writers", Value: opts.Batch.Writers},
+		Attribute{Key: "chunk_size", Value: opts.Batch.ChunkSize},
+		Attribute{Key: "resume", Value: opts.Resume},
+	)
+	g.log().LogAttrs(ctx, slog.LevelInfo, "import started",
+		slog.String("repository", repositoryType(db)),
+		slog.Int("workers", opts.Workers),
+		slog.Int("writers", opts.Batch.Writers),
+		slog.Int("chunk_size", opts.Batch.ChunkSize),
+		slog.String("dedupe", opts.Dedupe.String()),
+		slog.Bool("transactional", opts.Batch.Transactional),
+	)
+	var rejected int
+	defer func() {
+		g.logImportFinished(ctx, stat, rejected, err)
+		if stat != nil {
+			span.SetAttributes(
+				Attribute{Key: "accepted", Value: stat.AcceptedEntries},
+				Attribute{Key: "discarded", Value: stat.DiscardedEntries},
+				Attribute{Key: "duplicates", Value: stat.Duplicates},
+				Attribute{Key: "stored", Value: stat.StoredEntries},
+				Attribute{Key: "failed", Value: stat.FailedEntries},
+			)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	storeMany := db.StoreMany
+	var tx geolocation.Transaction
+	if opts.Batch.Transactional {
+		transactor, ok := geolocation.As[geolocation.Transactor](db)
+		if !ok {
+			err = ErrTransactionsUnsupported
+			return
+		}
+		if tx, err = transactor.Begin(); err != nil {
+			return
+		}
+		storeMany = tx.StoreMany
+	}
+
+	var checkpoint *Checkpoint
+	if opts.Resume && opts.Checkpoints != nil {
+		if checkpoint, err = opts.Checkpoints.Load(); err != nil {
+			return
+		}
+	}
+
+	prepare := rowPipeline{
+		exclude:   opts.Exclude,
+		filler:    g.newCityFiller(opts.FillCity),
+		enrichers: newEnrichPipeline(g.enrichStages(opts.Enrichers)),
+	}
+
+	r := bufio.NewReader(source)
+	seen := map[string]uint64{}
+	var (
+		startLine   int
+		startOffset int64
+		verifyUntil int
+	)
+	if checkpoint != nil {
+		// Rows before the checkpoint go through the same stages again to tell which ones were deduplicated,
+		// their enrichment isn't counted
+		var accept func(*geolocation.GeoLocation) bool
+		if opts.Dedupe == DedupeKeepFirst {
+			replayStats := prepare.enrichers.workerStats()
+			accept = func(location *geolocation.GeoLocation) bool {
+				_, _, _, err := prepare.row(location, replayStats)
+				return err == nil
+			}
+		}
+		r, seen, err = restoreCheckpoint(source, checkpoint, accept)
+		if err != nil {
+			return
+		}
+		startLine, startOffset, verifyUntil = checkpoint.Line, checkpoint.Offset, checkpoint.UntilLine
+		g.log().InfoContext(ctx, "import resumed", slog.Int("line", startLine), slog.Int64("offset", startOffset))
+	}
+
+	tracker := newProgressTracker(checkpoint)
+
+	stat = &Statistics{}
+	heap := g.sampleHeap()
+	defer func() {
+		stat.Elapsed = time.Now().Sub(begin)
+		stat.PeakHeapBytes = heap.stop()
+		stat.finish()
+	}()
+
+	rows := make(chan importRow, opts.Workers*2)
+	records := make(chan importRecord, opts.Workers*2)
+	chunks := make(chan []importRecord, opts.Batch.Writers)
+
+	// stagesWg waits for the reader, the parse closer and the batcher which can outlive the writers on cancellation
+	var stagesWg sync.WaitGroup
+	stagesWg.Add(3)
+
+	var readErr error
+	go func() {
+		defer stagesWg.Done()
+		defer close(rows)
+
+		_, readSpan := g.trace().Start(ctx, SpanRead, Attribute{Key: "start_line", Value: startLine})
+		stat.BytesRead, readErr = readRows(ctx, r, startLine, startOffset, rows)
+		readSpan.SetAttributes(Attribute{Key: "bytes", Value: stat.BytesRead})
+		if readErr != nil {
+			readSpan.RecordError(readErr)
+		}
+		readSpan.End()
+	}()
+
+	enrichers := prepare.enrichers
+	var enrichOnce sync.Once
+	var enrichErr error
+	var parseWg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		parseWg.Add(1)
+		go func(worker int) {
+			defer parseWg.Done()
+
+			// A parse span covers every row handled by a worker
+			_, parseSpan := g.trace().Start(ctx, SpanParse, Attribute{Key: "worker", Value: worker})
+			var parsed, invalid int
+			enrichStats := enrichers.workerStats()
+			defer func() {
+				enrichers.merge(enrichStats)
+				parseSpan.SetAttributes(Attribute{Key: "rows", Value: parsed}, Attribute{Key: "invalid", Value: invalid})
+				parseSpan.End()
+			}()
+
+			for row := range rows {
+				parsed++
+				record := importRecord{rowProgress: row.rowProgress, data: row.data}
+				location, locErr := geolocation.NewGeoLocationFromBytes(row.data)
+				if locErr == nil && location != nil {
+					// The hashes cover the row as parsed, which is what restoreCheckpoint sees
+					record.hash = hashIP(location)
+					if opts.Dedupe == DedupeKeepFirst {
+						record.content = hashContent(location)
+					}
+					var policy EnrichPolicy
+					record.class, record.filled, policy, locErr = prepare.row(location, enrichStats)
+					if policy == EnrichAbort {
+						enrichOnce.Do(func() {
+							enrichErr = locErr
+							cancel()
+						})
+						return
+					}
+				}
+				if locErr == nil && location != nil {
+					record.location = location
+				} else {
+					record.err = locErr
+					invalid++
+				}
+				select {
+				case records <- record:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i)
+	}
+	go func() {
+		defer stagesWg.Done()
+		parseWg.Wait()
+		stat.ElapsedParsed = time.Now().Sub(begin)
+		g.meter().ObserveParse(stat.ElapsedParsed)
+		close(records)
+	}()
+
+	var checkpointErr error
+	untilLine := verifyUntil
+	saveCheckpoint := func() {
+		if opts.Checkpoints == nil || checkpointErr != nil {
+			return
+		}
+		if checkpointErr = opts.Checkpoints.Save(tracker.checkpoint(untilLine)); checkpointErr != nil {
+			g.log().ErrorContext(ctx, "checkpoint save failed", slog.Any("error", checkpointErr))
+			cancel()
+		}
+	}
+
+	// The batcher puts parsed records back in source order, deduplicates them and groups them into chunks.
+	// Before a chunk goes past the last saved UntilLine a new checkpoint is saved, so a crash can never leave
+	// stored rows the next run doesn't know about.
+	next := tracker.next
+	collector := newStatsCollector()
+	var profile *profiler
+	if opts.Profile != nil {
+		profile = newProfiler(*opts.Profile)
+	}
+	var conflicts int
+	go func() {
+		defer stagesWg.Done()
+		defer close(chunks)
+
+		_, dedupeSpan := g.trace().Start(ctx, SpanDedupe, Attribute{Key: "policy", Value: opts.Dedupe.String()})
+		var handled, duplicates, chunkCount int
+		defer func() {
+			dedupeSpan.SetAttributes(
+				Attribute{Key: "rows", Value: handled},
+				Attribute{Key: "duplicates", Value: duplicates},
+				Attribute{Key: "chunks", Value: chunkCount},
+			)
+			dedupeSpan.End()
+		}()
+
+		pending := map[int64]importRecord{}
+		chunk := make([]importRecord, 0, opts.Batch.ChunkSize)
+		flush := func() bool {
+			if last := chunk[len(chunk)-1].line; opts.Checkpoints != nil && last > untilLine {
+				untilLine = last + opts.CheckpointEvery
+				if saveCheckpoint(); checkpointErr != nil {
+					return false
+				}
+			}
+			select {
+			case chunks <- chunk:
+				chunkCount++
+			case <-ctx.Done():
+				return false
+			}
+			chunk = make([]importRecord, 0, opts.Batch.ChunkSize)
+			return true
+		}
+
+		reject := func(record importRecord, err error) {
+			reason := rejectReason(err)
+			g.meter().RowRejected(reason)
+			if !errors.Is(err, ErrDuplicate) {
+				collector.discard(reason)
+			}
+			if rejected < g.logOpts.RejectionSample {
+				g.log().LogAttrs(ctx, slog.LevelWarn, "row rejected",
+					slog.Int("line", record.line),
+					slog.String("reason", reason),
+					slog.String("row", truncateRow(string(record.data))),
+					slog.Any("error", err),
+				)
+			}
+			rejected++
+			if opts.OnReject != nil {
+				opts.OnReject(Rejection{Line: record.line, Row: string(record.data), Err: err})
+			}
+		}
+
+		handle := func(record importRecord) bool {
+			handled++
+			collector.classify(record.class)
+			if record.location == nil {
+				reject(record, record.err)
+				tracker.complete(record.rowProgress, rowDiscarded)
+				return true
+			}
+
+			if opts.Dedupe == DedupeKeepFirst {
+				key := record.location.IPAddress.String()
+				if content, ok := seen[key]; ok {
+					duplicates++
+					if content != record.content {
+						conflicts++
+					}
+					reject(record, ErrDuplicate)
+					tracker.complete(record.rowProgress, rowDuplicate)
+					return true
+				}
+				seen[key] = record.content
+			}
+
+			stat.AcceptedEntries++
+			if record.filled {
+				stat.FilledCities++
+			}
+			collector.accept(record.location)
+			if profile != nil {
+				profile.add(record.location)
+			}
+			g.meter().RowsAccepted(1)
+
+			// Rows the interrupted import may have stored already are only checked, not written twice
+			if record.line <= verifyUntil {
+				if _, retrieveErr := db.Retrieve(record.location.IPAddress); retrieveErr == nil {
+					tracker.complete(record.rowProgress, rowStored)
+					return true
+				}
+			}
+
+			chunk = append(chunk, record)
+			if len(chunk) == opts.Batch.ChunkSize {
+				return flush()
+			}
+			return true
+		}
+
+		for record := range records {
+			pending[record.seq] = record
+			for {
+				record, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !handle(record) {
+					return
+				}
+			}
+		}
+		if len(chunk) > 0 {
+			flush()
+		}
+		stat.ElapsedAppend = time.Now().Sub(begin)
+	}()
+
+	var mu sync.Mutex
+	var storeErr error
+	var writersWg sync.WaitGroup
+	for i := 0; i < opts.Batch.Writers; i++ {
+		writersWg.Add(1)
+		go func(writer int) {
+			defer writersWg.Done()
+			for chunk := range chunks {
+				locations := make([]*geolocation.GeoLocation, len(chunk))
+				for index, record := range chunk {
+					locations[index] = record.location
+				}
+
+				_, chunkSpan := g.trace().Start(ctx, SpanStoreChunk,
+					Attribute{Key: "writer", Value: writer},
+					Attribute{Key: "locations", Value: len(locations)},
+					Attribute{Key: "first_line", Value: chunk[0].line},
+				)
+				storeBegin := time.Now()
+				chunkErr := storeMany(locations)
+				failures := chunkFailures(locations, chunkErr)
+				elapsed := time.Now().Sub(storeBegin)
+				chunkSpan.SetAttributes(Attribute{Key: "failed", Value: len(failures)})
+				if chunkErr != nil {
+					chunkSpan.RecordError(chunkErr)
+					g.log().LogAttrs(ctx, slog.LevelError, "store chunk failed",
+						slog.Int("first_line", chunk[0].line),
+						slog.Int("locations", len(locations)),
+						slog.Int("failed", len(failures)),
+						slog.Any("error", chunkErr),
+					)
+				}
+				chunkSpan.End()
+
+				tracker.Lock()
+				for index, record := range chunk {
+					if _, failed := failures[index]; failed {
+						tracker.completeLocked(record.rowProgress, rowFailed)
+						continue
+					}
+					tracker.completeLocked(record.rowProgress, rowStored)
+				}
+				progress := tracker.progressLocked()
+				tracker.Unlock()
+				g.meter().ObserveStore(elapsed, len(chunk)-len(failures), len(failures))
+				if opts.OnProgress != nil {
+					opts.OnProgress(progress)
+				}
+
+				mu.Lock()
+				stat.ElapsedStore += elapsed
+				for _, failure := range failures {
+					if storeErr == nil {
+						storeErr = failure
+					}
+				}
+				mu.Unlock()
+
+				if len(failures) > 0 && tx != nil {
+					cancel()
+					return
+				}
+			}
+		}(i)
+	}
+	writersWg.Wait()
+	stagesWg.Wait()
+	collector.fill(stat)
+	enrichers.fill(stat)
+	if profile != nil {
+		stat.Profile = profile.profile()
+	}
+	stat.ConflictingDuplicates = conflicts
+
+	if checkpoint != nil {
+		stat.AcceptedEntries += checkpoint.Stored + checkpoint.Failed
+	}
+	totals := tracker.totals()
+	stat.Duplicates = totals.duplicates
+	stat.DiscardedEntries = totals.discarded
+	stat.StoredEntries = totals.stored
+	stat.FailedEntries = totals.failed
+
+	if tx != nil {
+		if storeErr == nil && ctx.Err() == nil && readErr == nil {
+			if storeErr = tx.Commit(); storeErr == nil {
+				return
+			}
+		} else {
+			tx.Rollback()
+		}
+		stat.FailedEntries += stat.StoredEntries
+		stat.StoredEntries = 0
+		if storeErr != nil {
+			err = fmt.Errorf("%w: %v", ErrRolledBack, storeErr)
+			return
+		}
+	}
+
+	// Whatever happened, the contiguous progress is saved so the import can be resumed
+	saveCheckpoint()
+
+	if checkpointErr != nil {
+		err = checkpointErr
+		return
+	}
+	if readErr != nil {
+		err = readErr
+		return
+	}
+	if enrichErr != nil {
+		err = enrichErr
+		return
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if stat.FailedEntries > 0 {
+		err = fmt.Errorf("%w: %d of %d locations failed, first: %v", ErrIncompleteStore, stat.FailedEntries, stat.AcceptedEntries, storeErr)
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"strings"
+	"testing"
+)
+
+const importCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+70.95.73.73,TL,Saudi Arabia,Gradymouth,-49.16675918861615,-86.05920084416894,2559997162
+,PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+125.159.20.54,LI,Guyana,Port Karson,-78.2274228596799,-163.26218895343357,1337885276
+`
+
+func TestGeoService_Import(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          ImportOptions
+		existing      string
+		wantStat      Statistics
+		wantErr       error
+		wantRetrieved []string
+	}{
+		{
+			name: "Streamed",
+			opts: ImportOptions{Workers: 3, Batch: BatchOptions{ChunkSize: 2, Writers: 2}},
+			wantStat: Statistics{
+				Duplicates:       1,
+				AcceptedEntries:  4,
+				DiscardedEntries: 1,
+				StoredEntries:    4,
+			},
+			wantRetrieved: []string{"200.106.141.15", "160.103.7.140", "70.95.73.73", "125.159.20.54"},
+		},
+		{
+			name:     "PartialFailure",
+			opts:     ImportOptions{Workers: 2, Batch: BatchOptions{ChunkSize: 3}},
+			existing: "70.95.73.73",
+			wantStat: Statistics{
+				Duplicates:       1,
+				AcceptedEntries:  4,
+				DiscardedEntries: 1,
+				StoredEntries:    3,
+				FailedEntries:    1,
+			},
+			wantErr:       ErrIncompleteStore,
+			wantRetrieved: []string{"200.106.141.15", "160.103.7.140", "125.159.20.54"},
+		},
+		{
+			name:     "TransactionalRollback",
+			opts:     ImportOptions{Workers: 2, Batch: BatchOptions{ChunkSize: 1, Transactional: true}},
+			existing: "125.159.20.54",
+			wantErr:  ErrRolledBack,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB()
+			if tt.existing != "" {
+				db.Store(&geolocation.GeoLocation{IPAddress: net.ParseIP(tt.existing)})
+			}
+
+			g := NewGeoService(db)
+			stat, err := g.Import(context.Background(), strings.NewReader(importCSV), tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Import() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil || tt.wantErr == ErrIncompleteStore {
+				if stat.Duplicates != tt.wantStat.Duplicates || stat.AcceptedEntries != tt.wantStat.AcceptedEntries ||
+					stat.DiscardedEntries != tt.wantStat.DiscardedEntries || stat.StoredEntries != tt.wantStat.StoredEntries ||
+					stat.FailedEntries != tt.wantStat.FailedEntries {
+					t.Errorf("Import() gotStat = %+v, want %+v", stat, tt.wantStat)
+				}
+			}
+			if tt.wantErr == ErrRolledBack && stat.StoredEntries != 0 {
+				t.Errorf("Import() stored %d entries in a rolled back import", stat.StoredEntries)
+			}
+
+			for _, ip := range tt.wantRetrieved {
+				location, retrieveErr := g.RetrieveLocation(net.ParseIP(ip))
+				if retrieveErr != nil || location.IPAddress.String() != ip {
+					t.Errorf("RetrieveLocation(%s) error = %v", ip, retrieveErr)
+				}
+			}
+		})
+	}
+}
+
+func TestGeoService_ImportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g := NewGeoService(newTestDB())
+	_, err := g.Import(ctx, strings.NewReader(importCSV), ImportOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Import() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGeoService_ImportRejections(t *testing.T) {
+	var rejections []Rejection
+	opts := ImportOptions{Workers: 3, OnReject: func(rejection Rejection) {
+		rejections = append(rejections, rejection)
+	}}
+
+	if _, err := NewGeoService(newTestDB()).Import(context.Background(), strings.NewReader(importCSV), opts); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if len(rejections) != 2 || rejections[0].Line != 5 || rejections[1].Line != 6 || !errors.Is(rejections[1].Err, ErrDuplicate) {
+		t.Errorf("Import() rejections = %+v", rejections)
+	}
+	if !strings.HasPrefix(rejections[0].Row, ",PY,") || rejections[0].Err == nil {
+		t.Errorf("Import() rejection = %+v, want the empty IP address row", rejections[0])
+	}
+}
@@ -0,0 +1,37 @@
+package geoservice
+
+import (
+	"github.com/aliforever/geo-service/geolocation"
+)
+
+// FindLocations returns a page of the locations of the active dataset whose field matches key regardless of case and
+// accents, ordered by IP address, along with the total number of matches. The repository must be a geolocation.Indexer.
+func (g *GeoService) FindLocations(field geolocation.IndexField, key string, page geolocation.Page) (locations []*geolocation.GeoLocation, total int, err error) {
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	indexer, ok := geolocation.As[geolocation.Indexer](dataset.Repository)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+
+	if locations, total, err = indexer.FindBy(field, key, page); err != nil {
+		return
+	}
+	for i := range locations {
+		locations[i] = versionLocation(dataset, locations[i])
+	}
+	return
+}
+
+// CountLocations returns the number of locations of the active dataset per key of field, the most common first
+func (g *GeoService) CountLocations(field geolocation.IndexField) (counts []geolocation.KeyCount, err error) {
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	indexer, ok := geolocation.As[geolocation.Indexer](dataset.Repository)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	return indexer.CountBy(field)
+}
@@ -0,0 +1,65 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"strings"
+	"testing"
+)
+
+func TestGeoService_FindLocations(t *testing.T) {
+	g := NewGeoService(memory.New())
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		field     geolocation.IndexField
+		key       string
+		page      geolocation.Page
+		want      []string
+		wantTotal int
+		wantErr   error
+	}{
+		{name: "CountryCode", field: geolocation.FieldCountryCode, key: "cz", want: []string{"160.103.7.140"}, wantTotal: 1},
+		{name: "Country", field: geolocation.FieldCountry, key: "SAUDI ARABIA", want: []string{"70.95.73.73"}, wantTotal: 1},
+		{name: "City", field: geolocation.FieldCity, key: "port karson", want: []string{"125.159.20.54"}, wantTotal: 1},
+		{name: "PastLastPage", field: geolocation.FieldCity, key: "port karson", page: geolocation.Page{Offset: 1}, wantTotal: 1},
+		{name: "Missing", field: geolocation.FieldCity, key: "Prague"},
+		{name: "UnknownField", field: "mystery_value", key: "1", wantErr: geolocation.ErrUnknownField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, total, err := g.FindLocations(tt.field, tt.key, tt.page)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindLocations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if total != tt.wantTotal || len(locations) != len(tt.want) {
+				t.Fatalf("FindLocations() = %v, total = %d, want %v, total %d", locations, total, tt.want, tt.wantTotal)
+			}
+			for i, location := range locations {
+				if location.IPAddress.String() != tt.want[i] {
+					t.Errorf("FindLocations()[%d] = %v, want %s", i, location.IPAddress, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, _, err := NewGeoService(newTestDB()).FindLocations(geolocation.FieldCity, "x", geolocation.Page{}); !errors.Is(err, geolocation.ErrUnsupported) {
+		t.Errorf("FindLocations() error = %v, want %v", err, geolocation.ErrUnsupported)
+	}
+}
+
+func TestGeoService_CountLocations(t *testing.T) {
+	g := NewGeoService(memory.New())
+	g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{})
+	g.StoreLocations([]*geolocation.GeoLocation{{IPAddress: []byte{10, 0, 0, 1}, CountryCode: "Cz"}})
+
+	counts, err := g.CountLocations(geolocation.FieldCountryCode)
+	if err != nil || len(counts) != 4 || counts[0] != (geolocation.KeyCount{Key: "cz", Value: "CZ", Count: 2}) {
+		t.Errorf("CountLocations() = %+v, error = %v", counts, err)
+	}
+}
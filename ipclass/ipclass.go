@@ -0,0 +1,183 @@
+package ipclass
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+var ErrUnknownClass = errors.New("unknown_ip_class")
+
+// Class is a set of flags describing a special-purpose IP address, zero for ordinary global unicast addresses
+type Class uint16
+
+const (
+	// Private covers the private-use networks of RFC 1918 and the IPv6 unique local addresses
+	Private Class = 1 << iota
+	// Loopback covers 127.0.0.0/8 and ::1
+	Loopback
+	// LinkLocal covers 169.254.0.0/16 and fe80::/10
+	LinkLocal
+	// Multicast covers 224.0.0.0/4 and ff00::/8
+	Multicast
+	// Documentation covers the TEST-NET networks, 2001:db8::/32 and 3fff::/20
+	Documentation
+	// Reserved covers the other special-purpose networks which aren't globally reachable, such as 0.0.0.0/8,
+	// 100.64.0.0/10, 198.18.0.0/15 and 240.0.0.0/4, and IPv6 addresses outside of the 2000::/3 global unicast space
+	Reserved
+	// Bogon is set along with every other flag, these addresses should never appear as the source of public traffic
+	Bogon
+)
+
+// names lists the flags in the order Names returns them
+var names = []struct {
+	class Class
+	name  string
+}{
+	{Private, "private"},
+	{Loopback, "loopback"},
+	{LinkLocal, "link_local"},
+	{Multicast, "multicast"},
+	{Documentation, "documentation"},
+	{Reserved, "reserved"},
+	{Bogon, "bogon"},
+}
+
+// Has reports whether c has any flag of flags
+func (c Class) Has(flags Class) bool {
+	return c&flags != 0
+}
+
+// Names returns the names of the flags of c, e.g. ["private", "bogon"]
+func (c Class) Names() (flags []string) {
+	for _, flag := range names {
+		if c.Has(flag.class) {
+			flags = append(flags, flag.name)
+		}
+	}
+	return
+}
+
+func (c Class) String() string {
+	return strings.Join(c.Names(), ",")
+}
+
+// MarshalJSON writes c as the list of its names
+func (c Class) MarshalJSON() ([]byte, error) {
+	flags := c.Names()
+	if flags == nil {
+		flags = []string{}
+	}
+	return json.Marshal(flags)
+}
+
+func (c *Class) UnmarshalJSON(data []byte) (err error) {
+	var flags []string
+	if err = json.Unmarshal(data, &flags); err != nil {
+		return
+	}
+	*c, err = Parse(strings.Join(flags, ","))
+	return
+}
+
+// Parse parses comma separated names like "private,multicast", an empty string is zero
+func Parse(value string) (c Class, err error) {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, flag := range names {
+			if flag.name == name {
+				c |= flag.class
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, ErrUnknownClass
+		}
+	}
+	return
+}
+
+// registry holds the networks of the IANA IPv4 and IPv6 special-purpose address registries which aren't globally
+// reachable, along with multicast. IPv4-mapped IPv6 addresses are classified as IPv4 addresses.
+var registry = []struct {
+	prefix netip.Prefix
+	class  Class
+}{
+	{netip.MustParsePrefix("0.0.0.0/8"), Reserved},
+	{netip.MustParsePrefix("10.0.0.0/8"), Private},
+	{netip.MustParsePrefix("100.64.0.0/10"), Reserved},
+	{netip.MustParsePrefix("127.0.0.0/8"), Loopback},
+	{netip.MustParsePrefix("169.254.0.0/16"), LinkLocal},
+	{netip.MustParsePrefix("172.16.0.0/12"), Private},
+	{netip.MustParsePrefix("192.0.0.0/24"), Reserved},
+	{netip.MustParsePrefix("192.0.2.0/24"), Documentation},
+	{netip.MustParsePrefix("192.88.99.0/24"), Reserved},
+	{netip.MustParsePrefix("192.168.0.0/16"), Private},
+	{netip.MustParsePrefix("198.18.0.0/15"), Reserved},
+	{netip.MustParsePrefix("198.51.100.0/24"), Documentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), Documentation},
+	{netip.MustParsePrefix("224.0.0.0/4"), Multicast},
+	{netip.MustParsePrefix("233.252.0.0/24"), Multicast | Documentation},
+	{netip.MustParsePrefix("240.0.0.0/4"), Reserved},
+
+	{netip.MustParsePrefix("::1/128"), Loopback},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), Reserved},
+	{netip.MustParsePrefix("100::/64"), Reserved},
+	{netip.MustParsePrefix("2001::/23"), Reserved},
+	{netip.MustParsePrefix("2001:db8::/32"), Documentation},
+	{netip.MustParsePrefix("3fff::/20"), Documentation},
+	{netip.MustParsePrefix("5f00::/16"), Reserved},
+	{netip.MustParsePrefix("fc00::/7"), Private},
+	{netip.MustParsePrefix("fe80::/10"), LinkLocal},
+	{netip.MustParsePrefix("ff00::/8"), Multicast},
+}
+
+// reachable holds the globally reachable networks the registries list inside the Reserved blocks 192.0.0.0/24 and
+// 2001::/23, such as anycast services, AMT, AS112-v6 and ORCHIDv2. Addresses inside them are ordinary addresses.
+var reachable = []netip.Prefix{
+	netip.MustParsePrefix("192.0.0.9/32"),
+	netip.MustParsePrefix("192.0.0.10/32"),
+	netip.MustParsePrefix("2001:1::1/128"),
+	netip.MustParsePrefix("2001:1::2/128"),
+	netip.MustParsePrefix("2001:3::/32"),
+	netip.MustParsePrefix("2001:4:112::/48"),
+	netip.MustParsePrefix("2001:20::/28"),
+	netip.MustParsePrefix("2001:30::/28"),
+}
+
+// globalUnicast is the IPv6 space allocated for global unicast addresses
+var globalUnicast = netip.MustParsePrefix("2000::/3")
+
+// Classify returns the class of ip, zero for ordinary global unicast addresses and malformed ones
+func Classify(ip net.IP) (c Class) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return
+	}
+	addr = addr.Unmap()
+
+	for _, network := range reachable {
+		if network.Contains(addr) {
+			return
+		}
+	}
+	for _, network := range registry {
+		if network.prefix.Contains(addr) {
+			c |= network.class
+		}
+	}
+	if c == 0 && addr.Is6() && !globalUnicast.Contains(addr) {
+		c = Reserved
+	}
+	if c != 0 {
+		c |= Bogon
+	}
+	return
+}
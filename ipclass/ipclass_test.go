@@ -0,0 +1,104 @@
+package ipclass
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want Class
+	}{
+		{ip: "8.8.8.8"},
+		{ip: "2606:4700:4700::1111"},
+		{ip: "10.1.2.3", want: Private | Bogon},
+		{ip: "172.31.255.255", want: Private | Bogon},
+		{ip: "172.32.0.1"},
+		{ip: "192.168.1.1", want: Private | Bogon},
+		{ip: "::ffff:192.168.1.1", want: Private | Bogon},
+		{ip: "127.0.0.1", want: Loopback | Bogon},
+		{ip: "169.254.169.254", want: LinkLocal | Bogon},
+		{ip: "192.0.2.15", want: Documentation | Bogon},
+		{ip: "198.51.100.1", want: Documentation | Bogon},
+		{ip: "203.0.113.200", want: Documentation | Bogon},
+		{ip: "224.0.0.251", want: Multicast | Bogon},
+		{ip: "233.252.0.1", want: Multicast | Documentation | Bogon},
+		{ip: "0.1.2.3", want: Reserved | Bogon},
+		{ip: "100.64.0.1", want: Reserved | Bogon},
+		{ip: "198.18.0.1", want: Reserved | Bogon},
+		{ip: "255.255.255.255", want: Reserved | Bogon},
+		{ip: "::1", want: Loopback | Bogon},
+		{ip: "::", want: Reserved | Bogon},
+		{ip: "2001:db8::1", want: Documentation | Bogon},
+		{ip: "3fff::1", want: Documentation | Bogon},
+		{ip: "fd12:3456::1", want: Private | Bogon},
+		{ip: "fe80::1", want: LinkLocal | Bogon},
+		{ip: "ff02::1", want: Multicast | Bogon},
+		{ip: "4000::1", want: Reserved | Bogon},
+		// globally reachable exceptions inside the Reserved 192.0.0.0/24 and 2001::/23
+		{ip: "192.0.0.8", want: Reserved | Bogon},
+		{ip: "192.0.0.9"},
+		{ip: "192.0.0.10"},
+		{ip: "192.0.0.11", want: Reserved | Bogon},
+		{ip: "2001::1", want: Reserved | Bogon},
+		{ip: "2001:1::1"},
+		{ip: "2001:1::2"},
+		{ip: "2001:1::3", want: Reserved | Bogon},
+		{ip: "2001:3::1"},
+		{ip: "2001:4:112::1"},
+		{ip: "2001:4:113::1", want: Reserved | Bogon},
+		{ip: "2001:20::1"},
+		{ip: "2001:2f:ffff::1"},
+		{ip: "2001:30::1"},
+		{ip: "2001:40::1", want: Reserved | Bogon},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := Classify(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := Classify(net.IP{1, 2}); got != 0 {
+		t.Errorf("Classify() = %s for a malformed address, want none", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Class
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "bogon", want: Bogon},
+		{value: "private, documentation", want: Private | Documentation},
+		{value: "private,public", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClass_JSON(t *testing.T) {
+	data, err := json.Marshal(Multicast | Documentation | Bogon)
+	if err != nil || string(data) != `["multicast","documentation","bogon"]` {
+		t.Fatalf("Marshal() = %s, error = %v", data, err)
+	}
+
+	var decoded Class
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded != Multicast|Documentation|Bogon {
+		t.Errorf("Unmarshal() = %s, error = %v", decoded, err)
+	}
+}
@@ -0,0 +1,74 @@
+package geoservice
+
+import (
+	"context"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/ipclass"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const classCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+8.8.8.8,US,United States,Mountain View,37.39,-122.08,1
+10.0.0.1,US,United States,Mountain View,37.39,-122.08,2
+192.0.2.1,US,United States,Mountain View,37.39,-122.08,3
+224.0.0.1,US,United States,Mountain View,37.39,-122.08,4
+10.0.0.1,US,United States,Mountain View,37.39,-122.08,2
+`
+
+func TestGeoService_ImportExclude(t *testing.T) {
+	tests := []struct {
+		name          string
+		exclude       ipclass.Class
+		wantStored    int
+		wantExcluded  int
+		wantRetrieved []string
+	}{
+		{name: "Disabled", wantStored: 4, wantRetrieved: []string{"8.8.8.8", "10.0.0.1", "192.0.2.1", "224.0.0.1"}},
+		{name: "Private", exclude: ipclass.Private, wantStored: 3, wantExcluded: 2, wantRetrieved: []string{"8.8.8.8", "192.0.2.1", "224.0.0.1"}},
+		{name: "Bogon", exclude: ipclass.Bogon, wantStored: 1, wantExcluded: 4, wantRetrieved: []string{"8.8.8.8"}},
+	}
+	wantClasses := map[string]int{"private": 2, "documentation": 1, "multicast": 1, "bogon": 4}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			stat, err := NewGeoService(db).Import(context.Background(), strings.NewReader(classCSV), ImportOptions{Workers: 2, Exclude: tt.exclude})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if stat.StoredEntries != tt.wantStored || stat.DiscardReasons["excluded_ip_address"] != tt.wantExcluded {
+				t.Errorf("Import() stored %d, reasons %v, want %d stored and %d excluded", stat.StoredEntries, stat.DiscardReasons, tt.wantStored, tt.wantExcluded)
+			}
+			if !reflect.DeepEqual(stat.Classes, wantClasses) {
+				t.Errorf("Import() Classes = %v, want %v", stat.Classes, wantClasses)
+			}
+			for _, ip := range tt.wantRetrieved {
+				if _, err := db.Retrieve(net.ParseIP(ip)); err != nil {
+					t.Errorf("Retrieve(%s) error = %v", ip, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGeoService_RetrieveLocationClass(t *testing.T) {
+	db := memory.New()
+	g := NewGeoService(db)
+	for _, ip := range []string{"8.8.8.8", "192.168.1.1"} {
+		db.Store(&geolocation.GeoLocation{IPAddress: net.ParseIP(ip), CountryCode: "US"})
+	}
+
+	location, err := g.RetrieveLocation(net.ParseIP("192.168.1.1"))
+	if err != nil || location.Class != ipclass.Private|ipclass.Bogon {
+		t.Fatalf("RetrieveLocation() = %+v, error = %v, want a private bogon", location, err)
+	}
+	if stored, _ := db.Retrieve(net.ParseIP("192.168.1.1")); stored.Class != 0 {
+		t.Errorf("RetrieveLocation() modified the stored location")
+	}
+	if location, err = g.RetrieveLocation(net.ParseIP("8.8.8.8")); err != nil || location.Class != 0 {
+		t.Errorf("RetrieveLocation() = %+v, error = %v, want no class", location, err)
+	}
+}
@@ -0,0 +1,114 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"log/slog"
+	"net"
+	"time"
+)
+
+const (
+	DefaultSlowLookup      = 100 * time.Millisecond
+	DefaultRejectionSample = 10
+	// maxLoggedRow truncates rejected rows in log records
+	maxLoggedRow = 256
+)
+
+// LogOptions configures WithLogger
+type LogOptions struct {
+	// SlowLookup is the duration above which lookups are logged, defaults to DefaultSlowLookup
+	SlowLookup time.Duration
+	// RejectionSample is the number of rejected rows logged per import, defaults to DefaultRejectionSample.
+	// The rest is only counted in the import's completion record.
+	RejectionSample int
+}
+
+func (o LogOptions) withDefaults() LogOptions {
+	if o.SlowLookup <= 0 {
+		o.SlowLookup = DefaultSlowLookup
+	}
+	if o.RejectionSample <= 0 {
+		o.RejectionSample = DefaultRejectionSample
+	}
+	return o
+}
+
+// WithLogger records import lifecycle events, sampled rejections, repository errors, slow lookups and dataset
+// activations to logger. Nothing is logged without it.
+func WithLogger(logger *slog.Logger, opts LogOptions) Option {
+	return func(g *GeoService) {
+		g.logger = logger
+		g.logOpts = opts.withDefaults()
+	}
+}
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// log returns the configured logger, discarding everything when there is none
+func (g *GeoService) log() *slog.Logger {
+	if g.logger == nil {
+		return discardLogger
+	}
+	return g.logger
+}
+
+func truncateRow(row string) string {
+	if len(row) > maxLoggedRow {
+		return row[:maxLoggedRow] + "..."
+	}
+	return row
+}
+
+// logLookup logs repository failures and lookups slower than LogOptions.SlowLookup
+func (g *GeoService) logLookup(ip net.IP, elapsed time.Duration, err error) {
+	if g.logger == nil {
+		return
+	}
+
+	if err != nil && !errors.Is(err, ErrInvalidIP) && !errors.Is(err, geolocation.ErrNotFound) {
+		g.logger.Error("lookup failed", slog.String("ip", ip.String()), slog.Any("error", err))
+	}
+	if elapsed >= g.logOpts.SlowLookup {
+		g.logger.Warn("slow lookup", slog.String("ip", ip.String()), slog.Duration("elapsed", elapsed))
+	}
+}
+
+// logImportFinished logs the outcome of an import at a level matching its error
+func (g *GeoService) logImportFinished(ctx context.Context, stat *Statistics, rejected int, err error) {
+	if g.logger == nil || stat == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.Duration("elapsed", stat.Elapsed),
+		slog.Int("accepted", stat.AcceptedEntries),
+		slog.Int("stored", stat.StoredEntries),
+		slog.Int("failed", stat.FailedEntries),
+		slog.Int("discarded", stat.DiscardedEntries),
+		slog.Int("duplicates", stat.Duplicates),
+		slog.Int("rejected", rejected),
+	}
+	switch {
+	case err == nil:
+		g.logger.LogAttrs(ctx, slog.LevelInfo, "import finished", attrs...)
+	case errors.Is(err, context.Canceled):
+		g.logger.LogAttrs(ctx, slog.LevelWarn, "import canceled", attrs...)
+	default:
+		g.logger.LogAttrs(ctx, slog.LevelError, "import failed", append(attrs, slog.Any("error", err))...)
+	}
+}
+
+// logIncompleteStore logs StoreLocationsResult and StoreLocationsBatch calls which failed to store some locations
+func (g *GeoService) logIncompleteStore(result *BatchResult, err error) {
+	if g.logger == nil || err == nil || len(result.Failed) == 0 {
+		return
+	}
+	g.logger.Warn("store incomplete",
+		slog.Int("stored", len(result.Stored)),
+		slog.Int("failed", len(result.Failed)),
+		slog.String("first_ip", result.Failed[0].Location.IPAddress.String()),
+		slog.Any("error", result.Failed[0].Err),
+	)
+}
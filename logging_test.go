@@ -0,0 +1,57 @@
+package geoservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logRecords decodes the records written by a JSON handler
+func logRecords(t *testing.T, b *bytes.Buffer) (records []map[string]interface{}) {
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log record %q error = %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+func TestGeoService_WithLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	g := NewGeoService(newTestDB(), WithLogger(logger, LogOptions{RejectionSample: 1, SlowLookup: time.Nanosecond}))
+
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	g.RetrieveLocation(net.ParseIP("200.106.141.15"))
+
+	var messages []string
+	byMessage := map[string]map[string]interface{}{}
+	for _, record := range logRecords(t, &b) {
+		message := record["msg"].(string)
+		messages = append(messages, message)
+		byMessage[message] = record
+	}
+
+	want := []string{"import started", "row rejected", "import finished", "slow lookup"}
+	if strings.Join(messages, ",") != strings.Join(want, ",") {
+		t.Fatalf("log messages = %v, want %v", messages, want)
+	}
+	if rejection := byMessage["row rejected"]; rejection["line"] != 5.0 || rejection["reason"] != "empty_ip_address" {
+		t.Errorf("row rejected record = %v", rejection)
+	}
+	if finished := byMessage["import finished"]; finished["stored"] != 4.0 || finished["rejected"] != 2.0 {
+		t.Errorf("import finished record = %v", finished)
+	}
+	if lookup := byMessage["slow lookup"]; lookup["ip"] != "200.106.141.15" {
+		t.Errorf("slow lookup record = %v", lookup)
+	}
+}
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"bytes"
+	"github.com/aliforever/geo-service/geolocation"
+	"sort"
+)
+
+// indexKey holds the locations sharing a normalized key sorted by IP address, so pages are slices of it.
+// value is the smallest original spelling so it doesn't depend on the order locations are indexed in.
+type indexKey struct {
+	value     string
+	locations []*geolocation.GeoLocation
+}
+
+// indexes are the secondary indexes of a Repository, keyed by field then normalized key
+type indexes map[geolocation.IndexField]map[string]*indexKey
+
+func lessIP(a, b *geolocation.GeoLocation) bool {
+	return bytes.Compare(a.IPAddress.To16(), b.IPAddress.To16()) < 0
+}
+
+// search returns the position of g in the locations of the key, or where it belongs
+func (k *indexKey) search(g *geolocation.GeoLocation) int {
+	return sort.Search(len(k.locations), func(i int) bool { return !lessIP(k.locations[i], g) })
+}
+
+// entry returns the key of field g belongs to, creating it and keeping the smallest spelling
+func (x indexes) entry(field geolocation.IndexField, g *geolocation.GeoLocation) *indexKey {
+	value := field.Value(g)
+	normalized := geolocation.NormalizeKey(value)
+	entry := x[field][normalized]
+	if entry == nil {
+		entry = &indexKey{value: value}
+		x[field][normalized] = entry
+	}
+	if value < entry.value {
+		entry.value = value
+	}
+	return entry
+}
+
+// build indexes every location of data, sorting every key once instead of inserting in order
+func (x indexes) build(data map[string]*geolocation.GeoLocation) {
+	for _, field := range geolocation.IndexFields {
+		x[field] = map[string]*indexKey{}
+		for _, g := range data {
+			entry := x.entry(field, g)
+			entry.locations = append(entry.locations, g)
+		}
+		for _, entry := range x[field] {
+			sort.Slice(entry.locations, func(i, j int) bool { return lessIP(entry.locations[i], entry.locations[j]) })
+		}
+	}
+}
+
+func (x indexes) add(g *geolocation.GeoLocation) {
+	for _, field := range geolocation.IndexFields {
+		entry := x.entry(field, g)
+		i := entry.search(g)
+		entry.locations = append(entry.locations, nil)
+		copy(entry.locations[i+1:], entry.locations[i:])
+		entry.locations[i] = g
+	}
+}
+
+func (x indexes) remove(g *geolocation.GeoLocation) {
+	for _, field := range geolocation.IndexFields {
+		value := field.Value(g)
+		normalized := geolocation.NormalizeKey(value)
+		entry := x[field][normalized]
+		if entry == nil {
+			continue
+		}
+		if i := entry.search(g); i < len(entry.locations) && entry.locations[i] == g {
+			entry.locations = append(entry.locations[:i], entry.locations[i+1:]...)
+		}
+		if len(entry.locations) == 0 {
+			delete(x[field], normalized)
+			continue
+		}
+		// the spelling goes away with its last location
+		if value == entry.value {
+			entry.value = ""
+			for _, location := range entry.locations {
+				if spelling := field.Value(location); entry.value == "" || spelling < entry.value {
+					entry.value = spelling
+				}
+			}
+		}
+	}
+}
+
+// indexLocked adds g to the indexes once they are built, r must be write locked
+func (r *Repository) indexLocked(g *geolocation.GeoLocation) {
+	if r.indexes != nil {
+		r.indexes.add(g)
+	}
+}
+
+// unindexLocked removes g from the indexes once they are built, r must be write locked
+func (r *Repository) unindexLocked(g *geolocation.GeoLocation) {
+	if r.indexes != nil {
+		r.indexes.remove(g)
+	}
+}
+
+// rlockIndexed read locks r with its indexes built. Indexes are built on the first query so imports
+// that are never queried don't pay for them, writes keep them up to date afterwards.
+func (r *Repository) rlockIndexed() {
+	r.RLock()
+	if r.indexes != nil {
+		return
+	}
+	r.RUnlock()
+
+	r.Lock()
+	if r.indexes == nil {
+		built := indexes{}
+		built.build(r.data)
+		r.indexes = built
+	}
+	r.Unlock()
+	r.RLock()
+}
+
+// FindBy returns a page of the locations whose field matches key once normalized, ordered by IP address
+func (r *Repository) FindBy(field geolocation.IndexField, key string, page geolocation.Page) (locations []*geolocation.GeoLocation, total int, err error) {
+	if !field.Valid() {
+		err = geolocation.ErrUnknownField
+		return
+	}
+
+	r.rlockIndexed()
+	defer r.RUnlock()
+	entry := r.indexes[field][geolocation.NormalizeKey(key)]
+	if entry == nil {
+		return
+	}
+	// the page is copied as writes shift the locations of the key
+	total = len(entry.locations)
+	begin, end := page.Apply(total)
+	locations = append([]*geolocation.GeoLocation{}, entry.locations[begin:end]...)
+	return
+}
+
+// CountBy returns the number of locations per normalized key of field, by descending count then key
+func (r *Repository) CountBy(field geolocation.IndexField) (counts []geolocation.KeyCount, err error) {
+	if !field.Valid() {
+		err = geolocation.ErrUnknownField
+		return
+	}
+
+	r.rlockIndexed()
+	for normalized, entry := range r.indexes[field] {
+		counts = append(counts, geolocation.KeyCount{Key: normalized, Value: entry.value, Count: len(entry.locations)})
+	}
+	r.RUnlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return
+}
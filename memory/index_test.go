@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"testing"
+)
+
+func location(ip, city string) *geolocation.GeoLocation {
+	return &geolocation.GeoLocation{IPAddress: net.ParseIP(ip), CountryCode: "CZ", Country: "Nicaragua", City: city}
+}
+
+func TestRepository_FindBy(t *testing.T) {
+	r := New()
+	r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.3", "Zürich"), location("10.0.0.1", "Zurich"), location("10.0.0.2", "Bern")})
+
+	tests := []struct {
+		name      string
+		field     geolocation.IndexField
+		key       string
+		page      geolocation.Page
+		want      []string
+		wantTotal int
+		wantErr   error
+	}{
+		{name: "Accents", field: geolocation.FieldCity, key: "ZURICH", want: []string{"10.0.0.1", "10.0.0.3"}, wantTotal: 2},
+		{name: "Page", field: geolocation.FieldCity, key: "zürich", page: geolocation.Page{Offset: 1, Limit: 1}, want: []string{"10.0.0.3"}, wantTotal: 2},
+		{name: "CountryCode", field: geolocation.FieldCountryCode, key: "cz", page: geolocation.Page{Limit: 1}, want: []string{"10.0.0.1"}, wantTotal: 3},
+		{name: "Missing", field: geolocation.FieldCity, key: "Basel"},
+		{name: "UnknownField", field: "asn", key: "1", wantErr: geolocation.ErrUnknownField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, total, err := r.FindBy(tt.field, tt.key, tt.page)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindBy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if total != tt.wantTotal || len(locations) != len(tt.want) {
+				t.Fatalf("FindBy() = %v, total = %d, want %v, total %d", locations, total, tt.want, tt.wantTotal)
+			}
+			for i, location := range locations {
+				if location.IPAddress.String() != tt.want[i] {
+					t.Errorf("FindBy()[%d] = %v, want %s", i, location.IPAddress, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_FindByAfterWrites(t *testing.T) {
+	r := New()
+	r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.3", "Zürich"), location("10.0.0.1", "Zurich"), location("10.0.0.2", "Bern")})
+	r.FindBy(geolocation.FieldCity, "zurich", geolocation.Page{})
+
+	// writes after the indexes were built keep every key ordered by IP address
+	r.Store(location("10.0.0.10", "Zurich"))
+	r.Store(location("10.0.0.0", "ZURICH"))
+	r.Upsert(location("10.0.0.2", "Zürich"))
+	r.Delete(net.ParseIP("10.0.0.1"))
+	r.Store(location("10.0.0.4", "Łódź"))
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "zurich", want: []string{"10.0.0.0", "10.0.0.2", "10.0.0.3", "10.0.0.10"}},
+		{key: "Bern"},
+		{key: "Lodz", want: []string{"10.0.0.4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			locations, total, err := r.FindBy(geolocation.FieldCity, tt.key, geolocation.Page{})
+			if err != nil || total != len(tt.want) || len(locations) != len(tt.want) {
+				t.Fatalf("FindBy() = %v, total = %d, error = %v, want %v", locations, total, err, tt.want)
+			}
+			for i, location := range locations {
+				if location.IPAddress.String() != tt.want[i] {
+					t.Errorf("FindBy()[%d] = %v, want %s", i, location.IPAddress, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_CountBy(t *testing.T) {
+	r := New()
+	r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.3", "Zürich"), location("10.0.0.1", "Zurich"), location("10.0.0.2", "Bern")})
+
+	counts, err := r.CountBy(geolocation.FieldCity)
+	want := []geolocation.KeyCount{{Key: "zurich", Value: "Zurich", Count: 2}, {Key: "bern", Value: "Bern", Count: 1}}
+	if err != nil || len(counts) != len(want) || counts[0] != want[0] || counts[1] != want[1] {
+		t.Errorf("CountBy() = %+v, error = %v, want %+v", counts, err, want)
+	}
+
+	// Writes after the indexes were built keep them up to date, removing the spelling reported for a key
+	r.Delete(net.ParseIP("10.0.0.1"))
+	r.Upsert(location("10.0.0.2", "Basel"))
+	tx, _ := r.Begin()
+	tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.4", "Bern")})
+	tx.Commit()
+
+	counts, err = r.CountBy(geolocation.FieldCity)
+	want = []geolocation.KeyCount{{Key: "basel", Value: "Basel", Count: 1}, {Key: "bern", Value: "Bern", Count: 1}, {Key: "zurich", Value: "Zürich", Count: 1}}
+	if err != nil || len(counts) != len(want) {
+		t.Fatalf("CountBy() = %+v, error = %v, want %+v", counts, err, want)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("CountBy()[%d] = %+v, want %+v", i, counts[i], want[i])
+		}
+	}
+}
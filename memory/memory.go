@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"sync"
+)
+
+var errExists = errors.New("data exists")
+
+// Repository keeps locations in a map keyed by IP address, it implements every optional repository capability
+// but geolocation.RegionSearcher, region searches are answered by the GeoService's R-tree instead of a scan.
+// Secondary indexes for geolocation.Indexer are built on the first query and maintained by writes afterwards.
+type Repository struct {
+	sync.RWMutex
+	data map[string]*geolocation.GeoLocation
+	// indexes are nil until the first FindBy or CountBy
+	indexes indexes
+}
+
+func New() *Repository {
+	return &Repository{data: map[string]*geolocation.GeoLocation{}}
+}
+
+func (r *Repository) Store(g *geolocation.GeoLocation) (err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	key := g.IPAddress.String()
+	if _, ok := r.data[key]; ok {
+		err = errExists
+		return
+	}
+
+	r.data[key] = g
+	r.indexLocked(g)
+	return
+}
+
+// StoreMany stores every location it can and reports the existing ones in a geolocation.BatchError
+func (r *Repository) StoreMany(gs []*geolocation.GeoLocation) (err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	batchErr := &geolocation.BatchError{Errors: map[int]error{}}
+	for index, g := range gs {
+		key := g.IPAddress.String()
+		if _, ok := r.data[key]; ok {
+			batchErr.Errors[index] = errExists
+			continue
+		}
+		r.data[key] = g
+		r.indexLocked(g)
+	}
+
+	if len(batchErr.Errors) > 0 {
+		err = batchErr
+	}
+	return
+}
+
+func (r *Repository) Retrieve(ip net.IP) (g *geolocation.GeoLocation, err error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if g = r.data[ip.String()]; g == nil {
+		err = geolocation.ErrNotFound
+	}
+	return
+}
+
+func (r *Repository) Upsert(g *geolocation.GeoLocation) error {
+	r.Lock()
+	defer r.Unlock()
+
+	key := g.IPAddress.String()
+	if old, ok := r.data[key]; ok {
+		r.unindexLocked(old)
+	}
+	r.data[key] = g
+	r.indexLocked(g)
+	return nil
+}
+
+func (r *Repository) Delete(ip net.IP) error {
+	r.Lock()
+	defer r.Unlock()
+
+	key := ip.String()
+	if old, ok := r.data[key]; ok {
+		r.unindexLocked(old)
+		delete(r.data, key)
+	}
+	return nil
+}
+
+// Each calls fn for every stored location in no particular order, fn must not write to the repository
+func (r *Repository) Each(fn func(*geolocation.GeoLocation) bool) error {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, g := range r.data {
+		if !fn(g) {
+			break
+		}
+	}
+	return nil
+}
+
+// Len returns the number of stored locations
+func (r *Repository) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+
+	return len(r.data)
+}
+
+// Begin starts a transaction buffering locations until Commit
+func (r *Repository) Begin() (geolocation.Transaction, error) {
+	return &transaction{db: r, data: map[string]*geolocation.GeoLocation{}}, nil
+}
+
+type transaction struct {
+	sync.Mutex
+	db *Repository
+	// data is nil once the transaction is done
+	data map[string]*geolocation.GeoLocation
+}
+
+func (t *transaction) StoreMany(gs []*geolocation.GeoLocation) (err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.data == nil {
+		err = geolocation.ErrTransactionDone
+		return
+	}
+
+	t.db.RLock()
+	defer t.db.RUnlock()
+
+	batchErr := &geolocation.BatchError{Errors: map[int]error{}}
+	for index, g := range gs {
+		key := g.IPAddress.String()
+		_, stored := t.db.data[key]
+		_, pending := t.data[key]
+		if stored || pending {
+			batchErr.Errors[index] = errExists
+			continue
+		}
+		t.data[key] = g
+	}
+
+	if len(batchErr.Errors) > 0 {
+		err = batchErr
+	}
+	return
+}
+
+func (t *transaction) Commit() (err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.data == nil {
+		err = geolocation.ErrTransactionDone
+		return
+	}
+
+	t.db.Lock()
+	defer t.db.Unlock()
+
+	for key := range t.data {
+		if _, ok := t.db.data[key]; ok {
+			err = errExists
+			return
+		}
+	}
+	for key, g := range t.data {
+		t.db.data[key] = g
+		t.db.indexLocked(g)
+	}
+	t.data = nil
+	return
+}
+
+func (t *transaction) Rollback() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.data == nil {
+		return geolocation.ErrTransactionDone
+	}
+	t.data = nil
+	return nil
+}
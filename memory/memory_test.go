@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"testing"
+)
+
+func TestRepository_Store(t *testing.T) {
+	r := New()
+	if err := r.Store(location("10.0.0.1", "A")); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if err := r.Store(location("10.0.0.1", "B")); err == nil {
+		t.Errorf("Store() of an existing IP address error = nil")
+	}
+
+	var batchErr *geolocation.BatchError
+	err := r.StoreMany([]*geolocation.GeoLocation{location("10.0.0.2", "B"), location("10.0.0.1", "A"), location("10.0.0.3", "C")})
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[1] == nil {
+		t.Fatalf("StoreMany() error = %v, want a BatchError for index 1", err)
+	}
+
+	if got, err := r.Retrieve(net.ParseIP("10.0.0.3")); err != nil || got.City != "C" {
+		t.Errorf("Retrieve() = %v, error = %v", got, err)
+	}
+	if _, err = r.Retrieve(net.ParseIP("10.0.0.4")); !errors.Is(err, geolocation.ErrNotFound) {
+		t.Errorf("Retrieve() error = %v, want %v", err, geolocation.ErrNotFound)
+	}
+
+	r.Upsert(location("10.0.0.1", "A2"))
+	r.Delete(net.ParseIP("10.0.0.2"))
+	if got, _ := r.Retrieve(net.ParseIP("10.0.0.1")); got == nil || got.City != "A2" {
+		t.Errorf("Retrieve() upserted location = %v", got)
+	}
+
+	var cities []string
+	r.Each(func(g *geolocation.GeoLocation) bool {
+		cities = append(cities, g.City)
+		return true
+	})
+	if r.Len() != 2 || len(cities) != 2 {
+		t.Errorf("Len() = %d, Each() listed %v, want 2 locations", r.Len(), cities)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	r := New()
+	r.Store(location("10.0.0.1", "A"))
+
+	tests := []struct {
+		name      string
+		end       func(geolocation.Transaction) error
+		wantCount int
+	}{
+		{name: "Commit", end: geolocation.Transaction.Commit, wantCount: 3},
+		{name: "Rollback", end: geolocation.Transaction.Rollback, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			r.Store(location("10.0.0.1", "A"))
+
+			tx, _ := r.Begin()
+			var batchErr *geolocation.BatchError
+			err := tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.2", "B"), location("10.0.0.1", "A"), location("10.0.0.3", "C")})
+			if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 {
+				t.Fatalf("StoreMany() error = %v, want a BatchError for the stored location", err)
+			}
+			if r.Len() != 1 {
+				t.Errorf("Len() = %d before the transaction ended, want 1", r.Len())
+			}
+
+			if err = tt.end(tx); err != nil {
+				t.Fatalf("%s() error = %v", tt.name, err)
+			}
+			if r.Len() != tt.wantCount {
+				t.Errorf("Len() = %d, want %d", r.Len(), tt.wantCount)
+			}
+
+			// A finished transaction rejects every further call instead of writing
+			if err = tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.4", "D")}); !errors.Is(err, geolocation.ErrTransactionDone) {
+				t.Errorf("StoreMany() after %s() error = %v, want %v", tt.name, err, geolocation.ErrTransactionDone)
+			}
+			if err = tx.Commit(); !errors.Is(err, geolocation.ErrTransactionDone) {
+				t.Errorf("Commit() after %s() error = %v, want %v", tt.name, err, geolocation.ErrTransactionDone)
+			}
+			if err = tx.Rollback(); !errors.Is(err, geolocation.ErrTransactionDone) {
+				t.Errorf("Rollback() after %s() error = %v, want %v", tt.name, err, geolocation.ErrTransactionDone)
+			}
+		})
+	}
+
+	// Commit fails when a pending IP address was stored since
+	tx, _ := r.Begin()
+	tx.StoreMany([]*geolocation.GeoLocation{location("10.0.0.2", "B")})
+	r.Store(location("10.0.0.2", "B"))
+	if err := tx.Commit(); err == nil {
+		t.Errorf("Commit() of a conflicting transaction error = nil")
+	}
+}
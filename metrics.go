@@ -0,0 +1,72 @@
+package geoservice
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"strconv"
+	"time"
+)
+
+// Metrics receives measurements of a GeoService, implementations have to be safe for concurrent use.
+// The metrics package implements it and exports the measurements in the Prometheus text format.
+type Metrics interface {
+	// RowsAccepted counts valid rows read by Import and ParseCSV
+	RowsAccepted(count int)
+	// RowRejected counts a discarded row, reason is a short label such as "duplicate" or "invalid_ip_address"
+	RowRejected(reason string)
+	// ObserveParse records the time spent parsing the rows of an Import or ParseCSV call
+	ObserveParse(elapsed time.Duration)
+	// ObserveStore records a single write to the Repository along with the number of stored and failed locations
+	ObserveStore(elapsed time.Duration, stored, failed int)
+	// ObserveLookup records a RetrieveLocation call and its error
+	ObserveLookup(elapsed time.Duration, err error)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) RowsAccepted(int) {}
+
+func (nopMetrics) RowRejected(string) {}
+
+func (nopMetrics) ObserveParse(time.Duration) {}
+
+func (nopMetrics) ObserveStore(time.Duration, int, int) {}
+
+func (nopMetrics) ObserveLookup(time.Duration, error) {}
+
+// WithMetrics reports measurements to m
+func WithMetrics(m Metrics) Option {
+	return func(g *GeoService) {
+		g.metrics = m
+	}
+}
+
+// rejectReason turns a rejection error into a label with a bounded set of values, errors that aren't known are "other"
+func rejectReason(err error) string {
+	var numErr *strconv.NumError
+	if reason, ok := geolocation.RowErrorReason(err); ok {
+		return reason
+	}
+	switch {
+	case err == nil:
+		return "invalid_data"
+	case errors.Is(err, ErrDuplicate):
+		return "duplicate"
+	case errors.Is(err, ErrEnrichment):
+		return "enrichment_failed"
+	case errors.Is(err, ErrExcludedAddress):
+		return "excluded_ip_address"
+	case errors.As(err, &numErr):
+		return "invalid_number"
+	default:
+		return "other"
+	}
+}
+
+// meter returns the configured Metrics, a no-op when there is none
+func (g *GeoService) meter() Metrics {
+	if g.metrics == nil {
+		return nopMetrics{}
+	}
+	return g.metrics
+}
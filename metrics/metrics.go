@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"errors"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/cache"
+	"github.com/aliforever/geo-service/geolocation"
+	"net/http"
+	"time"
+)
+
+// Lookup results of geoservice_lookups_total
+const (
+	LookupFound    = "found"
+	LookupNotFound = "not_found"
+	LookupInvalid  = "invalid"
+	LookupError    = "error"
+)
+
+// Collector implements geoservice.Metrics on top of a Registry:
+//
+//	collector := metrics.New()
+//	gs := geoservice.NewGeoService(db, geoservice.WithMetrics(collector))
+//	http.Handle("/metrics", collector.Handler())
+type Collector struct {
+	Registry *Registry
+
+	rowsAccepted    *Counter
+	rowsRejected    *Counter
+	parseDuration   *Histogram
+	storeDuration   *Histogram
+	locationsStored *Counter
+	locationsFailed *Counter
+	lookups         *Counter
+	lookupDuration  *Histogram
+}
+
+// New registers the GeoService metrics in a new Registry
+func New() *Collector {
+	return NewWithRegistry(NewRegistry())
+}
+
+// NewWithRegistry registers the GeoService metrics in registry, which can hold other metrics of the application
+func NewWithRegistry(registry *Registry) *Collector {
+	return &Collector{
+		Registry:        registry,
+		rowsAccepted:    registry.Counter("geoservice_rows_accepted_total", "Valid rows read by imports."),
+		rowsRejected:    registry.Counter("geoservice_rows_rejected_total", "Rows discarded by imports, by reason.", "reason"),
+		parseDuration:   registry.Histogram("geoservice_parse_duration_seconds", "Time spent parsing the rows of an import.", []float64{.01, .1, .5, 1, 5, 10, 30, 60, 300}),
+		storeDuration:   registry.Histogram("geoservice_store_duration_seconds", "Duration of repository writes.", nil),
+		locationsStored: registry.Counter("geoservice_locations_stored_total", "Locations written to the repository."),
+		locationsFailed: registry.Counter("geoservice_locations_failed_total", "Locations the repository failed to store."),
+		lookups:         registry.Counter("geoservice_lookups_total", "Lookups, by result.", "result"),
+		lookupDuration:  registry.Histogram("geoservice_lookup_duration_seconds", "Duration of lookups.", []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1}),
+	}
+}
+
+func (c *Collector) Handler() http.Handler {
+	return c.Registry.Handler()
+}
+
+func (c *Collector) RowsAccepted(count int) {
+	c.rowsAccepted.Add(float64(count))
+}
+
+func (c *Collector) RowRejected(reason string) {
+	c.rowsRejected.Inc(reason)
+}
+
+func (c *Collector) ObserveParse(elapsed time.Duration) {
+	c.parseDuration.Observe(elapsed.Seconds())
+}
+
+func (c *Collector) ObserveStore(elapsed time.Duration, stored, failed int) {
+	c.storeDuration.Observe(elapsed.Seconds())
+	c.locationsStored.Add(float64(stored))
+	c.locationsFailed.Add(float64(failed))
+}
+
+func (c *Collector) ObserveLookup(elapsed time.Duration, err error) {
+	c.lookupDuration.Observe(elapsed.Seconds())
+
+	result := LookupFound
+	switch {
+	case err == nil:
+	case errors.Is(err, geolocation.ErrNotFound):
+		result = LookupNotFound
+	case errors.Is(err, geoservice.ErrInvalidIP):
+		result = LookupInvalid
+	default:
+		result = LookupError
+	}
+	c.lookups.Inc(result)
+}
+
+// RegisterCache exports the statistics of a cache, read on every scrape
+func (c *Collector) RegisterCache(repository *cache.Repository) {
+	stat := func(fn func(cache.Stats) int64) func() float64 {
+		return func() float64 {
+			return float64(fn(repository.Stats()))
+		}
+	}
+	c.Registry.CounterFunc("geoservice_cache_hits_total", "Lookups answered by the cache.", stat(func(s cache.Stats) int64 { return s.Hits }))
+	c.Registry.CounterFunc("geoservice_cache_negative_hits_total", "Lookups answered by a cached not found result.", stat(func(s cache.Stats) int64 { return s.NegativeHits }))
+	c.Registry.CounterFunc("geoservice_cache_misses_total", "Lookups passed to the cached repository.", stat(func(s cache.Stats) int64 { return s.Misses }))
+	c.Registry.CounterFunc("geoservice_cache_evictions_total", "Entries evicted from the cache.", stat(func(s cache.Stats) int64 { return s.Evictions }))
+	c.Registry.GaugeFunc("geoservice_cache_entries", "Entries held by the cache.", stat(func(s cache.Stats) int64 { return int64(s.Entries) }))
+	c.Registry.GaugeFunc("geoservice_cache_bytes", "Estimated memory used by the cache.", stat(func(s cache.Stats) int64 { return s.Bytes }))
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/cache"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"testing"
+)
+
+const importCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+,PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+70.95.73.73,TL,Saudi Arabia,Gradymouth,x,-49.16675918861615,2559997162
+`
+
+func TestCollector(t *testing.T) {
+	collector := New()
+	db := cache.New(memory.New(), cache.Options{})
+	collector.RegisterCache(db)
+	gs := geoservice.NewGeoService(db, geoservice.WithMetrics(collector))
+
+	if _, err := gs.Import(context.Background(), strings.NewReader(importCSV), geoservice.ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	for _, ip := range []string{"200.106.141.15", "200.106.141.15", "1.1.1.1"} {
+		gs.RetrieveLocation(net.ParseIP(ip))
+	}
+	gs.RetrieveLocation(nil)
+
+	var b bytes.Buffer
+	if _, err := collector.Registry.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"# TYPE geoservice_rows_accepted_total counter\ngeoservice_rows_accepted_total 2\n",
+		`geoservice_rows_rejected_total{reason="duplicate"} 1`,
+		`geoservice_rows_rejected_total{reason="empty_ip_address"} 1`,
+		`geoservice_rows_rejected_total{reason="invalid_number"} 1`,
+		"geoservice_locations_stored_total 2\n",
+		"geoservice_store_duration_seconds_count 1\n",
+		`geoservice_lookups_total{result="found"} 2`,
+		`geoservice_lookups_total{result="not_found"} 1`,
+		`geoservice_lookups_total{result="invalid"} 1`,
+		`geoservice_lookup_duration_seconds_bucket{le="+Inf"} 4`,
+		"geoservice_cache_hits_total 1\n",
+		"geoservice_cache_misses_total 2\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteTo() missing %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestHistogram_Buckets(t *testing.T) {
+	registry := NewRegistry()
+	h := registry.Histogram("test_seconds", "Test.", []float64{1, 0.5}, "path")
+	for _, value := range []float64{0.1, 0.5, 0.7, 3} {
+		h.Observe(value, `a"b`)
+	}
+
+	var b bytes.Buffer
+	registry.WriteTo(&b)
+	want := `# HELP test_seconds Test.
+# TYPE test_seconds histogram
+test_seconds_bucket{path="a\"b",le="0.5"} 2
+test_seconds_bucket{path="a\"b",le="1"} 3
+test_seconds_bucket{path="a\"b",le="+Inf"} 4
+test_seconds_sum{path="a\"b"} 4.3
+test_seconds_count{path="a\"b"} 4
+`
+	if b.String() != want {
+		t.Errorf("WriteTo() = \n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds of duration histograms
+var DefaultBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5, 10, 30}
+
+// family is a named metric with every label combination it was recorded with
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and writes them in the Prometheus text exposition format
+type Registry struct {
+	sync.Mutex
+	names    map[string]struct{}
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]struct{}{}}
+}
+
+func (r *Registry) register(name string, f family) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.names[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = struct{}{}
+	r.families = append(r.families, f)
+}
+
+// WriteTo writes every metric in registration order
+func (r *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	r.Lock()
+	families := append([]family{}, r.families...)
+	r.Unlock()
+
+	counter := &countingWriter{w: w}
+	bw := bufio.NewWriter(counter)
+	for _, f := range families {
+		f.write(bw)
+	}
+	err = bw.Flush()
+	n = counter.n
+	return
+}
+
+// Handler serves the metrics to Prometheus scrapers
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = c.w.Write(p)
+	c.n += int64(n)
+	return
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders {name="value",...}, extra is appended as is
+func formatLabels(names, values []string, extra string) string {
+	if len(names) == 0 && extra == "" {
+		return ""
+	}
+	var parts []string
+	for index, name := range names {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[index])))
+	}
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.ReplaceAll(help, "\n", " "), name, kind)
+}
+
+// labelKey joins label values into a map key
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// vec keeps a series per label combination, sorted when written so the output is stable
+type vec[T any] struct {
+	sync.Mutex
+	labels []string
+	series map[string]*T
+	values map[string][]string
+	create func() *T
+}
+
+func newVec[T any](labels []string, create func() *T) vec[T] {
+	return vec[T]{labels: labels, series: map[string]*T{}, values: map[string][]string{}, create: create}
+}
+
+// with returns the series of the label values, creating it on first use. The vec has to be locked.
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values, want %d", len(values), len(v.labels)))
+	}
+	key := labelKey(values)
+	series, ok := v.series[key]
+	if !ok {
+		series = v.create()
+		v.series[key] = series
+		v.values[key] = append([]string{}, values...)
+	}
+	return series
+}
+
+func (v *vec[T]) sortedKeys() (keys []string) {
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// Counter is a monotonically increasing value per label combination
+type Counter struct {
+	name, help string
+	vec        vec[float64]
+}
+
+// Counter registers a counter partitioned by labels
+func (r *Registry) Counter(name, help string, labels ...string) (c *Counter) {
+	c = &Counter{name: name, help: help, vec: newVec(labels, func() *float64 { return new(float64) })}
+	r.register(name, c)
+	return
+}
+
+// Add increases the counter of the label values by value, negative values are ignored
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.vec.Lock()
+	defer c.vec.Unlock()
+
+	*c.vec.with(labelValues) += value
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Value returns the counter of the label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.vec.Lock()
+	defer c.vec.Unlock()
+
+	if value, ok := c.vec.series[labelKey(labelValues)]; ok {
+		return *value
+	}
+	return 0
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.vec.Lock()
+	defer c.vec.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range c.vec.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.vec.labels, c.vec.values[key], ""), formatValue(*c.vec.series[key]))
+	}
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Histogram counts observations in cumulative buckets per label combination
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	vec        vec[histogramSeries]
+}
+
+// Histogram registers a histogram with the given upper bounds, DefaultBuckets when nil
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) (h *Histogram) {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	h = &Histogram{name: name, help: help, buckets: buckets}
+	h.vec = newVec(labels, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(buckets))}
+	})
+	r.register(name, h)
+	return
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.vec.Lock()
+	defer h.vec.Unlock()
+
+	series := h.vec.with(labelValues)
+	if index := sort.SearchFloat64s(h.buckets, value); index < len(h.buckets) {
+		series.counts[index]++
+	}
+	series.sum += value
+	series.count++
+}
+
+// Count returns the number of observations of the label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.vec.Lock()
+	defer h.vec.Unlock()
+
+	if series, ok := h.vec.series[labelKey(labelValues)]; ok {
+		return series.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.vec.Lock()
+	defer h.vec.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range h.vec.sortedKeys() {
+		series, values := h.vec.series[key], h.vec.values[key]
+
+		var cumulative uint64
+		for index, bound := range h.buckets {
+			cumulative += series.counts[index]
+			le := fmt.Sprintf(`le="%s"`, formatValue(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.vec.labels, values, le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.vec.labels, values, `le="+Inf"`), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.vec.labels, values, ""), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.vec.labels, values, ""), series.count)
+	}
+}
+
+// funcFamily reads its value when written, for values kept elsewhere such as cache statistics
+type funcFamily struct {
+	name, help, kind string
+	fn               func() float64
+}
+
+func (f *funcFamily) write(w *bufio.Writer) {
+	writeHeader(w, f.name, f.help, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.fn()))
+}
+
+// CounterFunc registers a counter whose value is read from fn on every scrape
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcFamily{name: name, help: help, kind: "counter", fn: fn})
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcFamily{name: name, help: help, kind: "gauge", fn: fn})
+}
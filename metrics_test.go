@@ -0,0 +1,36 @@
+package geoservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"strconv"
+	"testing"
+)
+
+func TestRejectReason(t *testing.T) {
+	_, emptyIP := geolocation.NewGeoLocationFromBytes([]byte(",PY,Paraguay,,75.4,-144.6,0"))
+	_, numErr := strconv.ParseInt("12x", 10, 64)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "Nil", want: "invalid_data"},
+		{name: "Row", err: emptyIP, want: "empty_ip_address"},
+		{name: "Number", err: numErr, want: "invalid_number"},
+		{name: "Duplicate", err: ErrDuplicate, want: "duplicate"},
+		{name: "Enrichment", err: fmt.Errorf("%w: geohash: invalid coordinates", ErrEnrichment), want: "enrichment_failed"},
+		{name: "Excluded", err: fmt.Errorf("%w: private,bogon", ErrExcludedAddress), want: "excluded_ip_address"},
+		// Messages carrying values would make the label unbounded
+		{name: "Unknown", err: errors.New("line 55: unexpected value 1.2.3.4"), want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rejectReason(tt.err); got != tt.want {
+				t.Errorf("rejectReason(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package geoservice
+
+import (
+	"github.com/aliforever/geo-service/geolocation"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	DefaultProfileTopN           = 10
+	DefaultProfileQuantileSample = 10000
+)
+
+// ProfileOptions configures the dataset profile of an Import
+type ProfileOptions struct {
+	// TopN is the number of most frequent values kept per column and of cities kept per country, defaults to DefaultProfileTopN
+	TopN int
+	// QuantileSample is the number of mystery values sampled for quantiles, which are exact below it.
+	// Defaults to DefaultProfileQuantileSample.
+	QuantileSample int
+}
+
+func (o ProfileOptions) withDefaults() ProfileOptions {
+	if o.TopN <= 0 {
+		o.TopN = DefaultProfileTopN
+	}
+	if o.QuantileSample <= 0 {
+		o.QuantileSample = DefaultProfileQuantileSample
+	}
+	return o
+}
+
+// Profile describes the accepted rows of an Import
+type Profile struct {
+	Rows int `json:"rows" yaml:"rows"`
+	IPv4 int `json:"ipv4" yaml:"ipv4"`
+	IPv6 int `json:"ipv6" yaml:"ipv6"`
+	// Countries holds every country code, most frequent first
+	Countries []CountryProfile `json:"countries" yaml:"countries"`
+	// DistinctCities counts country and city pairs
+	DistinctCities int                 `json:"distinct_cities" yaml:"distinct_cities"`
+	MysteryValue   Distribution        `json:"mystery_value" yaml:"mystery_value"`
+	NullRates      map[string]float64  `json:"null_rates" yaml:"null_rates"`
+	TopValues      map[string][]Ranked `json:"top_values" yaml:"top_values"`
+}
+
+type CountryProfile struct {
+	CountryCode string      `json:"country_code" yaml:"country_code"`
+	Rows        int         `json:"rows" yaml:"rows"`
+	Cities      int         `json:"cities" yaml:"cities"`
+	TopCities   []Ranked    `json:"top_cities" yaml:"top_cities"`
+	Bounds      BoundingBox `json:"bounds" yaml:"bounds"`
+}
+
+type BoundingBox = geolocation.BoundingBox
+
+// Ranked is a value with its number of rows
+type Ranked struct {
+	Value string `json:"value" yaml:"value"`
+	Rows  int    `json:"rows" yaml:"rows"`
+}
+
+type Distribution struct {
+	Min  int64   `json:"min" yaml:"min"`
+	Max  int64   `json:"max" yaml:"max"`
+	Mean float64 `json:"mean" yaml:"mean"`
+	P50  int64   `json:"p50" yaml:"p50"`
+	P90  int64   `json:"p90" yaml:"p90"`
+	P99  int64   `json:"p99" yaml:"p99"`
+}
+
+// Profiled columns
+const (
+	columnCountryCode = "country_code"
+	columnCountry     = "country"
+	columnCity        = "city"
+)
+
+type countryState struct {
+	rows   int
+	cities map[string]int
+	bounds BoundingBox
+}
+
+// profiler builds a Profile from accepted locations, it isn't safe for concurrent use
+type profiler struct {
+	opts      ProfileOptions
+	rows      int
+	ipv4      int
+	countries map[string]*countryState
+	values    map[string]map[string]int
+	nulls     map[string]int
+	sum       float64
+	min, max  int64
+	sample    []int64
+	random    *rand.Rand
+}
+
+func newProfiler(opts ProfileOptions) *profiler {
+	return &profiler{
+		opts:      opts.withDefaults(),
+		countries: map[string]*countryState{},
+		values:    map[string]map[string]int{columnCountryCode: {}, columnCountry: {}, columnCity: {}},
+		nulls:     map[string]int{},
+		// A fixed seed keeps profiles of the same source identical
+		random: rand.New(rand.NewSource(1)),
+	}
+}
+
+func (p *profiler) add(location *geolocation.GeoLocation) {
+	p.rows++
+	if location.IPAddress.To4() != nil {
+		p.ipv4++
+	}
+
+	country, ok := p.countries[location.CountryCode]
+	if !ok {
+		country = &countryState{
+			cities: map[string]int{},
+			bounds: BoundingBox{
+				MinLatitude: location.Latitude, MaxLatitude: location.Latitude,
+				MinLongitude: location.Longitude, MaxLongitude: location.Longitude,
+			},
+		}
+		p.countries[location.CountryCode] = country
+	}
+	country.rows++
+	country.cities[location.City]++
+	country.bounds.MinLatitude = min(country.bounds.MinLatitude, location.Latitude)
+	country.bounds.MaxLatitude = max(country.bounds.MaxLatitude, location.Latitude)
+	country.bounds.MinLongitude = min(country.bounds.MinLongitude, location.Longitude)
+	country.bounds.MaxLongitude = max(country.bounds.MaxLongitude, location.Longitude)
+
+	for _, column := range [...]struct{ name, value string }{
+		{columnCountryCode, location.CountryCode},
+		{columnCountry, location.Country},
+		{columnCity, location.City},
+	} {
+		if column.value == "" {
+			p.nulls[column.name]++
+			continue
+		}
+		p.values[column.name][column.value]++
+	}
+
+	value := location.MysteryValue
+	if p.rows == 1 {
+		p.min, p.max = value, value
+	}
+	p.min, p.max = min(p.min, value), max(p.max, value)
+	p.sum += float64(value)
+
+	// Reservoir sampling keeps a uniform sample of every value seen
+	if len(p.sample) < p.opts.QuantileSample {
+		p.sample = append(p.sample, value)
+	} else if index := p.random.Intn(p.rows); index < len(p.sample) {
+		p.sample[index] = value
+	}
+}
+
+// topValues returns the n most frequent values, ties ordered by value
+func topValues(counts map[string]int, n int) (ranked []Ranked) {
+	ranked = make([]Ranked, 0, len(counts))
+	for value, rows := range counts {
+		ranked = append(ranked, Ranked{Value: value, Rows: rows})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Rows != ranked[j].Rows {
+			return ranked[i].Rows > ranked[j].Rows
+		}
+		return ranked[i].Value < ranked[j].Value
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return
+}
+
+// quantile picks the nearest rank of a sorted sample
+func quantile(sorted []int64, q float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(index, 0)]
+}
+
+func (p *profiler) profile() (profile *Profile) {
+	profile = &Profile{
+		Rows:      p.rows,
+		IPv4:      p.ipv4,
+		IPv6:      p.rows - p.ipv4,
+		Countries: []CountryProfile{},
+		NullRates: map[string]float64{},
+		TopValues: map[string][]Ranked{},
+	}
+
+	for code, country := range p.countries {
+		profile.DistinctCities += len(country.cities)
+		profile.Countries = append(profile.Countries, CountryProfile{
+			CountryCode: code,
+			Rows:        country.rows,
+			Cities:      len(country.cities),
+			TopCities:   topValues(country.cities, p.opts.TopN),
+			Bounds:      country.bounds,
+		})
+	}
+	sort.Slice(profile.Countries, func(i, j int) bool {
+		if profile.Countries[i].Rows != profile.Countries[j].Rows {
+			return profile.Countries[i].Rows > profile.Countries[j].Rows
+		}
+		return profile.Countries[i].CountryCode < profile.Countries[j].CountryCode
+	})
+
+	for column, counts := range p.values {
+		profile.TopValues[column] = topValues(counts, p.opts.TopN)
+		profile.NullRates[column] = 0
+		if p.rows > 0 {
+			profile.NullRates[column] = float64(p.nulls[column]) / float64(p.rows)
+		}
+	}
+
+	if p.rows > 0 {
+		sorted := append([]int64{}, p.sample...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		profile.MysteryValue = Distribution{
+			Min:  p.min,
+			Max:  p.max,
+			Mean: p.sum / float64(p.rows),
+			P50:  quantile(sorted, 0.5),
+			P90:  quantile(sorted, 0.9),
+			P99:  quantile(sorted, 0.99),
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package geoservice
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGeoService_ImportProfile(t *testing.T) {
+	source := importCSV +
+		"2001:db8::1,CZ,Nicaragua,,-60.5,-30.25,100\n" +
+		"10.0.0.1,CZ,Nicaragua,New Neva,-70,-40,200\n"
+
+	opts := ImportOptions{Workers: 2, Profile: &ProfileOptions{TopN: 1}}
+	stat, err := NewGeoService(newTestDB()).Import(context.Background(), strings.NewReader(source), opts)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	profile := stat.Profile
+	if profile == nil {
+		t.Fatalf("Import() profile = nil")
+	}
+	if profile.Rows != 6 || profile.IPv4 != 5 || profile.IPv6 != 1 || len(profile.Countries) != 4 || profile.DistinctCities != 5 {
+		t.Errorf("Import() profile rows = %d, ipv4 = %d, ipv6 = %d, countries = %d, cities = %d",
+			profile.Rows, profile.IPv4, profile.IPv6, len(profile.Countries), profile.DistinctCities)
+	}
+
+	czech := profile.Countries[0]
+	wantBounds := BoundingBox{MinLatitude: -70, MaxLatitude: -60.5, MinLongitude: -40, MaxLongitude: -30.25}
+	if czech.CountryCode != "CZ" || czech.Rows != 3 || czech.Cities != 2 || czech.Bounds != wantBounds {
+		t.Errorf("Import() first country = %+v", czech)
+	}
+	if len(czech.TopCities) != 1 || czech.TopCities[0] != (Ranked{Value: "New Neva", Rows: 2}) {
+		t.Errorf("Import() top cities = %+v", czech.TopCities)
+	}
+
+	if rate := profile.NullRates["city"]; rate != 1.0/6 {
+		t.Errorf("Import() city null rate = %f, want %f", rate, 1.0/6)
+	}
+	if top := profile.TopValues["country_code"]; len(top) != 1 || top[0] != (Ranked{Value: "CZ", Rows: 3}) {
+		t.Errorf("Import() top country codes = %+v", top)
+	}
+
+	want := Distribution{Min: 100, Max: 7823011346, P50: 1337885276, P90: 7823011346, P99: 7823011346}
+	got := profile.MysteryValue
+	got.Mean = 0
+	if got != want {
+		t.Errorf("Import() mystery value = %+v, want %+v", got, want)
+	}
+
+	data, err := json.Marshal(stat)
+	if err != nil || !strings.Contains(string(data), `"profile":{"rows":6`) {
+		t.Errorf("Marshal() = %s, error = %v", data, err)
+	}
+}
@@ -0,0 +1,225 @@
+package geoservice
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aliforever/geo-service/geocode"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"sort"
+)
+
+// spatialCache holds the spatial indexes of a dataset as of a number of writes through the GeoService,
+// each index is built on first use
+type spatialCache struct {
+	dataset   *Dataset
+	writes    uint64
+	locations []*geolocation.GeoLocation
+	index     *spatial.Index
+	tree      *spatial.RTree
+	gazetteer *geocode.Gazetteer
+}
+
+// written invalidates the spatial indexes after the active dataset was written to
+func (g *GeoService) written() {
+	g.writes.Add(1)
+}
+
+// spatialCache returns the cache of the active dataset, listing its locations again after the dataset was
+// written to through the GeoService or a new version was activated. spatialMu must be held.
+func (g *GeoService) spatialCache() (cache *spatialCache, err error) {
+	dataset, writes := g.dataset(), g.writes.Load()
+	if cache = g.spatial; cache != nil && cache.dataset == dataset && cache.writes == writes {
+		return
+	}
+
+	iterable, ok := geolocation.As[geolocation.Iterable](dataset.Repository)
+	if !ok {
+		err = geolocation.ErrUnsupported
+		return
+	}
+	if !dataset.acquire() {
+		// The dataset was dropped after another version was activated
+		return g.spatialCache()
+	}
+	defer dataset.release()
+	cache = &spatialCache{dataset: dataset, writes: writes}
+	err = iterable.Each(func(location *geolocation.GeoLocation) bool {
+		cache.locations = append(cache.locations, location)
+		return true
+	})
+	if err != nil {
+		return
+	}
+	g.spatial = cache
+	return
+}
+
+// spatialIndex returns the k-d tree of the active dataset
+func (g *GeoService) spatialIndex() (dataset *Dataset, index *spatial.Index, err error) {
+	g.spatialMu.Lock()
+	defer g.spatialMu.Unlock()
+
+	cache, err := g.spatialCache()
+	if err != nil {
+		return
+	}
+	if cache.index == nil {
+		cache.index = spatial.NewIndex(cache.locations)
+	}
+	return cache.dataset, cache.index, nil
+}
+
+// regionTree returns the R-tree of the active dataset
+func (g *GeoService) regionTree() (dataset *Dataset, tree *spatial.RTree, err error) {
+	g.spatialMu.Lock()
+	defer g.spatialMu.Unlock()
+
+	cache, err := g.spatialCache()
+	if err != nil {
+		return
+	}
+	if cache.tree == nil {
+		cache.tree = spatial.NewRTree(cache.locations)
+	}
+	return cache.dataset, cache.tree, nil
+}
+
+// versionLocation copies location to carry the version of the dataset it was found in
+func versionLocation(dataset *Dataset, location *geolocation.GeoLocation) *geolocation.GeoLocation {
+	if dataset.Version == "" {
+		return location
+	}
+	versioned := *location
+	versioned.DatasetVersion = dataset.Version
+	return &versioned
+}
+
+// NearestLocations returns the k locations of the active dataset closest to lat, lng by great-circle distance.
+// The repository must be geolocation.Iterable, the index is built on first use and rebuilt after writes.
+func (g *GeoService) NearestLocations(lat, lng float64, k int) (results []spatial.Result, err error) {
+	dataset, index, err := g.spatialIndex()
+	if err != nil {
+		return
+	}
+	if results, err = index.Nearest(lat, lng, k); err != nil {
+		return
+	}
+	for i := range results {
+		results[i].Location = versionLocation(dataset, results[i].Location)
+	}
+	return
+}
+
+// LocationsWithin returns every location of the active dataset at most radiusKm away from lat, lng, closest first
+func (g *GeoService) LocationsWithin(lat, lng, radiusKm float64) (results []spatial.Result, err error) {
+	dataset, index, err := g.spatialIndex()
+	if err != nil {
+		return
+	}
+	if results, err = index.Within(lat, lng, radiusKm); err != nil {
+		return
+	}
+	for i := range results {
+		results[i].Location = versionLocation(dataset, results[i].Location)
+	}
+	return
+}
+
+func validBox(box geolocation.BoundingBox) bool {
+	return spatial.ValidCoordinates(box.MinLatitude, box.MinLongitude) &&
+		spatial.ValidCoordinates(box.MaxLatitude, box.MaxLongitude) && box.MinLatitude <= box.MaxLatitude
+}
+
+// eachInBoxes calls fn once for every location of the active dataset inside any of boxes.
+// Boxes are pushed down to repositories implementing geolocation.RegionSearcher according to geolocation.As, others are
+// searched with an R-tree, as are decorators without Unwrap returning geolocation.ErrUnsupported.
+func (g *GeoService) eachInBoxes(boxes []geolocation.BoundingBox, fn func(*geolocation.GeoLocation)) (err error) {
+	dataset := g.acquireDataset()
+	defer dataset.release()
+	var search func(box geolocation.BoundingBox) ([]*geolocation.GeoLocation, error)
+	useTree := func() (err error) {
+		var tree *spatial.RTree
+		if dataset, tree, err = g.regionTree(); err != nil {
+			return
+		}
+		search = func(box geolocation.BoundingBox) ([]*geolocation.GeoLocation, error) {
+			return tree.Search(box), nil
+		}
+		return
+	}
+	searcher, pushDown := geolocation.As[geolocation.RegionSearcher](dataset.Repository)
+	if pushDown {
+		search = searcher.SearchBox
+	} else if err = useTree(); err != nil {
+		return
+	}
+
+	seen := map[string]struct{}{}
+	for _, box := range boxes {
+		for _, part := range box.Split() {
+			found, searchErr := search(part)
+			if pushDown && errors.Is(searchErr, geolocation.ErrUnsupported) {
+				pushDown = false
+				if err = useTree(); err != nil {
+					return
+				}
+				found, searchErr = search(part)
+			}
+			if searchErr != nil {
+				err = searchErr
+				return
+			}
+			for _, location := range found {
+				key := location.IPAddress.String()
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				fn(versionLocation(dataset, location))
+			}
+		}
+	}
+	return
+}
+
+// searchBoxes returns the locations inside any of boxes for which keep returns true, sorted by IP address
+func (g *GeoService) searchBoxes(boxes []geolocation.BoundingBox, keep func(lat, lng float64) bool) (locations []*geolocation.GeoLocation, err error) {
+	err = g.eachInBoxes(boxes, func(location *geolocation.GeoLocation) {
+		if keep(location.Latitude, location.Longitude) {
+			locations = append(locations, location)
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return bytes.Compare(locations[i].IPAddress.To16(), locations[j].IPAddress.To16()) < 0
+	})
+	return
+}
+
+// LocationsInBox returns the locations of the active dataset inside box sorted by IP address,
+// a box whose MinLongitude is greater than its MaxLongitude crosses the antimeridian
+func (g *GeoService) LocationsInBox(box geolocation.BoundingBox) (locations []*geolocation.GeoLocation, err error) {
+	if !validBox(box) {
+		err = spatial.ErrInvalidCoordinates
+		return
+	}
+	return g.searchBoxes([]geolocation.BoundingBox{box}, box.Contains)
+}
+
+// LocationsInPolygon returns the locations of the active dataset inside shape sorted by IP address.
+// The bounding box of every polygon is searched first, then its points are tested against the polygon.
+func (g *GeoService) LocationsInPolygon(shape spatial.MultiPolygon) (locations []*geolocation.GeoLocation, err error) {
+	var boxes []geolocation.BoundingBox
+	for _, polygon := range shape {
+		if len(polygon) == 0 {
+			err = spatial.ErrInvalidGeoJSON
+			return
+		}
+		boxes = append(boxes, polygon.Bounds())
+	}
+	return g.searchBoxes(boxes, shape.Contains)
+}
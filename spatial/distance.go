@@ -0,0 +1,53 @@
+package spatial
+
+import (
+	"errors"
+	"math"
+)
+
+// EarthRadiusKm is the mean radius of the Earth
+const EarthRadiusKm = 6371.0088
+
+var ErrInvalidCoordinates = errors.New("invalid_coordinates")
+
+// ValidCoordinates reports whether lat and lng are finite degrees within [-90, 90] and [-180, 180]
+func ValidCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Haversine returns the great-circle distance in kilometers between two points given in degrees
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLng := radians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// point is a position on the unit sphere, the straight (chord) distance between two points grows with their
+// great-circle distance so Euclidean searches over points give exact spherical results
+type point [3]float64
+
+func toPoint(lat, lng float64) point {
+	phi, lambda := radians(lat), radians(lng)
+	return point{math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)}
+}
+
+func squaredChord(a, b point) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// chordForKm returns the squared chord length matching a great-circle distance
+func chordForKm(km float64) float64 {
+	angle := km / EarthRadiusKm
+	if angle >= math.Pi {
+		return 4
+	}
+	chord := 2 * math.Sin(angle/2)
+	return chord * chord
+}
@@ -0,0 +1,83 @@
+package spatial
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"strings"
+)
+
+// MaxGeohashPrecision is the longest supported geohash, 12 characters are about 3.7cm by 1.9cm
+const MaxGeohashPrecision = 12
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var ErrInvalidGeohash = errors.New("invalid_geohash")
+
+// EncodeGeohash returns the geohash of lat, lng with precision characters, precision is clamped to [1, MaxGeohashPrecision]
+func EncodeGeohash(lat, lng float64, precision int) string {
+	precision = max(1, min(precision, MaxGeohashPrecision))
+
+	minLat, maxLat, minLng, maxLng := -90.0, 90.0, -180.0, 180.0
+	hash := make([]byte, precision)
+	even := true
+	for index := range hash {
+		var char byte
+		for bit := 4; bit >= 0; bit-- {
+			// bits alternate between longitude and latitude, starting with longitude
+			if even {
+				if middle := (minLng + maxLng) / 2; lng >= middle {
+					char |= 1 << bit
+					minLng = middle
+				} else {
+					maxLng = middle
+				}
+			} else {
+				if middle := (minLat + maxLat) / 2; lat >= middle {
+					char |= 1 << bit
+					minLat = middle
+				} else {
+					maxLat = middle
+				}
+			}
+			even = !even
+		}
+		hash[index] = geohashAlphabet[char]
+	}
+	return string(hash)
+}
+
+// DecodeGeohash returns the cell of a geohash, the empty geohash is the whole world
+func DecodeGeohash(hash string) (box geolocation.BoundingBox, err error) {
+	if len(hash) > MaxGeohashPrecision {
+		err = ErrInvalidGeohash
+		return
+	}
+
+	box = geolocation.BoundingBox{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180}
+	even := true
+	for _, r := range strings.ToLower(hash) {
+		char := strings.IndexRune(geohashAlphabet, r)
+		if char < 0 {
+			err = ErrInvalidGeohash
+			return
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := char&(1<<bit) != 0
+			if even {
+				if middle := (box.MinLongitude + box.MaxLongitude) / 2; set {
+					box.MinLongitude = middle
+				} else {
+					box.MaxLongitude = middle
+				}
+			} else {
+				if middle := (box.MinLatitude + box.MaxLatitude) / 2; set {
+					box.MinLatitude = middle
+				} else {
+					box.MaxLatitude = middle
+				}
+			}
+			even = !even
+		}
+	}
+	return
+}
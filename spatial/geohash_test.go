@@ -0,0 +1,45 @@
+package spatial
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{name: "Copenhagen", lat: 57.64911, lng: 10.40744, precision: 11, want: "u4pruydqqvj"},
+		{name: "Origin", lat: 0, lng: 0, precision: 5, want: "s0000"},
+		{name: "SouthWest", lat: -90, lng: -180, precision: 3, want: "000"},
+		{name: "NorthEast", lat: 90, lng: 180, precision: 3, want: "zzz"},
+		{name: "Clamped", lat: 57.64911, lng: 10.40744, precision: 20, want: "u4pruydqqvj8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodeGeohash(tt.lat, tt.lng, tt.precision); got != tt.want {
+				t.Errorf("EncodeGeohash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeGeohash(t *testing.T) {
+	box, err := DecodeGeohash("u4pruydqqvj")
+	if err != nil {
+		t.Fatalf("DecodeGeohash() error = %v", err)
+	}
+	if !box.Contains(57.64911, 10.40744) || box.MaxLatitude-box.MinLatitude > 1e-5 || math.Abs(box.MaxLongitude-box.MinLongitude) > 1e-5 {
+		t.Errorf("DecodeGeohash() = %+v, want a tiny cell around Copenhagen", box)
+	}
+
+	for _, hash := range []string{"u4a", "0123456789bcd"} {
+		if _, err = DecodeGeohash(hash); !errors.Is(err, ErrInvalidGeohash) {
+			t.Errorf("DecodeGeohash(%s) error = %v, want %v", hash, err, ErrInvalidGeohash)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package spatial
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"math"
+)
+
+var ErrInvalidGeoJSON = errors.New("invalid_geojson")
+
+// Polygon holds GeoJSON linear rings of [longitude, latitude] positions, the first ring is the exterior and
+// the others are holes. As in RFC 7946 polygons crossing the antimeridian have to be cut into a MultiPolygon.
+type Polygon [][][2]float64
+
+// MultiPolygon is a union of polygons
+type MultiPolygon []Polygon
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// ParseGeoJSON reads a Polygon or MultiPolygon geometry, or a Feature or FeatureCollection of them
+func ParseGeoJSON(data []byte) (shape MultiPolygon, err error) {
+	var object geoJSON
+	if err = json.Unmarshal(data, &object); err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidGeoJSON, err)
+		return
+	}
+	if shape, err = object.polygons(); err != nil {
+		return
+	}
+	if len(shape) == 0 {
+		err = fmt.Errorf("%w: no polygon", ErrInvalidGeoJSON)
+		return
+	}
+	if err = shape.validate(); err != nil {
+		shape = nil
+	}
+	return
+}
+
+func (o *geoJSON) polygons() (shape MultiPolygon, err error) {
+	switch o.Type {
+	case "Polygon":
+		var polygon Polygon
+		if err = json.Unmarshal(o.Coordinates, &polygon); err == nil {
+			shape = MultiPolygon{polygon}
+		}
+	case "MultiPolygon":
+		err = json.Unmarshal(o.Coordinates, &shape)
+	case "Feature":
+		if o.Geometry == nil {
+			return nil, fmt.Errorf("%w: feature without geometry", ErrInvalidGeoJSON)
+		}
+		return o.Geometry.polygons()
+	case "FeatureCollection":
+		for index := range o.Features {
+			var polygons MultiPolygon
+			if polygons, err = o.Features[index].polygons(); err != nil {
+				return
+			}
+			shape = append(shape, polygons...)
+		}
+	default:
+		err = fmt.Errorf("%w: unsupported type %q", ErrInvalidGeoJSON, o.Type)
+	}
+	if err != nil && !errors.Is(err, ErrInvalidGeoJSON) {
+		err = fmt.Errorf("%w: %v", ErrInvalidGeoJSON, err)
+	}
+	return
+}
+
+// validate checks that every ring is closed, has at least four positions and valid coordinates
+func (m MultiPolygon) validate() error {
+	for _, polygon := range m {
+		if len(polygon) == 0 {
+			return fmt.Errorf("%w: polygon without rings", ErrInvalidGeoJSON)
+		}
+		for _, ring := range polygon {
+			if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+				return fmt.Errorf("%w: rings need at least four positions and the same first and last position", ErrInvalidGeoJSON)
+			}
+			for _, position := range ring {
+				if !ValidCoordinates(position[1], position[0]) {
+					return fmt.Errorf("%w: invalid position %v", ErrInvalidGeoJSON, position)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Bounds returns the bounding box of the exterior ring
+func (p Polygon) Bounds() (box geolocation.BoundingBox) {
+	box = geolocation.BoundingBox{MinLatitude: math.Inf(1), MaxLatitude: math.Inf(-1), MinLongitude: math.Inf(1), MaxLongitude: math.Inf(-1)}
+	for _, position := range p[0] {
+		box = union(box, geolocation.BoundingBox{
+			MinLatitude: position[1], MaxLatitude: position[1],
+			MinLongitude: position[0], MaxLongitude: position[0],
+		})
+	}
+	return
+}
+
+// Contains reports whether the point is inside the exterior ring and outside every hole
+func (p Polygon) Contains(lat, lng float64) bool {
+	if !insideRing(p[0], lat, lng) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if insideRing(hole, lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether the point is inside any of the polygons
+func (m MultiPolygon) Contains(lat, lng float64) bool {
+	for _, polygon := range m {
+		if polygon.Contains(lat, lng) {
+			return true
+		}
+	}
+	return false
+}
+
+// insideRing casts a ray towards increasing longitudes and counts the edges it crosses
+func insideRing(ring [][2]float64, lat, lng float64) (inside bool) {
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > lat) != (b[1] > lat) && lng < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return
+}
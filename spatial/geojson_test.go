@@ -0,0 +1,65 @@
+package spatial
+
+import (
+	"errors"
+	"testing"
+)
+
+const squareWithHole = `{"type":"Polygon","coordinates":[
+	[[0,0],[10,0],[10,10],[0,10],[0,0]],
+	[[4,4],[6,4],[6,6],[4,6],[4,4]]
+]}`
+
+func TestParseGeoJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantPolygons int
+		wantErr      error
+	}{
+		{name: "Polygon", data: squareWithHole, wantPolygons: 1},
+		{name: "MultiPolygon", data: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]}`, wantPolygons: 2},
+		{name: "Feature", data: `{"type":"Feature","properties":{},"geometry":` + squareWithHole + `}`, wantPolygons: 1},
+		{name: "FeatureCollection", data: `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":` + squareWithHole + `}]}`, wantPolygons: 1},
+		{name: "Point", data: `{"type":"Point","coordinates":[1,2]}`, wantErr: ErrInvalidGeoJSON},
+		{name: "OpenRing", data: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, wantErr: ErrInvalidGeoJSON},
+		{name: "InvalidPosition", data: `{"type":"Polygon","coordinates":[[[0,0],[200,0],[1,1],[0,0]]]}`, wantErr: ErrInvalidGeoJSON},
+		{name: "Malformed", data: `{"type":`, wantErr: ErrInvalidGeoJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shape, err := ParseGeoJSON([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseGeoJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(shape) != tt.wantPolygons {
+				t.Errorf("ParseGeoJSON() = %d polygons, want %d", len(shape), tt.wantPolygons)
+			}
+		})
+	}
+}
+
+func TestMultiPolygon_Contains(t *testing.T) {
+	shape, err := ParseGeoJSON([]byte(squareWithHole))
+	if err != nil {
+		t.Fatalf("ParseGeoJSON() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     bool
+	}{
+		{name: "Inside", lat: 2, lng: 2, want: true},
+		{name: "Hole", lat: 5, lng: 5, want: false},
+		{name: "Outside", lat: 11, lng: 5, want: false},
+		{name: "BetweenHoleAndEdge", lat: 5, lng: 8, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shape.Contains(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package spatial
+
+import (
+	"container/heap"
+	"github.com/aliforever/geo-service/geolocation"
+	"sort"
+)
+
+// Result is a location found by a spatial query with its distance to the queried point
+type Result struct {
+	Location   *geolocation.GeoLocation `json:"location"`
+	DistanceKm float64                  `json:"distance_km"`
+}
+
+// Neighbor is a value found by a Tree query with its distance to the queried point
+type Neighbor[T any] struct {
+	Value      T
+	DistanceKm float64
+}
+
+type entry[T any] struct {
+	point    point
+	lat, lng float64
+	value    T
+}
+
+// Tree is an immutable k-d tree over the unit sphere positions of values.
+// The tree is implicit: the median of every range is its root and the axis cycles with the depth.
+type Tree[T any] struct {
+	entries []entry[T]
+}
+
+// NewTree builds a tree over values positioned by position, values with invalid coordinates are skipped
+func NewTree[T any](values []T, position func(T) (lat, lng float64)) *Tree[T] {
+	entries := make([]entry[T], 0, len(values))
+	for _, value := range values {
+		lat, lng := position(value)
+		if !ValidCoordinates(lat, lng) {
+			continue
+		}
+		entries = append(entries, entry[T]{point: toPoint(lat, lng), lat: lat, lng: lng, value: value})
+	}
+	build(entries, 0)
+	return &Tree[T]{entries: entries}
+}
+
+func build[T any](entries []entry[T], depth int) {
+	if len(entries) <= 1 {
+		return
+	}
+	axis := depth % 3
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].point[axis] < entries[j].point[axis]
+	})
+	median := len(entries) / 2
+	build(entries[:median], depth+1)
+	build(entries[median+1:], depth+1)
+}
+
+// Len returns the number of indexed values
+func (t *Tree[T]) Len() int {
+	return len(t.entries)
+}
+
+// candidates is a max-heap on the squared chord, holding the best matches found so far
+type candidates[T any] []candidate[T]
+
+type candidate[T any] struct {
+	entry  *entry[T]
+	chord2 float64
+}
+
+func (c candidates[T]) Len() int            { return len(c) }
+func (c candidates[T]) Less(i, j int) bool  { return c[i].chord2 > c[j].chord2 }
+func (c candidates[T]) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *candidates[T]) Push(x interface{}) { *c = append(*c, x.(candidate[T])) }
+func (c *candidates[T]) Pop() interface{} {
+	old := *c
+	last := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return last
+}
+
+// Nearest returns the k values closest to lat, lng ordered by distance
+func (t *Tree[T]) Nearest(lat, lng float64, k int) (neighbors []Neighbor[T], err error) {
+	if !ValidCoordinates(lat, lng) {
+		err = ErrInvalidCoordinates
+		return
+	}
+	if k <= 0 {
+		return
+	}
+
+	target := toPoint(lat, lng)
+	best := &candidates[T]{}
+	var search func(entries []entry[T], depth int)
+	search = func(entries []entry[T], depth int) {
+		if len(entries) == 0 {
+			return
+		}
+		median := len(entries) / 2
+		e := &entries[median]
+		if chord2 := squaredChord(e.point, target); best.Len() < k {
+			heap.Push(best, candidate[T]{entry: e, chord2: chord2})
+		} else if chord2 < (*best)[0].chord2 {
+			(*best)[0] = candidate[T]{entry: e, chord2: chord2}
+			heap.Fix(best, 0)
+		}
+
+		axis := depth % 3
+		diff := target[axis] - e.point[axis]
+		near, far := entries[:median], entries[median+1:]
+		if diff > 0 {
+			near, far = far, near
+		}
+		search(near, depth+1)
+		if best.Len() < k || diff*diff < (*best)[0].chord2 {
+			search(far, depth+1)
+		}
+	}
+	search(t.entries, 0)
+
+	found := make([]candidate[T], best.Len())
+	for index := len(found) - 1; index >= 0; index-- {
+		found[index] = heap.Pop(best).(candidate[T])
+	}
+	return neighborsOf(lat, lng, found), nil
+}
+
+// Within returns every value at most radiusKm away from lat, lng ordered by distance
+func (t *Tree[T]) Within(lat, lng, radiusKm float64) (neighbors []Neighbor[T], err error) {
+	if !ValidCoordinates(lat, lng) || radiusKm < 0 {
+		err = ErrInvalidCoordinates
+		return
+	}
+
+	target := toPoint(lat, lng)
+	limit := chordForKm(radiusKm)
+	var found []candidate[T]
+	var search func(entries []entry[T], depth int)
+	search = func(entries []entry[T], depth int) {
+		if len(entries) == 0 {
+			return
+		}
+		median := len(entries) / 2
+		e := &entries[median]
+		if chord2 := squaredChord(e.point, target); chord2 <= limit {
+			found = append(found, candidate[T]{entry: e, chord2: chord2})
+		}
+
+		axis := depth % 3
+		diff := target[axis] - e.point[axis]
+		if diff <= 0 || diff*diff <= limit {
+			search(entries[:median], depth+1)
+		}
+		if diff >= 0 || diff*diff <= limit {
+			search(entries[median+1:], depth+1)
+		}
+	}
+	search(t.entries, 0)
+
+	sort.Slice(found, func(a, b int) bool {
+		return found[a].chord2 < found[b].chord2
+	})
+	return neighborsOf(lat, lng, found), nil
+}
+
+func neighborsOf[T any](lat, lng float64, found []candidate[T]) (neighbors []Neighbor[T]) {
+	neighbors = make([]Neighbor[T], len(found))
+	for index, c := range found {
+		neighbors[index] = Neighbor[T]{Value: c.entry.value, DistanceKm: Haversine(lat, lng, c.entry.lat, c.entry.lng)}
+	}
+	return
+}
+
+// Index is a Tree of locations
+type Index struct {
+	tree *Tree[*geolocation.GeoLocation]
+}
+
+func locationPosition(location *geolocation.GeoLocation) (lat, lng float64) {
+	return location.Latitude, location.Longitude
+}
+
+// NewIndex builds an index over locations, locations with invalid coordinates are skipped
+func NewIndex(locations []*geolocation.GeoLocation) *Index {
+	return &Index{tree: NewTree(locations, locationPosition)}
+}
+
+// Build indexes every location of a repository
+func Build(db geolocation.Iterable) (index *Index, err error) {
+	var locations []*geolocation.GeoLocation
+	err = db.Each(func(location *geolocation.GeoLocation) bool {
+		locations = append(locations, location)
+		return true
+	})
+	if err != nil {
+		return
+	}
+	index = NewIndex(locations)
+	return
+}
+
+// Len returns the number of indexed locations
+func (i *Index) Len() int {
+	return i.tree.Len()
+}
+
+// Nearest returns the k locations closest to lat, lng ordered by distance
+func (i *Index) Nearest(lat, lng float64, k int) (results []Result, err error) {
+	neighbors, err := i.tree.Nearest(lat, lng, k)
+	return toResults(neighbors), err
+}
+
+// Within returns every location at most radiusKm away from lat, lng ordered by distance
+func (i *Index) Within(lat, lng, radiusKm float64) (results []Result, err error) {
+	neighbors, err := i.tree.Within(lat, lng, radiusKm)
+	return toResults(neighbors), err
+}
+
+func toResults(neighbors []Neighbor[*geolocation.GeoLocation]) (results []Result) {
+	if neighbors == nil {
+		return
+	}
+	results = make([]Result, len(neighbors))
+	for index, neighbor := range neighbors {
+		results[index] = Result{Location: neighbor.Value, DistanceKm: neighbor.DistanceKm}
+	}
+	return
+}
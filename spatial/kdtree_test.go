@@ -0,0 +1,114 @@
+package spatial
+
+import (
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"testing"
+)
+
+func randomLocations(n int) (locations []*geolocation.GeoLocation) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		locations = append(locations, &geolocation.GeoLocation{
+			IPAddress: net.IPv4(10, byte(i>>16), byte(i>>8), byte(i)),
+			Latitude:  random.Float64()*180 - 90,
+			Longitude: random.Float64()*360 - 180,
+		})
+	}
+	return
+}
+
+// bruteForce returns the distances of every location to lat, lng sorted ascending
+func bruteForce(locations []*geolocation.GeoLocation, lat, lng float64) (distances []float64) {
+	for _, location := range locations {
+		distances = append(distances, Haversine(lat, lng, location.Latitude, location.Longitude))
+	}
+	sort.Float64s(distances)
+	return
+}
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{name: "Same", lat1: 51.5, lng1: -0.12, lat2: 51.5, lng2: -0.12, want: 0},
+		{name: "LondonParis", lat1: 51.5074, lng1: -0.1278, lat2: 48.8566, lng2: 2.3522, want: 343.56},
+		{name: "Antimeridian", lat1: 0, lng1: 179.5, lat2: 0, lng2: -179.5, want: 111.19},
+		{name: "Antipodal", lat1: 90, lng1: 0, lat2: -90, lng2: 0, want: math.Pi * EarthRadiusKm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Haversine(tt.lat1, tt.lng1, tt.lat2, tt.lng2); math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("Haversine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_Nearest(t *testing.T) {
+	locations := randomLocations(2000)
+	index := NewIndex(locations)
+
+	for _, point := range [][2]float64{{0, 0}, {89.9, 10}, {-45, 179.9}, {12.5, -179.9}} {
+		want := bruteForce(locations, point[0], point[1])[:7]
+		results, err := index.Nearest(point[0], point[1], 7)
+		if err != nil || len(results) != len(want) {
+			t.Fatalf("Nearest(%v) = %d results, error = %v, want %d", point, len(results), err, len(want))
+		}
+		for i, result := range results {
+			if math.Abs(result.DistanceKm-want[i]) > 1e-6 {
+				t.Errorf("Nearest(%v)[%d] distance = %v, want %v", point, i, result.DistanceKm, want[i])
+			}
+		}
+	}
+
+	if results, _ := index.Nearest(0, 0, 5000); len(results) != len(locations) {
+		t.Errorf("Nearest() = %d results, want every %d locations", len(results), len(locations))
+	}
+	if _, err := index.Nearest(91, 0, 1); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("Nearest() error = %v, want %v", err, ErrInvalidCoordinates)
+	}
+}
+
+func TestIndex_Within(t *testing.T) {
+	locations := randomLocations(2000)
+	index := NewIndex(locations)
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		radiusKm float64
+	}{
+		{name: "Equator", lat: 0, lng: 0, radiusKm: 800},
+		{name: "Pole", lat: 90, lng: 0, radiusKm: 1500},
+		{name: "Antimeridian", lat: -20, lng: 180, radiusKm: 1000},
+		{name: "Everything", lat: 10, lng: 10, radiusKm: 30000},
+		{name: "Nothing", lat: 10, lng: 10, radiusKm: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want int
+			for _, distance := range bruteForce(locations, tt.lat, tt.lng) {
+				if distance <= tt.radiusKm {
+					want++
+				}
+			}
+
+			results, err := index.Within(tt.lat, tt.lng, tt.radiusKm)
+			if err != nil || len(results) != want {
+				t.Fatalf("Within() = %d results, error = %v, want %d", len(results), err, want)
+			}
+			for i := 1; i < len(results); i++ {
+				if results[i].DistanceKm < results[i-1].DistanceKm {
+					t.Fatalf("Within() results are not ordered by distance at %d", i)
+				}
+			}
+		})
+	}
+}
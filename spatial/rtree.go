@@ -0,0 +1,185 @@
+package spatial
+
+import (
+	"github.com/aliforever/geo-service/geolocation"
+	"math"
+	"sort"
+)
+
+// nodeCapacity is the maximum number of children or values of an R-tree node
+const nodeCapacity = 16
+
+type rnode[T any] struct {
+	bounds   geolocation.BoundingBox
+	children []*rnode[T]
+	// boxes holds the bounds of every value of a leaf
+	boxes  []geolocation.BoundingBox
+	values []T
+}
+
+// BoxTree is an immutable R-tree over the bounding boxes of values, bulk loaded with Sort-Tile-Recursive packing so
+// every node but the last of each level is full
+type BoxTree[T any] struct {
+	root *rnode[T]
+	size int
+}
+
+// RTree is a BoxTree over the latitude and longitude of locations
+type RTree struct {
+	tree *BoxTree[*geolocation.GeoLocation]
+}
+
+func pointBounds(location *geolocation.GeoLocation) geolocation.BoundingBox {
+	return geolocation.BoundingBox{
+		MinLatitude: location.Latitude, MaxLatitude: location.Latitude,
+		MinLongitude: location.Longitude, MaxLongitude: location.Longitude,
+	}
+}
+
+func union(a, b geolocation.BoundingBox) geolocation.BoundingBox {
+	return geolocation.BoundingBox{
+		MinLatitude:  math.Min(a.MinLatitude, b.MinLatitude),
+		MaxLatitude:  math.Max(a.MaxLatitude, b.MaxLatitude),
+		MinLongitude: math.Min(a.MinLongitude, b.MinLongitude),
+		MaxLongitude: math.Max(a.MaxLongitude, b.MaxLongitude),
+	}
+}
+
+// intersects reports whether two boxes not crossing the antimeridian overlap
+func intersects(a, b geolocation.BoundingBox) bool {
+	return a.MinLatitude <= b.MaxLatitude && b.MinLatitude <= a.MaxLatitude &&
+		a.MinLongitude <= b.MaxLongitude && b.MinLongitude <= a.MaxLongitude
+}
+
+// covers reports whether a contains the whole of b, neither crossing the antimeridian
+func covers(a, b geolocation.BoundingBox) bool {
+	return a.MinLatitude <= b.MinLatitude && b.MaxLatitude <= a.MaxLatitude &&
+		a.MinLongitude <= b.MinLongitude && b.MaxLongitude <= a.MaxLongitude
+}
+
+func center(box geolocation.BoundingBox) (lat, lng float64) {
+	return (box.MinLatitude + box.MaxLatitude) / 2, (box.MinLongitude + box.MaxLongitude) / 2
+}
+
+// pack groups nodes into parents of at most nodeCapacity children, tiling them by longitude then latitude
+func pack[T any](nodes []*rnode[T]) (parents []*rnode[T]) {
+	leaves := int(math.Ceil(float64(len(nodes)) / nodeCapacity))
+	slabSize := int(math.Ceil(math.Sqrt(float64(leaves)))) * nodeCapacity
+
+	sort.Slice(nodes, func(i, j int) bool {
+		_, a := center(nodes[i].bounds)
+		_, b := center(nodes[j].bounds)
+		return a < b
+	})
+	for begin := 0; begin < len(nodes); begin += slabSize {
+		slab := nodes[begin:min(begin+slabSize, len(nodes))]
+		sort.Slice(slab, func(i, j int) bool {
+			a, _ := center(slab[i].bounds)
+			b, _ := center(slab[j].bounds)
+			return a < b
+		})
+		for first := 0; first < len(slab); first += nodeCapacity {
+			children := slab[first:min(first+nodeCapacity, len(slab))]
+			parent := &rnode[T]{bounds: children[0].bounds, children: append([]*rnode[T](nil), children...)}
+			for _, child := range children[1:] {
+				parent.bounds = union(parent.bounds, child.bounds)
+			}
+			parents = append(parents, parent)
+		}
+	}
+	return
+}
+
+// NewBoxTree builds an R-tree over values bounded by bounds, which must not cross the antimeridian
+func NewBoxTree[T any](values []T, bounds func(T) geolocation.BoundingBox) *BoxTree[T] {
+	nodes := make([]*rnode[T], 0, len(values))
+	for _, value := range values {
+		box := bounds(value)
+		nodes = append(nodes, &rnode[T]{bounds: box, boxes: []geolocation.BoundingBox{box}, values: []T{value}})
+	}
+
+	tree := &BoxTree[T]{size: len(nodes)}
+	if len(nodes) == 0 {
+		return tree
+	}
+
+	// the first level turns single value nodes into leaves holding up to nodeCapacity values
+	leaves := pack(nodes)
+	for _, leaf := range leaves {
+		for _, child := range leaf.children {
+			leaf.boxes = append(leaf.boxes, child.boxes[0])
+			leaf.values = append(leaf.values, child.values[0])
+		}
+		leaf.children = nil
+	}
+	for nodes = leaves; len(nodes) > 1; {
+		nodes = pack(nodes)
+	}
+	tree.root = nodes[0]
+	return tree
+}
+
+// Len returns the number of indexed values
+func (t *BoxTree[T]) Len() int {
+	return t.size
+}
+
+// Search returns the values whose bounds intersect box, which may cross the antimeridian. A value intersecting both
+// sides of the antimeridian is returned twice.
+func (t *BoxTree[T]) Search(box geolocation.BoundingBox) (values []T) {
+	if t.root == nil {
+		return
+	}
+	for _, part := range box.Split() {
+		values = t.root.search(part, values)
+	}
+	return
+}
+
+func (n *rnode[T]) search(box geolocation.BoundingBox, found []T) []T {
+	if !intersects(n.bounds, box) {
+		return found
+	}
+	if covers(box, n.bounds) {
+		return n.collect(found)
+	}
+	for i, value := range n.values {
+		if intersects(n.boxes[i], box) {
+			found = append(found, value)
+		}
+	}
+	for _, child := range n.children {
+		found = child.search(box, found)
+	}
+	return found
+}
+
+// collect appends every value below n
+func (n *rnode[T]) collect(found []T) []T {
+	found = append(found, n.values...)
+	for _, child := range n.children {
+		found = child.collect(found)
+	}
+	return found
+}
+
+// NewRTree builds an R-tree over locations, locations with invalid coordinates are skipped
+func NewRTree(locations []*geolocation.GeoLocation) *RTree {
+	valid := make([]*geolocation.GeoLocation, 0, len(locations))
+	for _, location := range locations {
+		if ValidCoordinates(location.Latitude, location.Longitude) {
+			valid = append(valid, location)
+		}
+	}
+	return &RTree{tree: NewBoxTree(valid, pointBounds)}
+}
+
+// Len returns the number of indexed locations
+func (t *RTree) Len() int {
+	return t.tree.Len()
+}
+
+// Search returns the locations inside box, which may cross the antimeridian
+func (t *RTree) Search(box geolocation.BoundingBox) (locations []*geolocation.GeoLocation) {
+	return t.tree.Search(box)
+}
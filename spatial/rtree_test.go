@@ -0,0 +1,94 @@
+package spatial
+
+import (
+	"github.com/aliforever/geo-service/geolocation"
+	"math/rand"
+	"testing"
+)
+
+func TestRTree_Search(t *testing.T) {
+	locations := randomLocations(3000)
+	tree := NewRTree(locations)
+
+	tests := []struct {
+		name string
+		box  geolocation.BoundingBox
+	}{
+		{name: "Europe", box: geolocation.BoundingBox{MinLatitude: 35, MaxLatitude: 70, MinLongitude: -10, MaxLongitude: 40}},
+		{name: "Antimeridian", box: geolocation.BoundingBox{MinLatitude: -30, MaxLatitude: 30, MinLongitude: 160, MaxLongitude: -165}},
+		{name: "World", box: geolocation.BoundingBox{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180}},
+		{name: "Empty", box: geolocation.BoundingBox{MinLatitude: 10, MaxLatitude: 10, MinLongitude: 10, MaxLongitude: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[*geolocation.GeoLocation]bool{}
+			for _, location := range locations {
+				if tt.box.Contains(location.Latitude, location.Longitude) {
+					want[location] = true
+				}
+			}
+
+			got := tree.Search(tt.box)
+			if len(got) != len(want) {
+				t.Fatalf("Search() = %d locations, want %d", len(got), len(want))
+			}
+			for _, location := range got {
+				if !want[location] {
+					t.Errorf("Search() returned %v outside of the box", location.IPAddress)
+				}
+			}
+		})
+	}
+}
+
+func TestBoxTree_Search(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var boxes []geolocation.BoundingBox
+	for i := 0; i < 1000; i++ {
+		lat, lng := random.Float64()*170-85, random.Float64()*350-175
+		boxes = append(boxes, geolocation.BoundingBox{
+			MinLatitude: lat, MaxLatitude: lat + random.Float64()*5,
+			MinLongitude: lng, MaxLongitude: lng + random.Float64()*5,
+		})
+	}
+	indexes := make([]int, len(boxes))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	tree := NewBoxTree(indexes, func(i int) geolocation.BoundingBox { return boxes[i] })
+	if tree.Len() != len(boxes) {
+		t.Fatalf("Len() = %d, want %d", tree.Len(), len(boxes))
+	}
+
+	tests := []struct {
+		name string
+		box  geolocation.BoundingBox
+	}{
+		{name: "Point", box: geolocation.BoundingBox{MinLatitude: 48.2, MaxLatitude: 48.2, MinLongitude: 16.37, MaxLongitude: 16.37}},
+		{name: "Europe", box: geolocation.BoundingBox{MinLatitude: 35, MaxLatitude: 70, MinLongitude: -10, MaxLongitude: 40}},
+		{name: "Antimeridian", box: geolocation.BoundingBox{MinLatitude: -30, MaxLatitude: 30, MinLongitude: 175, MaxLongitude: -175}},
+		{name: "World", box: geolocation.BoundingBox{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[int]bool{}
+			for i, box := range boxes {
+				for _, part := range tt.box.Split() {
+					if intersects(box, part) {
+						want[i] = true
+					}
+				}
+			}
+
+			got := tree.Search(tt.box)
+			if len(got) != len(want) {
+				t.Fatalf("Search() = %d boxes, want %d", len(got), len(want))
+			}
+			for _, i := range got {
+				if !want[i] {
+					t.Errorf("Search() returned box %d not intersecting %+v", i, tt.box)
+				}
+			}
+		})
+	}
+}
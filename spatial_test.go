@@ -0,0 +1,136 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/cache"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"github.com/aliforever/geo-service/spatial"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestGeoService_NearestLocations(t *testing.T) {
+	g := NewGeoService(memory.New())
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	results, err := g.NearestLocations(-70, -40, 2)
+	if err != nil || len(results) != 2 || results[0].Location.IPAddress.String() != "160.103.7.140" {
+		t.Fatalf("NearestLocations() = %+v, error = %v", results, err)
+	}
+
+	// the index must be rebuilt after writes through the GeoService
+	closer := &geolocation.GeoLocation{IPAddress: net.ParseIP("10.0.0.1"), Latitude: -70, Longitude: -40}
+	if err = g.StoreLocations([]*geolocation.GeoLocation{closer}); err != nil {
+		t.Fatalf("StoreLocations() error = %v", err)
+	}
+	results, err = g.NearestLocations(-70, -40, 1)
+	if err != nil || len(results) != 1 || results[0].Location != closer || results[0].DistanceKm != 0 {
+		t.Errorf("NearestLocations() = %+v, error = %v, want the stored location", results, err)
+	}
+}
+
+func TestGeoService_LocationsWithin(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       geolocation.Repository
+		radiusKm float64
+		want     int
+		wantErr  error
+	}{
+		{name: "Radius", db: memory.New(), radiusKm: 2000, want: 2},
+		{name: "Unsupported", db: newTestDB(), radiusKm: 3000, wantErr: geolocation.ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGeoService(tt.db)
+			g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{})
+
+			results, err := g.LocationsWithin(-75, -60, tt.radiusKm)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LocationsWithin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(results) != tt.want {
+				t.Errorf("LocationsWithin() = %d results, want %d", len(results), tt.want)
+			}
+		})
+	}
+}
+
+// regionDB pushes box searches down like a SQL backend would, recording the boxes it was asked for
+type regionDB struct {
+	*testDB
+	boxes []geolocation.BoundingBox
+}
+
+func (r *regionDB) SearchBox(box geolocation.BoundingBox) (locations []*geolocation.GeoLocation, err error) {
+	r.boxes = append(r.boxes, box)
+	for _, location := range r.testDB.data {
+		if box.Contains(location.Latitude, location.Longitude) {
+			locations = append(locations, location)
+		}
+	}
+	return
+}
+
+func TestGeoService_LocationsInBox(t *testing.T) {
+	crossing := geolocation.BoundingBox{MinLatitude: -80, MaxLatitude: -40, MinLongitude: -40, MaxLongitude: -160}
+	tests := []struct {
+		name       string
+		db         geolocation.Repository
+		box        geolocation.BoundingBox
+		want       []string
+		wantPushed int
+		wantErr    error
+	}{
+		{name: "RTree", db: memory.New(), box: crossing, want: []string{"125.159.20.54", "160.103.7.140"}},
+		{name: "PushedDown", db: &regionDB{testDB: newTestDB()}, box: crossing, want: []string{"125.159.20.54", "160.103.7.140"}, wantPushed: 2},
+		{name: "Invalid", db: memory.New(), box: geolocation.BoundingBox{MinLatitude: 10, MaxLatitude: -10}, wantErr: spatial.ErrInvalidCoordinates},
+		{name: "Unsupported", db: newTestDB(), box: crossing, wantErr: geolocation.ErrUnsupported},
+		{name: "Cached", db: cache.New(memory.New(), cache.Options{}), box: crossing, want: []string{"125.159.20.54", "160.103.7.140"}},
+		{name: "CachedUnsupported", db: cache.New(newTestDB(), cache.Options{}), box: crossing, wantErr: geolocation.ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGeoService(tt.db)
+			g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{})
+
+			locations, err := g.LocationsInBox(tt.box)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LocationsInBox() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(locations) != len(tt.want) {
+				t.Fatalf("LocationsInBox() = %d locations, want %v", len(locations), tt.want)
+			}
+			for i, location := range locations {
+				if location.IPAddress.String() != tt.want[i] {
+					t.Errorf("LocationsInBox()[%d] = %v, want %s", i, location.IPAddress, tt.want[i])
+				}
+			}
+			if db, ok := tt.db.(*regionDB); ok && len(db.boxes) != tt.wantPushed {
+				t.Errorf("SearchBox() called with %v, want %d boxes", db.boxes, tt.wantPushed)
+			}
+		})
+	}
+}
+
+func TestGeoService_LocationsInPolygon(t *testing.T) {
+	g := NewGeoService(memory.New())
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// a triangle holding New Neva (-68.3, -37.6) but not Gradymouth (-49.2, -86.1) although both are in its bounding box
+	shape, err := spatial.ParseGeoJSON([]byte(`{"type":"Polygon","coordinates":[[[-90,-70],[-30,-70],[-30,-45],[-90,-70]]]}`))
+	if err != nil {
+		t.Fatalf("ParseGeoJSON() error = %v", err)
+	}
+	locations, err := g.LocationsInPolygon(shape)
+	if err != nil || len(locations) != 1 || locations[0].IPAddress.String() != "160.103.7.140" {
+		t.Errorf("LocationsInPolygon() = %v, error = %v", locations, err)
+	}
+}
@@ -1,12 +1,336 @@
 package geoservice
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/ipclass"
+	"hash/fnv"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
 
+// Statistics describes a ParseCSV or Import run.
+// It marshals to JSON and YAML (through its yaml tags and MarshalYAML) with durations written as strings like "1.5s".
+// After a resumed Import, the counts are cumulative while the detail fields (BytesRead, DiscardReasons,
+// ConflictingDuplicates, DistinctCountries, Coordinates) cover the resumed part only.
 type Statistics struct {
-	Elapsed          time.Duration
-	ElapsedParsed    time.Duration
-	ElapsedAppend    time.Duration
-	Duplicates       int
-	AcceptedEntries  int
-	DiscardedEntries int
+	Elapsed       time.Duration `json:"elapsed" yaml:"elapsed"`
+	ElapsedParsed time.Duration `json:"elapsed_parsed" yaml:"elapsed_parsed"`
+	ElapsedAppend time.Duration `json:"elapsed_append" yaml:"elapsed_append"`
+	ElapsedStore  time.Duration `json:"elapsed_store" yaml:"elapsed_store"`
+	BytesRead     int64         `json:"bytes_read" yaml:"bytes_read"`
+	// RowsPerSecond is the number of rows read, valid or not, per second of Elapsed
+	RowsPerSecond float64 `json:"rows_per_second" yaml:"rows_per_second"`
+	Duplicates    int     `json:"duplicates" yaml:"duplicates"`
+	// ConflictingDuplicates counts duplicates whose columns differ from the kept row of their IP address
+	ConflictingDuplicates int `json:"conflicting_duplicates" yaml:"conflicting_duplicates"`
+	AcceptedEntries       int `json:"accepted_entries" yaml:"accepted_entries"`
+	DiscardedEntries      int `json:"discarded_entries" yaml:"discarded_entries"`
+	// DiscardReasons counts discarded rows by reason, such as "empty_ip_address" or "invalid_number"
+	DiscardReasons    map[string]int `json:"discard_reasons,omitempty" yaml:"discard_reasons,omitempty"`
+	StoredEntries     int            `json:"stored_entries" yaml:"stored_entries"`
+	FailedEntries     int            `json:"failed_entries" yaml:"failed_entries"`
+	DistinctCountries int            `json:"distinct_countries" yaml:"distinct_countries"`
+	// FilledCities counts accepted rows whose empty city was filled by ImportOptions.FillCity
+	FilledCities int `json:"filled_cities,omitempty" yaml:"filled_cities,omitempty"`
+	// Enrichment describes the ImportOptions.Enrichers by name
+	Enrichment map[string]EnrichmentStat `json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
+	// Classes counts the valid rows with special-purpose IP addresses by ipclass flag, such as "private" or "bogon".
+	// Rows discarded by ImportOptions.Exclude and duplicates are counted too.
+	Classes map[string]int `json:"classes,omitempty" yaml:"classes,omitempty"`
+	// Coordinates summarizes the accepted locations
+	Coordinates CoordinateStats `json:"coordinates" yaml:"coordinates"`
+	// PeakHeapBytes is the largest heap size sampled during the run, zero unless WithHeapSampling is set
+	PeakHeapBytes uint64 `json:"peak_heap_bytes" yaml:"peak_heap_bytes"`
+	// Profile is set by imports with ImportOptions.Profile
+	Profile *Profile `json:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
+type CoordinateStats struct {
+	MinLatitude   float64 `json:"min_latitude" yaml:"min_latitude"`
+	MaxLatitude   float64 `json:"max_latitude" yaml:"max_latitude"`
+	MeanLatitude  float64 `json:"mean_latitude" yaml:"mean_latitude"`
+	MinLongitude  float64 `json:"min_longitude" yaml:"min_longitude"`
+	MaxLongitude  float64 `json:"max_longitude" yaml:"max_longitude"`
+	MeanLongitude float64 `json:"mean_longitude" yaml:"mean_longitude"`
+}
+
+// statisticsText is Statistics with durations as strings, shared by the JSON and YAML encodings
+type statisticsText struct {
+	Elapsed               string                    `json:"elapsed" yaml:"elapsed"`
+	ElapsedParsed         string                    `json:"elapsed_parsed" yaml:"elapsed_parsed"`
+	ElapsedAppend         string                    `json:"elapsed_append" yaml:"elapsed_append"`
+	ElapsedStore          string                    `json:"elapsed_store" yaml:"elapsed_store"`
+	BytesRead             int64                     `json:"bytes_read" yaml:"bytes_read"`
+	RowsPerSecond         float64                   `json:"rows_per_second" yaml:"rows_per_second"`
+	Duplicates            int                       `json:"duplicates" yaml:"duplicates"`
+	ConflictingDuplicates int                       `json:"conflicting_duplicates" yaml:"conflicting_duplicates"`
+	AcceptedEntries       int                       `json:"accepted_entries" yaml:"accepted_entries"`
+	DiscardedEntries      int                       `json:"discarded_entries" yaml:"discarded_entries"`
+	DiscardReasons        map[string]int            `json:"discard_reasons,omitempty" yaml:"discard_reasons,omitempty"`
+	StoredEntries         int                       `json:"stored_entries" yaml:"stored_entries"`
+	FailedEntries         int                       `json:"failed_entries" yaml:"failed_entries"`
+	DistinctCountries     int                       `json:"distinct_countries" yaml:"distinct_countries"`
+	FilledCities          int                       `json:"filled_cities,omitempty" yaml:"filled_cities,omitempty"`
+	Enrichment            map[string]EnrichmentStat `json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
+	Classes               map[string]int            `json:"classes,omitempty" yaml:"classes,omitempty"`
+	Coordinates           CoordinateStats           `json:"coordinates" yaml:"coordinates"`
+	PeakHeapBytes         uint64                    `json:"peak_heap_bytes" yaml:"peak_heap_bytes"`
+	Profile               *Profile                  `json:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
+func (s Statistics) text() statisticsText {
+	return statisticsText{
+		Elapsed:               s.Elapsed.String(),
+		ElapsedParsed:         s.ElapsedParsed.String(),
+		ElapsedAppend:         s.ElapsedAppend.String(),
+		ElapsedStore:          s.ElapsedStore.String(),
+		BytesRead:             s.BytesRead,
+		RowsPerSecond:         s.RowsPerSecond,
+		Duplicates:            s.Duplicates,
+		ConflictingDuplicates: s.ConflictingDuplicates,
+		AcceptedEntries:       s.AcceptedEntries,
+		DiscardedEntries:      s.DiscardedEntries,
+		DiscardReasons:        s.DiscardReasons,
+		StoredEntries:         s.StoredEntries,
+		FailedEntries:         s.FailedEntries,
+		DistinctCountries:     s.DistinctCountries,
+		FilledCities:          s.FilledCities,
+		Enrichment:            s.Enrichment,
+		Classes:               s.Classes,
+		Coordinates:           s.Coordinates,
+		PeakHeapBytes:         s.PeakHeapBytes,
+		Profile:               s.Profile,
+	}
+}
+
+func (s Statistics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.text())
+}
+
+func (s *Statistics) UnmarshalJSON(data []byte) (err error) {
+	var text statisticsText
+	if err = json.Unmarshal(data, &text); err != nil {
+		return
+	}
+
+	*s = Statistics{
+		BytesRead:             text.BytesRead,
+		RowsPerSecond:         text.RowsPerSecond,
+		Duplicates:            text.Duplicates,
+		ConflictingDuplicates: text.ConflictingDuplicates,
+		AcceptedEntries:       text.AcceptedEntries,
+		DiscardedEntries:      text.DiscardedEntries,
+		DiscardReasons:        text.DiscardReasons,
+		StoredEntries:         text.StoredEntries,
+		FailedEntries:         text.FailedEntries,
+		DistinctCountries:     text.DistinctCountries,
+		FilledCities:          text.FilledCities,
+		Enrichment:            text.Enrichment,
+		Classes:               text.Classes,
+		Coordinates:           text.Coordinates,
+		PeakHeapBytes:         text.PeakHeapBytes,
+		Profile:               text.Profile,
+	}
+	for _, field := range []struct {
+		value string
+		dst   *time.Duration
+	}{
+		{text.Elapsed, &s.Elapsed},
+		{text.ElapsedParsed, &s.ElapsedParsed},
+		{text.ElapsedAppend, &s.ElapsedAppend},
+		{text.ElapsedStore, &s.ElapsedStore},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if *field.dst, err = time.ParseDuration(field.value); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// MarshalYAML implements the Marshaler interface of gopkg.in/yaml.v2 and v3, which decode "1.5s" into time.Duration on their own
+func (s Statistics) MarshalYAML() (interface{}, error) {
+	return s.text(), nil
+}
+
+// String summarizes the statistics on a single line
+func (s Statistics) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d stored, %d failed, %d accepted, %d discarded", s.StoredEntries, s.FailedEntries, s.AcceptedEntries, s.DiscardedEntries)
+
+	if len(s.DiscardReasons) > 0 {
+		reasons := make([]string, 0, len(s.DiscardReasons))
+		for reason, count := range s.DiscardReasons {
+			reasons = append(reasons, reason+"="+strconv.Itoa(count))
+		}
+		sort.Strings(reasons)
+		fmt.Fprintf(&b, " (%s)", strings.Join(reasons, " "))
+	}
+
+	fmt.Fprintf(&b, ", %d duplicates (%d conflicting), %d countries in %s (%.0f rows/s, %d bytes read, %d bytes peak heap)",
+		s.Duplicates, s.ConflictingDuplicates, s.DistinctCountries, s.Elapsed, s.RowsPerSecond, s.BytesRead, s.PeakHeapBytes)
+	return b.String()
+}
+
+// statsCollector gathers the detail fields of Statistics, it isn't safe for concurrent use
+type statsCollector struct {
+	reasons   map[string]int
+	classes   map[string]int
+	countries map[string]struct{}
+	accepted  int
+	coords    CoordinateStats
+	sumLat    float64
+	sumLng    float64
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{reasons: map[string]int{}, countries: map[string]struct{}{}}
+}
+
+func (c *statsCollector) discard(reason string) {
+	c.reasons[reason]++
+}
+
+func (c *statsCollector) classify(class ipclass.Class) {
+	if class == 0 {
+		return
+	}
+	if c.classes == nil {
+		c.classes = map[string]int{}
+	}
+	for _, name := range class.Names() {
+		c.classes[name]++
+	}
+}
+
+func (c *statsCollector) accept(location *geolocation.GeoLocation) {
+	if c.accepted == 0 {
+		c.coords.MinLatitude, c.coords.MaxLatitude = location.Latitude, location.Latitude
+		c.coords.MinLongitude, c.coords.MaxLongitude = location.Longitude, location.Longitude
+	}
+	c.accepted++
+
+	c.coords.MinLatitude = min(c.coords.MinLatitude, location.Latitude)
+	c.coords.MaxLatitude = max(c.coords.MaxLatitude, location.Latitude)
+	c.coords.MinLongitude = min(c.coords.MinLongitude, location.Longitude)
+	c.coords.MaxLongitude = max(c.coords.MaxLongitude, location.Longitude)
+	c.sumLat += location.Latitude
+	c.sumLng += location.Longitude
+
+	if location.CountryCode != "" {
+		c.countries[location.CountryCode] = struct{}{}
+	}
+}
+
+// fill copies the collected details into stat
+func (c *statsCollector) fill(stat *Statistics) {
+	if len(c.reasons) > 0 {
+		stat.DiscardReasons = c.reasons
+	}
+	if len(c.classes) > 0 {
+		stat.Classes = c.classes
+	}
+	stat.DistinctCountries = len(c.countries)
+	if c.accepted > 0 {
+		stat.Coordinates = c.coords
+		stat.Coordinates.MeanLatitude = c.sumLat / float64(c.accepted)
+		stat.Coordinates.MeanLongitude = c.sumLng / float64(c.accepted)
+	}
+}
+
+// finish derives the throughput once Elapsed is known
+func (s *Statistics) finish() {
+	if seconds := s.Elapsed.Seconds(); seconds > 0 {
+		s.RowsPerSecond = float64(s.AcceptedEntries+s.DiscardedEntries+s.Duplicates) / seconds
+	}
+}
+
+// hashContent identifies the columns of a location to tell conflicting duplicates apart from identical ones
+func hashContent(location *geolocation.GeoLocation) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%v\x00%v\x00%d", location.CountryCode, location.Country, location.City,
+		location.Latitude, location.Longitude, location.MysteryValue)
+	return h.Sum64()
+}
+
+// DefaultHeapSampleInterval is used by WithHeapSampling for intervals which aren't positive
+const DefaultHeapSampleInterval = 100 * time.Millisecond
+
+// WithHeapSampling makes ParseCSV and Import report Statistics.PeakHeapBytes, sampling the heap size every interval
+// through runtime/metrics which doesn't stop the world
+func WithHeapSampling(interval time.Duration) Option {
+	return func(g *GeoService) {
+		if interval <= 0 {
+			interval = DefaultHeapSampleInterval
+		}
+		g.heapInterval = interval
+	}
+}
+
+// heapObjectsMetric is the memory occupied by live and not yet swept heap objects, runtime.MemStats.HeapAlloc
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// heapSampler records the peak heap size until stopped
+type heapSampler struct {
+	once    sync.Once
+	quit    chan struct{}
+	done    chan struct{}
+	samples []metrics.Sample
+	peak    uint64
+}
+
+// sampleHeap starts sampling the heap size of the GeoService, it returns nil when heap sampling is disabled
+func (g *GeoService) sampleHeap() (s *heapSampler) {
+	if g.heapInterval <= 0 {
+		return
+	}
+
+	s = &heapSampler{
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+		samples: []metrics.Sample{{Name: heapObjectsMetric}},
+	}
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(g.heapInterval)
+		defer ticker.Stop()
+		for {
+			s.sample()
+			select {
+			case <-ticker.C:
+			case <-s.quit:
+				s.sample()
+				return
+			}
+		}
+	}()
+	return
+}
+
+func (s *heapSampler) sample() {
+	metrics.Read(s.samples)
+	if s.samples[0].Value.Kind() == metrics.KindUint64 {
+		s.peak = max(s.peak, s.samples[0].Value.Uint64())
+	}
+}
+
+// stop ends the sampling and returns the peak heap size, zero for a nil sampler
+func (s *heapSampler) stop() uint64 {
+	if s == nil {
+		return 0
+	}
+	s.once.Do(func() {
+		close(s.quit)
+	})
+	<-s.done
+	return s.peak
 }
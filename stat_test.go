@@ -0,0 +1,78 @@
+package geoservice
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeoService_ImportDetails(t *testing.T) {
+	source := importCSV +
+		"70.95.73.73,TL,Saudi Arabia,Elsewhere,-49.16675918861615,-86.05920084416894,2559997162\n" +
+		"1.2.3.4,TL,Saudi Arabia,Gradymouth,north,-86.05920084416894,2559997162\n"
+
+	stat, err := NewGeoService(newTestDB(), WithHeapSampling(0)).Import(context.Background(), strings.NewReader(source), ImportOptions{Workers: 3})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if stat.BytesRead != int64(len(source)) || stat.RowsPerSecond <= 0 || stat.PeakHeapBytes == 0 {
+		t.Errorf("Import() bytes = %d, rows/s = %f, peak heap = %d", stat.BytesRead, stat.RowsPerSecond, stat.PeakHeapBytes)
+	}
+	if want := map[string]int{"empty_ip_address": 1, "invalid_number": 1}; !reflect.DeepEqual(stat.DiscardReasons, want) || stat.DiscardedEntries != 2 {
+		t.Errorf("Import() discard reasons = %v, discarded = %d, want %v", stat.DiscardReasons, stat.DiscardedEntries, want)
+	}
+	if stat.Duplicates != 2 || stat.ConflictingDuplicates != 1 || stat.DistinctCountries != 4 {
+		t.Errorf("Import() duplicates = %d, conflicting = %d, countries = %d", stat.Duplicates, stat.ConflictingDuplicates, stat.DistinctCountries)
+	}
+	if c := stat.Coordinates; c.MinLatitude != -84.87503094689836 || c.MaxLatitude != -49.16675918861615 || c.MaxLongitude != 7.206435933364332 {
+		t.Errorf("Import() coordinates = %+v", c)
+	}
+}
+
+func TestStatistics_JSON(t *testing.T) {
+	stat := Statistics{
+		Elapsed:          1500 * time.Millisecond,
+		ElapsedParsed:    time.Second,
+		BytesRead:        1024,
+		AcceptedEntries:  3,
+		DiscardedEntries: 1,
+		DiscardReasons:   map[string]int{"empty_ip_address": 1},
+		StoredEntries:    3,
+		Coordinates:      CoordinateStats{MinLatitude: -10, MaxLatitude: 10},
+	}
+
+	data, err := json.Marshal(stat)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"elapsed":"1.5s"`) || !strings.Contains(string(data), `"discard_reasons":{"empty_ip_address":1}`) {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	var decoded Statistics
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, stat) {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded, stat)
+	}
+
+	want := "3 stored, 0 failed, 3 accepted, 1 discarded (empty_ip_address=1), 0 duplicates (0 conflicting), 0 countries in 1.5s"
+	if !strings.HasPrefix(stat.String(), want) {
+		t.Errorf("String() = %s, want prefix %s", stat.String(), want)
+	}
+}
+
+func TestGeoService_ImportHeapSampling(t *testing.T) {
+	stat, err := NewGeoService(newTestDB()).Import(context.Background(), strings.NewReader(importCSV), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if stat.PeakHeapBytes != 0 {
+		t.Errorf("Import() peak heap = %d without WithHeapSampling, want 0", stat.PeakHeapBytes)
+	}
+}
@@ -33,18 +33,23 @@ func (t *testDB) Store(g *geolocation.GeoLocation) (err error) {
 	return
 }
 
+// StoreMany stores every location it can and reports the existing ones in a geolocation.BatchError
 func (t *testDB) StoreMany(gs []*geolocation.GeoLocation) (err error) {
 	t.Lock()
 	defer t.Unlock()
 
-	for _, g := range gs {
+	batchErr := &geolocation.BatchError{Errors: map[int]error{}}
+	for index, g := range gs {
 		if _, ok := t.data[g.IPAddress.String()]; ok {
-			err = errors.New("data exists")
-			return
+			batchErr.Errors[index] = errors.New("data exists")
+			continue
 		}
 		t.data[g.IPAddress.String()] = g
 	}
 
+	if len(batchErr.Errors) > 0 {
+		err = batchErr
+	}
 	return
 }
 
@@ -53,9 +58,63 @@ func (t *testDB) Retrieve(ip net.IP) (g *geolocation.GeoLocation, err error) {
 	defer t.Unlock()
 
 	if g = t.data[ip.String()]; g == nil {
-		err = errors.New("data does not exists")
+		err = geolocation.ErrNotFound
 		return
 	}
 
 	return
 }
+
+// Begin starts a transaction buffering locations until Commit
+func (t *testDB) Begin() (geolocation.Transaction, error) {
+	return &testTx{db: t, data: map[string]*geolocation.GeoLocation{}}, nil
+}
+
+// testTx is the geolocation.Transaction of testDB
+type testTx struct {
+	db   *testDB
+	data map[string]*geolocation.GeoLocation
+}
+
+func (t *testTx) StoreMany(gs []*geolocation.GeoLocation) (err error) {
+	t.db.Lock()
+	defer t.db.Unlock()
+
+	for _, g := range gs {
+		if _, ok := t.db.data[g.IPAddress.String()]; ok {
+			err = errors.New("data exists")
+			return
+		}
+		if _, ok := t.data[g.IPAddress.String()]; ok {
+			err = errors.New("data exists")
+			return
+		}
+	}
+
+	for _, g := range gs {
+		t.data[g.IPAddress.String()] = g
+	}
+	return
+}
+
+func (t *testTx) Commit() (err error) {
+	t.db.Lock()
+	defer t.db.Unlock()
+
+	for key := range t.data {
+		if _, ok := t.db.data[key]; ok {
+			err = errors.New("data exists")
+			return
+		}
+	}
+
+	for key, g := range t.data {
+		t.db.data[key] = g
+	}
+	return
+}
+
+func (t *testTx) Rollback() error {
+	t.data = nil
+	return nil
+}
@@ -0,0 +1,29 @@
+package geoservice
+
+import (
+	"github.com/aliforever/geo-service/enrich"
+	"github.com/aliforever/geo-service/timezone"
+)
+
+// WithTimezone sets the Timezone of every location imported or stored through the GeoService, running enrich.Timezone,
+// lookups of locations stored without one resolve it too. Imports report it as the "timezone" enricher, locations
+// whose timezone can't be resolved keep an empty one. A nil resolver uses timezone.Embedded.
+func WithTimezone(resolver *timezone.Resolver) Option {
+	return func(g *GeoService) {
+		if resolver == nil {
+			resolver = timezone.Embedded()
+		}
+		g.timezones = resolver
+		g.setEnricher(enrich.Timezone(resolver))
+	}
+}
+
+// ResolveTimezone returns the IANA timezone of lat, lng in countryCode, see timezone.Resolver.Resolve.
+// It uses the resolver given to WithTimezone, timezone.Embedded otherwise.
+func (g *GeoService) ResolveTimezone(lat, lng float64, countryCode string) (zone timezone.Zone, err error) {
+	resolver := g.timezones
+	if resolver == nil {
+		resolver = timezone.Embedded()
+	}
+	return resolver.Resolve(lat, lng, countryCode)
+}
@@ -0,0 +1,309 @@
+package timezone
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownZone = errors.New("unknown_timezone")
+
+//go:embed zone.tab
+var zoneTab string
+
+// boundaries.json.gz holds the timezone-boundary-builder 2025b land boundaries (ODbL), taken from tzf-rel-lite and
+// simplified to a tolerance of 0.02 degrees
+//
+//go:embed boundaries.json.gz
+var boundariesGzip []byte
+
+// Zone is an IANA timezone of a country
+type Zone struct {
+	ID          string `json:"id"`
+	CountryCode string `json:"country_code"`
+	// Latitude and Longitude are the principal location of the zone, e.g. Vienna for Europe/Vienna
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Comment tells zones of the same country apart
+	Comment string `json:"comment,omitempty"`
+}
+
+// Boundary is the area covered by a zone
+type Boundary struct {
+	ID    string
+	Shape spatial.MultiPolygon
+}
+
+// Resolver derives timezones from coordinates and country codes, it is immutable and safe for concurrent use.
+// A point belongs to the zone whose boundary contains it, without a boundary to the zone of its country with the
+// closest principal location.
+type Resolver struct {
+	zones []Zone
+	ids   map[string]Zone
+	tree  *spatial.Tree[Zone]
+	// countries holds the zones of every upper case country code, the most populous first
+	countries map[string][]Zone
+	trees     map[string]*spatial.Tree[Zone]
+	areas     []area
+	// boxes indexes the bounding boxes of areas by their position in areas
+	boxes *spatial.BoxTree[int]
+}
+
+// area is a polygon of a boundary with its bounding box
+type area struct {
+	id      string
+	polygon spatial.Polygon
+	box     geolocation.BoundingBox
+}
+
+func zonePosition(zone Zone) (lat, lng float64) {
+	return zone.Latitude, zone.Longitude
+}
+
+// New builds a resolver over zones and their boundaries, the first zone of a country is its fallback when coordinates
+// are unusable. boundaries may be nil to only use the principal locations.
+func New(zones []Zone, boundaries []Boundary) *Resolver {
+	r := &Resolver{
+		zones:     zones,
+		ids:       map[string]Zone{},
+		tree:      spatial.NewTree(zones, zonePosition),
+		countries: map[string][]Zone{},
+		trees:     map[string]*spatial.Tree[Zone]{},
+	}
+	for _, zone := range zones {
+		code := strings.ToUpper(zone.CountryCode)
+		r.countries[code] = append(r.countries[code], zone)
+		r.ids[zone.ID] = zone
+	}
+	for _, boundary := range boundaries {
+		for _, polygon := range boundary.Shape {
+			r.areas = append(r.areas, area{id: boundary.ID, polygon: polygon, box: polygon.Bounds()})
+		}
+	}
+	indexes := make([]int, len(r.areas))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	r.boxes = spatial.NewBoxTree(indexes, func(i int) geolocation.BoundingBox { return r.areas[i].box })
+	for code, countryZones := range r.countries {
+		if len(countryZones) > 1 {
+			r.trees[code] = spatial.NewTree(countryZones, zonePosition)
+		}
+	}
+	return r
+}
+
+var embedded = sync.OnceValue(func() *Resolver {
+	zones, err := LoadZoneTab(strings.NewReader(zoneTab))
+	if err != nil {
+		panic(fmt.Sprintf("embedded timezones: %v", err))
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(boundariesGzip))
+	if err != nil {
+		panic(fmt.Sprintf("embedded timezone boundaries: %v", err))
+	}
+	boundaries, err := LoadGeoJSON(reader)
+	if err != nil {
+		panic(fmt.Sprintf("embedded timezone boundaries: %v", err))
+	}
+	return New(zones.Zones(), boundaries)
+})
+
+// Embedded returns the resolver of the tzdb zone.tab and the timezone-boundary-builder boundaries built into the
+// binary. The boundaries are simplified to about 2 km and cover land only, points at sea use the principal locations.
+func Embedded() *Resolver {
+	return embedded()
+}
+
+// Zones returns every zone of the resolver
+func (r *Resolver) Zones() []Zone {
+	return r.zones
+}
+
+// Country returns the zone of countryCode used when coordinates are unusable, the most populous one
+func (r *Resolver) Country(countryCode string) (zone Zone, err error) {
+	zones := r.countries[strings.ToUpper(countryCode)]
+	if len(zones) == 0 {
+		err = ErrUnknownZone
+		return
+	}
+	zone = zones[0]
+	return
+}
+
+// Resolve returns the zone of lat, lng in countryCode: the zone whose boundary contains the coordinates, as long as it
+// belongs to a known country code. Otherwise countries with a single zone resolve to it, others to the zone with the
+// closest principal location. Invalid coordinates fall back to Country, an unknown or empty country code to any
+// boundary or the closest zone of any country.
+func (r *Resolver) Resolve(lat, lng float64, countryCode string) (zone Zone, err error) {
+	code := strings.ToUpper(countryCode)
+	zones := r.countries[code]
+	valid := spatial.ValidCoordinates(lat, lng)
+	if valid {
+		var ok bool
+		if zone, ok = r.locate(lat, lng, code, len(zones) > 0); ok {
+			return
+		}
+	}
+	if len(zones) == 1 || (len(zones) > 1 && !valid) {
+		zone = zones[0]
+		return
+	}
+	if !valid {
+		err = ErrUnknownZone
+		return
+	}
+
+	tree := r.tree
+	if len(zones) > 1 {
+		tree = r.trees[code]
+	}
+	neighbors, err := tree.Nearest(lat, lng, 1)
+	if err != nil {
+		return
+	}
+	if len(neighbors) == 0 {
+		err = ErrUnknownZone
+		return
+	}
+	zone = neighbors[0].Value
+	return
+}
+
+// locate returns the zone of the first boundary containing lat, lng, only of countryCode when inCountry is set.
+// Only the areas whose bounding box contains the point are tested against their polygon, in the order of the boundaries.
+func (r *Resolver) locate(lat, lng float64, countryCode string, inCountry bool) (zone Zone, ok bool) {
+	candidates := r.boxes.Search(geolocation.BoundingBox{MinLatitude: lat, MaxLatitude: lat, MinLongitude: lng, MaxLongitude: lng})
+	sort.Ints(candidates)
+	for _, i := range candidates {
+		area := r.areas[i]
+		if !area.polygon.Contains(lat, lng) {
+			continue
+		}
+		if zone, ok = r.ids[area.id]; !ok {
+			zone = Zone{ID: area.id}
+		}
+		if !inCountry || strings.EqualFold(zone.CountryCode, countryCode) {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+// LoadGeoJSON reads zone boundaries from a GeoJSON FeatureCollection whose features have a tzid property, the format of
+// the timezone-boundary-builder releases
+func LoadGeoJSON(r io.Reader) (boundaries []Boundary, err error) {
+	var collection struct {
+		Features []struct {
+			Properties struct {
+				TZID string `json:"tzid"`
+			} `json:"properties"`
+			Geometry json.RawMessage `json:"geometry"`
+		} `json:"features"`
+	}
+	if err = json.NewDecoder(r).Decode(&collection); err != nil {
+		return
+	}
+	for i, feature := range collection.Features {
+		if feature.Properties.TZID == "" {
+			return nil, fmt.Errorf("feature %d: missing tzid", i)
+		}
+		boundary := Boundary{ID: feature.Properties.TZID}
+		if boundary.Shape, err = spatial.ParseGeoJSON(feature.Geometry); err != nil {
+			return nil, fmt.Errorf("feature %d (%s): %w", i, boundary.ID, err)
+		}
+		boundaries = append(boundaries, boundary)
+	}
+	return
+}
+
+// LoadZoneTab reads a tzdb zone.tab: tab-separated country code, ISO 6709 coordinates, zone and optional comment.
+// Lines starting with # are skipped.
+func LoadZoneTab(r io.Reader) (resolver *Resolver, err error) {
+	scanner := bufio.NewScanner(r)
+	var zones []Zone
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		columns := strings.Split(text, "\t")
+		if len(columns) < 3 {
+			err = fmt.Errorf("line %d: %d columns, want at least 3", line, len(columns))
+			return
+		}
+		zone := Zone{CountryCode: columns[0], ID: columns[2]}
+		if len(columns) > 3 {
+			zone.Comment = columns[3]
+		}
+		if zone.Latitude, zone.Longitude, err = parseISO6709(columns[1]); err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			return
+		}
+		zones = append(zones, zone)
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	resolver = New(zones, nil)
+	return
+}
+
+// parseISO6709 parses the ±DDMM±DDDMM and ±DDMMSS±DDDMMSS coordinates of zone.tab
+func parseISO6709(value string) (lat, lng float64, err error) {
+	split := strings.IndexAny(value[min(1, len(value)):], "+-") + 1
+	if split <= 0 {
+		err = fmt.Errorf("invalid coordinates %q", value)
+		return
+	}
+	if lat, err = parseDegrees(value[:split], 2); err != nil {
+		return
+	}
+	lng, err = parseDegrees(value[split:], 3)
+	return
+}
+
+// parseDegrees parses a signed DDMM or DDMMSS value whose degrees have digits characters
+func parseDegrees(value string, digits int) (degrees float64, err error) {
+	invalid := fmt.Errorf("invalid coordinate %q", value)
+	if (len(value) != 3+digits && len(value) != 5+digits) || (value[0] != '+' && value[0] != '-') {
+		err = invalid
+		return
+	}
+
+	var seconds int
+	if len(value) == 5+digits {
+		if seconds, err = strconv.Atoi(value[3+digits:]); err != nil {
+			err = invalid
+			return
+		}
+	}
+	whole, err := strconv.Atoi(value[1 : 1+digits])
+	if err != nil {
+		err = invalid
+		return
+	}
+	minutes, err := strconv.Atoi(value[1+digits : 3+digits])
+	if err != nil {
+		err = invalid
+		return
+	}
+
+	degrees = float64(whole) + float64(minutes)/60 + float64(seconds)/3600
+	if value[0] == '-' {
+		degrees = -degrees
+	}
+	return
+}
@@ -0,0 +1,200 @@
+package timezone
+
+import (
+	"github.com/aliforever/geo-service/spatial"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestEmbedded_Resolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		lat, lng    float64
+		countryCode string
+		want        string
+		wantErr     error
+	}{
+		{name: "SingleZone", lat: 48.2, lng: 16.37, countryCode: "AT", want: "Europe/Vienna"},
+		{name: "SingleZoneFarAway", lat: 40.71, lng: -74.01, countryCode: "AT", want: "Europe/Vienna"},
+		{name: "Boundary", lat: 34.05, lng: -118.24, countryCode: "us", want: "America/Los_Angeles"},
+		// The principal locations closest to El Paso and Kazan are Phoenix and Ulyanovsk
+		{name: "BoundaryNotClosest", lat: 31.76, lng: -106.49, countryCode: "US", want: "America/Denver"},
+		{name: "BoundaryNotClosestRussia", lat: 55.79, lng: 49.12, countryCode: "RU", want: "Europe/Moscow"},
+		{name: "BoundaryBrazil", lat: -3.1, lng: -60.02, countryCode: "BR", want: "America/Manaus"},
+		{name: "BoundaryOfOtherCountry", lat: 32.52, lng: -117.03, countryCode: "US", want: "America/Los_Angeles"},
+		{name: "AtSea", lat: 0, lng: -30, countryCode: "BR", want: "America/Noronha"},
+		{name: "CountryFallback", lat: 91, lng: 0, countryCode: "US", want: "America/New_York"},
+		{name: "UnknownCountry", lat: 32.52, lng: -117.03, countryCode: "XX", want: "America/Tijuana"},
+		{name: "NoCountry", lat: 48.15, lng: 16.94, want: "Europe/Vienna"},
+		{name: "NoCountryAtSea", lat: 35.5, lng: 140.5, want: "Asia/Tokyo"},
+		{name: "Unresolvable", lat: 91, lng: 0, wantErr: ErrUnknownZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := Embedded().Resolve(tt.lat, tt.lng, tt.countryCode)
+			if err != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if zone.ID != tt.want {
+				t.Errorf("Resolve() = %s, want %s", zone.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedded_Zones(t *testing.T) {
+	zones := Embedded().Zones()
+	if len(zones) < 400 {
+		t.Fatalf("Zones() = %d zones, want at least 400", len(zones))
+	}
+	for _, zone := range zones {
+		if _, err := time.LoadLocation(zone.ID); err != nil {
+			t.Errorf("LoadLocation(%s) error = %v", zone.ID, err)
+		}
+	}
+}
+
+func TestEmbedded_Boundaries(t *testing.T) {
+	resolver := Embedded()
+	if len(resolver.areas) == 0 {
+		t.Fatalf("Embedded() has no boundaries")
+	}
+	for _, area := range resolver.areas {
+		if _, ok := resolver.ids[area.id]; !ok {
+			t.Errorf("boundary %s is not a zone of zone.tab", area.id)
+		}
+	}
+}
+
+func TestEmbedded_Locate(t *testing.T) {
+	resolver := Embedded()
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		lat, lng := random.Float64()*140-60, random.Float64()*360-180
+
+		// the R-tree finds the same boundary as testing every polygon in order
+		var want string
+		for _, area := range resolver.areas {
+			if area.polygon.Contains(lat, lng) {
+				want = area.id
+				break
+			}
+		}
+		zone, ok := resolver.locate(lat, lng, "", false)
+		if zone.ID != want || ok != (want != "") {
+			t.Errorf("locate(%v, %v) = %s, %v, want %s", lat, lng, zone.ID, ok, want)
+		}
+	}
+}
+
+func TestLoadGeoJSON(t *testing.T) {
+	square := `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`
+	tests := []struct {
+		name    string
+		data    string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "FeatureCollection",
+			data:    `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"tzid":"Etc/A"},"geometry":` + square + `},{"type":"Feature","properties":{"tzid":"Etc/B"},"geometry":` + square + `}]}`,
+			wantIDs: []string{"Etc/A", "Etc/B"},
+		},
+		{name: "MissingTZID", data: `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":` + square + `}]}`, wantErr: true},
+		{name: "InvalidGeometry", data: `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"tzid":"Etc/A"},"geometry":{"type":"Point","coordinates":[0,0]}}]}`, wantErr: true},
+		{name: "InvalidJSON", data: `{"features":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			boundaries, err := LoadGeoJSON(strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadGeoJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(boundaries) != len(tt.wantIDs) {
+				t.Fatalf("LoadGeoJSON() = %d boundaries, want %d", len(boundaries), len(tt.wantIDs))
+			}
+			for i, boundary := range boundaries {
+				if boundary.ID != tt.wantIDs[i] || !boundary.Shape.Contains(5, 5) {
+					t.Errorf("LoadGeoJSON()[%d] = %s, want %s containing 5, 5", i, boundary.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNew_Boundaries(t *testing.T) {
+	zones := []Zone{
+		{ID: "Etc/West", CountryCode: "AA", Latitude: 0, Longitude: 0},
+		{ID: "Etc/East", CountryCode: "AA", Latitude: 0, Longitude: 20},
+		{ID: "Etc/Other", CountryCode: "BB", Latitude: 0, Longitude: 40},
+	}
+	// Etc/West reaches far past the middle between the principal locations
+	boundaries := []Boundary{
+		{ID: "Etc/West", Shape: spatial.MultiPolygon{{{{-5, -5}, {15, -5}, {15, 5}, {-5, 5}, {-5, -5}}}}},
+		{ID: "Etc/Other", Shape: spatial.MultiPolygon{{{{30, -5}, {50, -5}, {50, 5}, {30, 5}, {30, -5}}}}},
+	}
+	resolver := New(zones, boundaries)
+
+	tests := []struct {
+		name        string
+		lat, lng    float64
+		countryCode string
+		want        string
+	}{
+		{name: "Boundary", lat: 0, lng: 14, countryCode: "AA", want: "Etc/West"},
+		{name: "OutsideBoundaries", lat: 0, lng: 16, countryCode: "AA", want: "Etc/East"},
+		{name: "BoundaryOfOtherCountry", lat: 0, lng: 31, countryCode: "AA", want: "Etc/East"},
+		{name: "UnknownCountry", lat: 0, lng: 14, want: "Etc/West"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := resolver.Resolve(tt.lat, tt.lng, tt.countryCode)
+			if err != nil || zone.ID != tt.want {
+				t.Errorf("Resolve() = %s, error = %v, want %s", zone.ID, err, tt.want)
+			}
+		})
+	}
+	if zone, _ := New(zones, nil).Resolve(0, 14, "AA"); zone.ID != "Etc/East" {
+		t.Errorf("Resolve() without boundaries = %s, want Etc/East", zone.ID)
+	}
+}
+
+func TestLoadZoneTab(t *testing.T) {
+	tests := []struct {
+		name    string
+		tab     string
+		want    Zone
+		wantErr bool
+	}{
+		{
+			name: "Minutes",
+			tab:  "# comment\nAT\t+4813+01620\tEurope/Vienna\n",
+			want: Zone{ID: "Europe/Vienna", CountryCode: "AT", Latitude: 48 + 13.0/60, Longitude: 16 + 20.0/60},
+		},
+		{
+			name: "Seconds",
+			tab:  "US\t+404251-0740023\tAmerica/New_York\tEastern (most areas)\n",
+			want: Zone{ID: "America/New_York", CountryCode: "US", Latitude: 40.71416666666667, Longitude: -74.00638888888889, Comment: "Eastern (most areas)"},
+		},
+		{name: "MissingColumn", tab: "AT\t+4813+01620\n", wantErr: true},
+		{name: "InvalidCoordinates", tab: "AT\t+48+016\tEurope/Vienna\n", wantErr: true},
+		{name: "MissingLongitude", tab: "AT\t+4813\tEurope/Vienna\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := LoadZoneTab(strings.NewReader(tt.tab))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadZoneTab() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if zones := resolver.Zones(); len(zones) != 1 || zones[0] != tt.want {
+				t.Errorf("Zones() = %+v, want %+v", zones, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package geoservice
+
+import (
+	"context"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"github.com/aliforever/geo-service/timezone"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestGeoService_ImportTimezone(t *testing.T) {
+	db := memory.New()
+	g := NewGeoService(db, WithTimezone(nil))
+	if _, err := g.Import(context.Background(), strings.NewReader(importCSV), ImportOptions{}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	want := map[string]string{
+		"200.106.141.15": "Europe/Ljubljana",
+		"160.103.7.140":  "Europe/Prague",
+		"70.95.73.73":    "Asia/Dili",
+		"125.159.20.54":  "Europe/Vaduz",
+	}
+	for ip, zone := range want {
+		location, err := db.Retrieve(net.ParseIP(ip))
+		if err != nil || location.Timezone != zone {
+			t.Errorf("Retrieve(%s) = %+v, error = %v, want timezone %s", ip, location, err, zone)
+		}
+	}
+}
+
+func TestGeoService_RetrieveLocationTimezone(t *testing.T) {
+	db := memory.New()
+	stored := &geolocation.GeoLocation{IPAddress: net.ParseIP("1.1.1.1"), CountryCode: "US", Latitude: 34.05, Longitude: -118.24}
+	if err := NewGeoService(db).StoreLocations([]*geolocation.GeoLocation{stored}); err != nil {
+		t.Fatalf("StoreLocations() error = %v", err)
+	}
+	if stored.Timezone != "" {
+		t.Fatalf("StoreLocations() Timezone = %s, want none without WithTimezone", stored.Timezone)
+	}
+
+	resolver := timezone.New([]timezone.Zone{
+		{ID: "America/New_York", CountryCode: "US", Latitude: 40.71, Longitude: -74.01},
+		{ID: "America/Los_Angeles", CountryCode: "US", Latitude: 34.05, Longitude: -118.24},
+	}, nil)
+	location, err := NewGeoService(db, WithTimezone(resolver)).RetrieveLocation(stored.IPAddress)
+	if err != nil || location.Timezone != "America/Los_Angeles" {
+		t.Fatalf("RetrieveLocation() = %+v, error = %v, want America/Los_Angeles", location, err)
+	}
+	if location == stored || stored.Timezone != "" {
+		t.Errorf("RetrieveLocation() modified the stored location")
+	}
+}
+
+func TestGeoService_ResolveTimezone(t *testing.T) {
+	tests := []struct {
+		name        string
+		lat, lng    float64
+		countryCode string
+		want        string
+		wantErr     error
+	}{
+		{name: "Coordinates", lat: 34.05, lng: -118.24, countryCode: "US", want: "America/Los_Angeles"},
+		{name: "CountryFallback", lat: 0, lng: 200, countryCode: "AT", want: "Europe/Vienna"},
+		{name: "Unknown", lat: 0, lng: 200, wantErr: timezone.ErrUnknownZone},
+	}
+	g := NewGeoService(memory.New())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := g.ResolveTimezone(tt.lat, tt.lng, tt.countryCode)
+			if err != tt.wantErr {
+				t.Fatalf("ResolveTimezone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if zone.ID != tt.want {
+				t.Errorf("ResolveTimezone() = %s, want %s", zone.ID, tt.want)
+			}
+		})
+	}
+}
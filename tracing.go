@@ -0,0 +1,69 @@
+package geoservice
+
+import (
+	"context"
+	"fmt"
+)
+
+// Span names started by GeoService
+const (
+	SpanImport     = "geoservice.import"
+	SpanRead       = "geoservice.read"
+	SpanParse      = "geoservice.parse"
+	SpanDedupe     = "geoservice.dedupe"
+	SpanStoreChunk = "geoservice.store_chunk"
+	SpanRetrieve   = "geoservice.retrieve"
+)
+
+// Attribute is a key value pair describing a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts spans around the import stages and lookups, implementations have to be safe for concurrent use.
+// It maps directly onto OpenTelemetry's trace.Tracer, the tracing package records spans in memory.
+type Tracer interface {
+	// Start starts a span as a child of the span carried by ctx, if any, and returns a context carrying the new span
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+
+func (nopSpan) RecordError(error) {}
+
+func (nopSpan) End() {}
+
+// WithTracer traces imports and lookups with t
+func WithTracer(t Tracer) Option {
+	return func(g *GeoService) {
+		g.tracer = t
+	}
+}
+
+// trace returns the configured Tracer, a no-op when there is none
+func (g *GeoService) trace() Tracer {
+	if g.tracer == nil {
+		return nopTracer{}
+	}
+	return g.tracer
+}
+
+// repositoryType names the implementation of a Repository for span attributes
+func repositoryType(db interface{}) string {
+	return fmt.Sprintf("%T", db)
+}
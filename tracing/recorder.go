@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	geoservice "github.com/aliforever/geo-service"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanData is a finished span
+type SpanData struct {
+	ID uint64
+	// ParentID is 0 for root spans
+	ParentID   uint64
+	Name       string
+	Attributes map[string]interface{}
+	Errors     []error
+	Start      time.Time
+	End        time.Time
+}
+
+func (s SpanData) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+type spanKey struct{}
+
+// Recorder is a geoservice.Tracer keeping finished spans in memory, for tests and debugging
+type Recorder struct {
+	mu     sync.Mutex
+	spans  []SpanData
+	nextID atomic.Uint64
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...geoservice.Attribute) (context.Context, geoservice.Span) {
+	s := &span{
+		recorder: r,
+		data: SpanData{
+			ID:         r.nextID.Add(1),
+			Name:       name,
+			Attributes: map[string]interface{}{},
+			Start:      time.Now(),
+		},
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.data.ParentID = parent.data.ID
+	}
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Spans returns the finished spans in the order they ended
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SpanData{}, r.spans...)
+}
+
+// Named returns the finished spans called name
+func (r *Recorder) Named(name string) (spans []SpanData) {
+	for _, s := range r.Spans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return
+}
+
+// Reset drops the finished spans
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = nil
+}
+
+type span struct {
+	sync.Mutex
+	recorder *Recorder
+	data     SpanData
+	ended    bool
+}
+
+func (s *span) SetAttributes(attrs ...geoservice.Attribute) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, attr := range attrs {
+		s.data.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *span) RecordError(err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.data.Errors = append(s.data.Errors, err)
+}
+
+// End records the span, later calls are ignored
+func (s *span) End() {
+	s.Lock()
+	if s.ended {
+		s.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.Unlock()
+
+	s.recorder.mu.Lock()
+	s.recorder.spans = append(s.recorder.spans, data)
+	s.recorder.mu.Unlock()
+}
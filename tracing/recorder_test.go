@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	geoservice "github.com/aliforever/geo-service"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"net"
+	"strings"
+	"testing"
+)
+
+const importCSV = `ip_address,country_code,country,city,latitude,longitude,mystery_value
+200.106.141.15,SI,Nepal,DuBuquemouth,-84.87503094689836,7.206435933364332,7823011346
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+,PY,Falkland Islands (Malvinas),,75.41685191518815,-144.6943217219469,0
+160.103.7.140,CZ,Nicaragua,New Neva,-68.31023296602508,-37.62435199624531,7301823115
+`
+
+// failingDB fails every write
+type failingDB struct {
+	*memory.Repository
+}
+
+func (failingDB) StoreMany([]*geolocation.GeoLocation) error {
+	return errors.New("disk_full")
+}
+
+func TestRecorder_Import(t *testing.T) {
+	recorder := NewRecorder()
+	gs := geoservice.NewGeoService(memory.New(), geoservice.WithTracer(recorder))
+
+	ctx, parent := recorder.Start(context.Background(), "request")
+	opts := geoservice.ImportOptions{Workers: 2, Batch: geoservice.BatchOptions{ChunkSize: 1}}
+	if _, err := gs.Import(ctx, strings.NewReader(importCSV), opts); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	parent.End()
+
+	imports := recorder.Named(geoservice.SpanImport)
+	if len(imports) != 1 || imports[0].ParentID != recorder.Named("request")[0].ID {
+		t.Fatalf("Import() spans = %+v, want a child of the request span", imports)
+	}
+	root := imports[0]
+	if root.Attributes["stored"] != 2 || root.Attributes["duplicates"] != 1 || root.Attributes["repository"] != "*memory.Repository" {
+		t.Errorf("Import() span attributes = %v", root.Attributes)
+	}
+
+	tests := []struct {
+		name      string
+		wantSpans int
+		wantSum   map[string]int
+	}{
+		{name: geoservice.SpanRead, wantSpans: 1},
+		{name: geoservice.SpanParse, wantSpans: 2, wantSum: map[string]int{"rows": 4, "invalid": 1}},
+		{name: geoservice.SpanDedupe, wantSpans: 1, wantSum: map[string]int{"rows": 4, "duplicates": 1, "chunks": 2}},
+		{name: geoservice.SpanStoreChunk, wantSpans: 2, wantSum: map[string]int{"locations": 2, "failed": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := recorder.Named(tt.name)
+			if len(spans) != tt.wantSpans {
+				t.Fatalf("Import() %s spans = %d, want %d", tt.name, len(spans), tt.wantSpans)
+			}
+			sum := map[string]int{}
+			for _, s := range spans {
+				if s.ParentID != root.ID {
+					t.Errorf("Import() %s parent = %d, want %d", tt.name, s.ParentID, root.ID)
+				}
+				for key := range tt.wantSum {
+					value, _ := s.Attributes[key].(int)
+					sum[key] += value
+				}
+			}
+			for key, want := range tt.wantSum {
+				if sum[key] != want {
+					t.Errorf("Import() %s %s = %d, want %d", tt.name, key, sum[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecorder_Errors(t *testing.T) {
+	recorder := NewRecorder()
+	gs := geoservice.NewGeoService(failingDB{memory.New()}, geoservice.WithTracer(recorder))
+
+	if _, err := gs.Import(context.Background(), strings.NewReader(importCSV), geoservice.ImportOptions{}); !errors.Is(err, geoservice.ErrIncompleteStore) {
+		t.Fatalf("Import() error = %v, want %v", err, geoservice.ErrIncompleteStore)
+	}
+	if chunks := recorder.Named(geoservice.SpanStoreChunk); len(chunks) != 1 || len(chunks[0].Errors) != 1 {
+		t.Errorf("Import() store chunk spans = %+v, want the StoreMany error", chunks)
+	}
+	if imports := recorder.Named(geoservice.SpanImport); len(imports[0].Errors) != 1 {
+		t.Errorf("Import() span errors = %v", imports[0].Errors)
+	}
+
+	gs.RetrieveLocation(net.ParseIP("1.1.1.1"))
+	lookups := recorder.Named(geoservice.SpanRetrieve)
+	if len(lookups) != 1 || lookups[0].Attributes["found"] != false || len(lookups[0].Errors) != 0 {
+		t.Errorf("RetrieveLocation() spans = %+v, want a not found lookup without error", lookups)
+	}
+}
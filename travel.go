@@ -0,0 +1,207 @@
+package geoservice
+
+import (
+	"context"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/spatial"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSpeedKmh is roughly the cruise speed of an airliner
+	DefaultMaxSpeedKmh = 1000
+	// DefaultMinDistanceKm ignores jumps within the usual error of IP geolocation
+	DefaultMinDistanceKm = 300
+	// DefaultTravelExpiry forgets users whose last event is older than a day
+	DefaultTravelExpiry = 24 * time.Hour
+)
+
+// pruneEvery is the number of evaluated events between two sweeps of expired users
+const pruneEvery = 1024
+
+// Distance is the great-circle distance between the locations of two IP addresses
+type Distance struct {
+	From       *geolocation.GeoLocation `json:"from"`
+	To         *geolocation.GeoLocation `json:"to"`
+	Kilometers float64                  `json:"kilometers"`
+}
+
+// Speed returns the speed in km/h needed to cover the distance in elapsed, elapsed shorter than a second counts as a second
+func (d *Distance) Speed(elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = -elapsed
+	}
+	return d.Kilometers / max(elapsed, time.Second).Hours()
+}
+
+// Distance looks both IP addresses up with RetrieveLocation and returns the great-circle distance between them
+func (g *GeoService) Distance(from, to net.IP) (distance *Distance, err error) {
+	var fromLocation, toLocation *geolocation.GeoLocation
+	if fromLocation, err = g.RetrieveLocation(from); err != nil {
+		return
+	}
+	if toLocation, err = g.RetrieveLocation(to); err != nil {
+		return
+	}
+	distance = newDistance(fromLocation, toLocation)
+	return
+}
+
+func newDistance(from, to *geolocation.GeoLocation) *Distance {
+	return &Distance{
+		From:       from,
+		To:         to,
+		Kilometers: spatial.Haversine(from.Latitude, from.Longitude, to.Latitude, to.Longitude),
+	}
+}
+
+// TravelEvent is an IP address a user was seen at, e.g. a login
+type TravelEvent struct {
+	User string    `json:"user"`
+	IP   net.IP    `json:"ip"`
+	Time time.Time `json:"time"`
+}
+
+// TravelAlert reports two consecutive events of a user too far apart for the time between them
+type TravelAlert struct {
+	User     string        `json:"user"`
+	Previous TravelEvent   `json:"previous"`
+	Current  TravelEvent   `json:"current"`
+	Distance Distance      `json:"distance"`
+	Elapsed  time.Duration `json:"elapsed"`
+	SpeedKmh float64       `json:"speed_kmh"`
+}
+
+// TravelOptions configures a TravelEvaluator
+type TravelOptions struct {
+	// MaxSpeedKmh is the fastest plausible travel, defaults to DefaultMaxSpeedKmh
+	MaxSpeedKmh float64
+	// MinDistanceKm never alerts on shorter distances whatever the speed, defaults to DefaultMinDistanceKm
+	MinDistanceKm float64
+	// Expiry forgets the last event of users who weren't seen for longer, defaults to DefaultTravelExpiry
+	Expiry time.Duration
+	// OnError is called by Run for events whose IP address couldn't be located, they are skipped
+	OnError func(TravelEvent, error)
+}
+
+func (o TravelOptions) withDefaults() TravelOptions {
+	if o.MaxSpeedKmh <= 0 {
+		o.MaxSpeedKmh = DefaultMaxSpeedKmh
+	}
+	if o.MinDistanceKm <= 0 {
+		o.MinDistanceKm = DefaultMinDistanceKm
+	}
+	if o.Expiry <= 0 {
+		o.Expiry = DefaultTravelExpiry
+	}
+	return o
+}
+
+// lastSeen is the latest event of a user along with its location
+type lastSeen struct {
+	event    TravelEvent
+	location *geolocation.GeoLocation
+}
+
+// TravelEvaluator compares every event of a user with the user's latest one, it is safe for concurrent use
+type TravelEvaluator struct {
+	sync.Mutex
+	gs        *GeoService
+	opts      TravelOptions
+	users     map[string]lastSeen
+	evaluated int
+	latest    time.Time
+}
+
+// NewTravelEvaluator returns an evaluator locating events with RetrieveLocation
+func (g *GeoService) NewTravelEvaluator(opts TravelOptions) *TravelEvaluator {
+	return &TravelEvaluator{gs: g, opts: opts.withDefaults(), users: map[string]lastSeen{}}
+}
+
+// Evaluate locates the event and returns an alert when reaching it from the user's latest event needs a speed above
+// MaxSpeedKmh over at least MinDistanceKm. Events older than the user's latest one are compared with it but don't replace it.
+func (e *TravelEvaluator) Evaluate(event TravelEvent) (alert *TravelAlert, err error) {
+	location, err := e.gs.RetrieveLocation(event.IP)
+	if err != nil {
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.prune(event.Time)
+	previous, seen := e.users[event.User]
+	if !seen || !event.Time.Before(previous.event.Time) {
+		e.users[event.User] = lastSeen{event: event, location: location}
+	}
+	if !seen || previous.event.Time.Before(event.Time.Add(-e.opts.Expiry)) {
+		return
+	}
+
+	distance := newDistance(previous.location, location)
+	elapsed := event.Time.Sub(previous.event.Time)
+	if elapsed < 0 {
+		elapsed = -elapsed
+	}
+	speed := distance.Speed(elapsed)
+	if distance.Kilometers < e.opts.MinDistanceKm || speed <= e.opts.MaxSpeedKmh {
+		return
+	}
+
+	alert = &TravelAlert{
+		User:     event.User,
+		Previous: previous.event,
+		Current:  event,
+		Distance: *distance,
+		Elapsed:  elapsed,
+		SpeedKmh: speed,
+	}
+	return
+}
+
+// prune drops users whose latest event expired every pruneEvery events, e must be locked
+func (e *TravelEvaluator) prune(now time.Time) {
+	if now.After(e.latest) {
+		e.latest = now
+	}
+	if e.evaluated++; e.evaluated%pruneEvery != 0 {
+		return
+	}
+	for user, last := range e.users {
+		if last.event.Time.Before(e.latest.Add(-e.opts.Expiry)) {
+			delete(e.users, user)
+		}
+	}
+}
+
+// Run evaluates events until the channel is closed or ctx is done and sends every alert to alerts.
+// It returns ctx.Err() when canceled, events that can't be located are reported to OnError and skipped.
+func (e *TravelEvaluator) Run(ctx context.Context, events <-chan TravelEvent, alerts chan<- TravelAlert) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			alert, err := e.Evaluate(event)
+			if err != nil {
+				if e.opts.OnError != nil {
+					e.opts.OnError(event, err)
+				}
+				continue
+			}
+			if alert == nil {
+				continue
+			}
+			select {
+			case alerts <- *alert:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
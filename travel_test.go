@@ -0,0 +1,133 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"github.com/aliforever/geo-service/geolocation"
+	"github.com/aliforever/geo-service/memory"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTravelService(t *testing.T) *GeoService {
+	db := memory.New()
+	for _, location := range []*geolocation.GeoLocation{
+		{IPAddress: net.ParseIP("1.1.1.1"), City: "Vienna", Latitude: 48.2082, Longitude: 16.3738},
+		{IPAddress: net.ParseIP("2.2.2.2"), City: "Bratislava", Latitude: 48.1486, Longitude: 17.1077},
+		{IPAddress: net.ParseIP("3.3.3.3"), City: "New York", Latitude: 40.7128, Longitude: -74.0060},
+	} {
+		if err := db.Store(location); err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+	}
+	return NewGeoService(db)
+}
+
+func TestGeoService_Distance(t *testing.T) {
+	g := newTravelService(t)
+
+	tests := []struct {
+		name     string
+		from, to string
+		wantKm   float64
+		wantErr  error
+	}{
+		{name: "Near", from: "1.1.1.1", to: "2.2.2.2", wantKm: 55},
+		{name: "Far", from: "1.1.1.1", to: "3.3.3.3", wantKm: 6796},
+		{name: "Same", from: "3.3.3.3", to: "3.3.3.3", wantKm: 0},
+		{name: "NotFound", from: "1.1.1.1", to: "4.4.4.4", wantErr: geolocation.ErrNotFound},
+		{name: "Invalid", from: "", to: "1.1.1.1", wantErr: ErrInvalidIP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance, err := g.Distance(net.ParseIP(tt.from), net.ParseIP(tt.to))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Distance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && math.Abs(distance.Kilometers-tt.wantKm) > 5 {
+				t.Errorf("Distance() = %v km, want about %v", distance.Kilometers, tt.wantKm)
+			}
+		})
+	}
+}
+
+func TestDistance_Speed(t *testing.T) {
+	distance := &Distance{Kilometers: 900}
+	if got := distance.Speed(90 * time.Minute); got != 600 {
+		t.Errorf("Speed() = %v, want 600", got)
+	}
+	if got := distance.Speed(0); got != 900*3600 {
+		t.Errorf("Speed() = %v, want the speed over a second", got)
+	}
+}
+
+func TestTravelEvaluator_Evaluate(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	e := newTravelService(t).NewTravelEvaluator(TravelOptions{Expiry: 12 * time.Hour})
+
+	tests := []struct {
+		name      string
+		event     TravelEvent
+		wantAlert bool
+		wantErr   error
+	}{
+		{name: "First", event: TravelEvent{User: "alice", IP: net.ParseIP("1.1.1.1"), Time: begin}},
+		{name: "TooClose", event: TravelEvent{User: "alice", IP: net.ParseIP("2.2.2.2"), Time: begin.Add(time.Minute)}},
+		{name: "Impossible", event: TravelEvent{User: "alice", IP: net.ParseIP("3.3.3.3"), Time: begin.Add(time.Hour)}, wantAlert: true},
+		{name: "OutOfOrder", event: TravelEvent{User: "alice", IP: net.ParseIP("1.1.1.1"), Time: begin.Add(30 * time.Minute)}, wantAlert: true},
+		{name: "Plausible", event: TravelEvent{User: "alice", IP: net.ParseIP("1.1.1.1"), Time: begin.Add(10 * time.Hour)}},
+		{name: "OtherUser", event: TravelEvent{User: "bob", IP: net.ParseIP("3.3.3.3"), Time: begin.Add(10 * time.Hour)}},
+		{name: "Expired", event: TravelEvent{User: "alice", IP: net.ParseIP("3.3.3.3"), Time: begin.Add(23 * time.Hour)}},
+		{name: "NotFound", event: TravelEvent{User: "alice", IP: net.ParseIP("4.4.4.4"), Time: begin.Add(23 * time.Hour)}, wantErr: geolocation.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, err := e.Evaluate(tt.event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (alert != nil) != tt.wantAlert {
+				t.Fatalf("Evaluate() alert = %+v, wantAlert %v", alert, tt.wantAlert)
+			}
+			if alert != nil && (alert.SpeedKmh <= DefaultMaxSpeedKmh || alert.Distance.Kilometers < DefaultMinDistanceKm || alert.Current.Time != tt.event.Time) {
+				t.Errorf("Evaluate() alert = %+v", alert)
+			}
+		})
+	}
+}
+
+func TestTravelEvaluator_Run(t *testing.T) {
+	begin := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	var failed []TravelEvent
+	e := newTravelService(t).NewTravelEvaluator(TravelOptions{OnError: func(event TravelEvent, err error) {
+		failed = append(failed, event)
+	}})
+
+	events := make(chan TravelEvent, 4)
+	events <- TravelEvent{User: "alice", IP: net.ParseIP("1.1.1.1"), Time: begin}
+	events <- TravelEvent{User: "alice", IP: net.ParseIP("4.4.4.4"), Time: begin.Add(time.Minute)}
+	events <- TravelEvent{User: "alice", IP: net.ParseIP("3.3.3.3"), Time: begin.Add(2 * time.Hour)}
+	close(events)
+
+	alerts := make(chan TravelAlert, 4)
+	if err := e.Run(context.Background(), events, alerts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	close(alerts)
+
+	var got []TravelAlert
+	for alert := range alerts {
+		got = append(got, alert)
+	}
+	if len(got) != 1 || got[0].Distance.To.City != "New York" || got[0].Elapsed != 2*time.Hour || len(failed) != 1 {
+		t.Errorf("Run() alerts = %+v, failed = %+v", got, failed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := e.Run(ctx, make(chan TravelEvent), alerts); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
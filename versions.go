@@ -0,0 +1,306 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrVersioningDisabled = errors.New("versioning_disabled")
+	ErrVersionNotFound    = errors.New("version_not_found")
+	ErrVersionExists      = errors.New("version_exists")
+	ErrNoPreviousVersion  = errors.New("no_previous_version")
+	ErrValidationFailed   = errors.New("validation_failed")
+)
+
+// RepositoryFactory creates an empty Repository holding a new dataset version
+type RepositoryFactory func(version string) (geolocation.Repository, error)
+
+// Dataset is a version of the served data along with the statistics of its import
+type Dataset struct {
+	Version     string
+	Repository  geolocation.Repository
+	Stat        *Statistics
+	CreatedAt   time.Time
+	ActivatedAt time.Time
+	// readers counts the lookups and writes using the dataset, retired is subtracted once it is dropped
+	readers atomic.Int64
+}
+
+// retired is subtracted from Dataset.readers when the dataset is dropped, making the count negative
+const retired = 1 << 62
+
+// acquire takes a reference keeping the dataset open until release, it fails once the dataset was retired
+func (d *Dataset) acquire() bool {
+	for {
+		readers := d.readers.Load()
+		if readers < 0 {
+			return false
+		}
+		if d.readers.CompareAndSwap(readers, readers+1) {
+			return true
+		}
+	}
+}
+
+// release drops a reference taken by acquire, the last one closes a retired dataset
+func (d *Dataset) release() {
+	if d.readers.Add(-1) == -retired {
+		closeDataset(d)
+	}
+}
+
+// retire drops the dataset, it is closed right away or by the release of its last reader
+func (d *Dataset) retire() {
+	if d.readers.Add(-retired) == -retired {
+		closeDataset(d)
+	}
+}
+
+// VersionOptions configures PrepareVersion
+type VersionOptions struct {
+	// Version is the ID of the new dataset, defaults to a timestamp
+	Version string
+	Import  ImportOptions
+	// MinStored rejects datasets with fewer stored locations
+	MinStored int
+	// MaxDiscardRatio rejects datasets discarding a larger share of their rows, zero disables the check
+	MaxDiscardRatio float64
+	// Validate runs custom checks against the imported dataset before it can be activated
+	Validate func(*Dataset) error
+}
+
+// versionStore keeps prepared datasets and the previously active ones, newest first
+type versionStore struct {
+	sync.Mutex
+	factory  RepositoryFactory
+	keep     int
+	prepared map[string]*Dataset
+	// preparing reserves the versions being imported by PrepareVersion
+	preparing map[string]struct{}
+	history   []*Dataset
+}
+
+// WithVersioning makes every import create a new dataset through factory and keeps keep previously active
+// datasets for rollback, a negative keep keeps none. The Repository passed to NewGeoService is served until the first
+// version is activated.
+func WithVersioning(factory RepositoryFactory, keep int) Option {
+	if keep < 0 {
+		keep = 0
+	}
+	return func(g *GeoService) {
+		g.versions = &versionStore{
+			factory:   factory,
+			keep:      keep,
+			prepared:  map[string]*Dataset{},
+			preparing: map[string]struct{}{},
+		}
+	}
+}
+
+// ActiveVersion returns the ID of the dataset serving lookups, empty before any version is activated
+func (g *GeoService) ActiveVersion() string {
+	return g.dataset().Version
+}
+
+// Versions returns the active dataset followed by the previous ones, newest first. Prepared datasets are not included.
+func (g *GeoService) Versions() (datasets []*Dataset) {
+	datasets = append(datasets, g.dataset())
+	if g.versions == nil {
+		return
+	}
+
+	g.versions.Lock()
+	defer g.versions.Unlock()
+
+	datasets = append(datasets, g.versions.history...)
+	return
+}
+
+// validateDataset runs the checks of opts against a freshly imported dataset
+func validateDataset(dataset *Dataset, opts VersionOptions) error {
+	stat := dataset.Stat
+	if stat.StoredEntries < opts.MinStored {
+		return fmt.Errorf("%w: %d locations stored, want at least %d", ErrValidationFailed, stat.StoredEntries, opts.MinStored)
+	}
+
+	if total := stat.AcceptedEntries + stat.DiscardedEntries + stat.Duplicates; opts.MaxDiscardRatio > 0 && total > 0 {
+		if ratio := float64(stat.DiscardedEntries) / float64(total); ratio > opts.MaxDiscardRatio {
+			return fmt.Errorf("%w: %.2f%% of rows discarded, want at most %.2f%%", ErrValidationFailed, ratio*100, opts.MaxDiscardRatio*100)
+		}
+	}
+
+	if opts.Validate != nil {
+		if err := opts.Validate(dataset); err != nil {
+			return fmt.Errorf("%w: %v", ErrValidationFailed, err)
+		}
+	}
+	return nil
+}
+
+// PrepareVersion imports source into a new Repository created by the factory and validates it.
+// The active dataset keeps serving lookups meanwhile, call ActivateVersion to switch to the new one.
+func (g *GeoService) PrepareVersion(ctx context.Context, source io.Reader, opts VersionOptions) (dataset *Dataset, err error) {
+	if g.versions == nil {
+		err = ErrVersioningDisabled
+		return
+	}
+
+	if opts.Version == "" {
+		opts.Version = time.Now().UTC().Format("20060102T150405.000000000Z")
+	}
+
+	if err = g.versions.reserve(opts.Version, g.ActiveVersion); err != nil {
+		return
+	}
+	defer func() {
+		g.versions.Lock()
+		defer g.versions.Unlock()
+		delete(g.versions.preparing, opts.Version)
+		if err == nil {
+			g.versions.prepared[opts.Version] = dataset
+		}
+	}()
+
+	dataset = &Dataset{Version: opts.Version, CreatedAt: time.Now()}
+	if dataset.Repository, err = g.versions.factory(opts.Version); err != nil {
+		return
+	}
+
+	dataset.Stat, err = g.importInto(ctx, dataset.Repository, source, opts.Import)
+	if err == nil {
+		err = validateDataset(dataset, opts)
+	}
+	if err != nil {
+		g.log().WarnContext(ctx, "dataset rejected", slog.String("version", opts.Version), slog.Any("error", err))
+		closeDataset(dataset)
+		return
+	}
+	return
+}
+
+// reserve claims version for PrepareVersion, it fails when a dataset with this version exists or is being prepared.
+// active returns the version of the active dataset, which only changes while the store is locked.
+func (s *versionStore) reserve(version string, active func() string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	_, exists := s.prepared[version]
+	if _, preparing := s.preparing[version]; preparing {
+		exists = true
+	}
+	for _, previous := range s.history {
+		exists = exists || previous.Version == version
+	}
+	if exists || active() == version {
+		return fmt.Errorf("%w: %s", ErrVersionExists, version)
+	}
+	s.preparing[version] = struct{}{}
+	return nil
+}
+
+// ActivateVersion atomically switches lookups to a prepared or previously active dataset.
+// The dataset being replaced is kept for rollback, datasets beyond the number to keep are closed if they implement io.Closer
+// once the lookups and writes still using them are done.
+func (g *GeoService) ActivateVersion(version string) (err error) {
+	if g.versions == nil {
+		err = ErrVersioningDisabled
+		return
+	}
+
+	g.versions.Lock()
+	defer g.versions.Unlock()
+
+	dataset, ok := g.versions.prepared[version]
+	if ok {
+		delete(g.versions.prepared, version)
+	} else {
+		for index, previous := range g.versions.history {
+			if previous.Version == version {
+				dataset = previous
+				g.versions.history = append(g.versions.history[:index], g.versions.history[index+1:]...)
+				break
+			}
+		}
+	}
+	if dataset == nil {
+		err = fmt.Errorf("%w: %s", ErrVersionNotFound, version)
+		return
+	}
+
+	dataset.ActivatedAt = time.Now()
+	replaced := g.dataset()
+	g.active.Store(dataset)
+	g.log().Info("dataset activated", slog.String("version", version), slog.String("replaced", replaced.Version))
+
+	if replaced.Repository != nil {
+		g.versions.history = append([]*Dataset{replaced}, g.versions.history...)
+	}
+	for len(g.versions.history) > g.versions.keep {
+		g.versions.history[len(g.versions.history)-1].retire()
+		g.versions.history = g.versions.history[:len(g.versions.history)-1]
+	}
+	return
+}
+
+// ImportVersion prepares a new dataset from source and activates it once it passes validation
+func (g *GeoService) ImportVersion(ctx context.Context, source io.Reader, opts VersionOptions) (dataset *Dataset, err error) {
+	if dataset, err = g.PrepareVersion(ctx, source, opts); err != nil {
+		return
+	}
+	err = g.ActivateVersion(dataset.Version)
+	return
+}
+
+// Rollback reactivates the most recent previous dataset, the current one becomes the previous dataset
+func (g *GeoService) Rollback() (err error) {
+	if g.versions == nil {
+		err = ErrVersioningDisabled
+		return
+	}
+
+	g.versions.Lock()
+	if len(g.versions.history) == 0 {
+		g.versions.Unlock()
+		err = ErrNoPreviousVersion
+		return
+	}
+	version := g.versions.history[0].Version
+	g.versions.Unlock()
+
+	err = g.ActivateVersion(version)
+	return
+}
+
+// DiscardVersion drops a prepared dataset that won't be activated
+func (g *GeoService) DiscardVersion(version string) (err error) {
+	if g.versions == nil {
+		err = ErrVersioningDisabled
+		return
+	}
+
+	g.versions.Lock()
+	defer g.versions.Unlock()
+
+	dataset, ok := g.versions.prepared[version]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrVersionNotFound, version)
+		return
+	}
+	delete(g.versions.prepared, version)
+	closeDataset(dataset)
+	return
+}
+
+func closeDataset(dataset *Dataset) {
+	if closer, ok := dataset.Repository.(io.Closer); ok {
+		closer.Close()
+	}
+}
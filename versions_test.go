@@ -0,0 +1,196 @@
+package geoservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliforever/geo-service/geolocation"
+	"net"
+	"strings"
+	"testing"
+)
+
+// closingDB records whether its dataset was dropped
+type closingDB struct {
+	*testDB
+	closed bool
+}
+
+func (c *closingDB) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestGeoService_ImportVersion(t *testing.T) {
+	created := map[string]*closingDB{}
+	factory := func(version string) (geolocation.Repository, error) {
+		created[version] = &closingDB{testDB: newTestDB()}
+		return created[version], nil
+	}
+
+	g := NewGeoService(newTestDB(), WithVersioning(factory, 1))
+
+	v1 := strings.Replace(importCSV, "DuBuquemouth", "Version One", 1)
+	v2 := strings.Replace(importCSV, "DuBuquemouth", "Version Two", 1)
+	ip := net.ParseIP("200.106.141.15")
+
+	tests := []struct {
+		name        string
+		source      string
+		opts        VersionOptions
+		wantErr     error
+		wantVersion string
+		wantCity    string
+	}{
+		{
+			name:        "First",
+			source:      v1,
+			opts:        VersionOptions{Version: "v1", MinStored: 4},
+			wantVersion: "v1",
+			wantCity:    "Version One",
+		},
+		{
+			name:        "Second",
+			source:      v2,
+			opts:        VersionOptions{Version: "v2"},
+			wantVersion: "v2",
+			wantCity:    "Version Two",
+		},
+		{
+			name:        "Existing",
+			source:      v2,
+			opts:        VersionOptions{Version: "v1"},
+			wantErr:     ErrVersionExists,
+			wantVersion: "v2",
+			wantCity:    "Version Two",
+		},
+		{
+			name:        "TooManyDiscarded",
+			source:      v1,
+			opts:        VersionOptions{Version: "v3", MaxDiscardRatio: 0.1},
+			wantErr:     ErrValidationFailed,
+			wantVersion: "v2",
+			wantCity:    "Version Two",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := g.ImportVersion(context.Background(), strings.NewReader(tt.source), tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			location, err := g.RetrieveLocation(ip)
+			if err != nil {
+				t.Fatalf("RetrieveLocation() error = %v", err)
+			}
+			if location.DatasetVersion != tt.wantVersion || location.City != tt.wantCity || g.ActiveVersion() != tt.wantVersion {
+				t.Errorf("RetrieveLocation() version = %s, city = %s, want %s, %s", location.DatasetVersion, location.City, tt.wantVersion, tt.wantCity)
+			}
+		})
+	}
+
+	if !created["v3"].closed {
+		t.Errorf("ImportVersion() didn't close the rejected dataset")
+	}
+
+	if err := g.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if location, _ := g.RetrieveLocation(ip); location.DatasetVersion != "v1" || location.City != "Version One" {
+		t.Errorf("RetrieveLocation() after Rollback() = %+v", location)
+	}
+
+	// Only one previous dataset is kept, activating a third one drops the oldest
+	if _, err := g.ImportVersion(context.Background(), strings.NewReader(v1), VersionOptions{Version: "v4"}); err != nil {
+		t.Fatalf("ImportVersion() error = %v", err)
+	}
+	if versions := g.Versions(); len(versions) != 2 || versions[0].Version != "v4" || versions[1].Version != "v1" {
+		t.Errorf("Versions() = %v", versions)
+	}
+	if !created["v2"].closed || created["v1"].closed {
+		t.Errorf("ActivateVersion() closed v1 = %v, v2 = %v, want only v2 closed", created["v1"].closed, created["v2"].closed)
+	}
+}
+
+func TestGeoService_ActivateVersionReaders(t *testing.T) {
+	created := map[string]*closingDB{}
+	factory := func(version string) (geolocation.Repository, error) {
+		created[version] = &closingDB{testDB: newTestDB()}
+		return created[version], nil
+	}
+	g := NewGeoService(newTestDB(), WithVersioning(factory, 0))
+
+	if _, err := g.ImportVersion(context.Background(), strings.NewReader(importCSV), VersionOptions{Version: "v1"}); err != nil {
+		t.Fatalf("ImportVersion() error = %v", err)
+	}
+	dataset := g.acquireDataset()
+	if _, err := g.ImportVersion(context.Background(), strings.NewReader(importCSV), VersionOptions{Version: "v2"}); err != nil {
+		t.Fatalf("ImportVersion() error = %v", err)
+	}
+
+	// Nothing is kept for rollback, v1 is closed as soon as its last reader is done
+	if created["v1"].closed {
+		t.Fatalf("ActivateVersion() closed v1 while it was used")
+	}
+	dataset.release()
+	if !created["v1"].closed {
+		t.Errorf("release() didn't close the dropped v1")
+	}
+	if created["v2"].closed {
+		t.Errorf("release() closed the active v2")
+	}
+}
+
+func TestGeoService_ActivateVersionKeep(t *testing.T) {
+	for _, keep := range []int{-1, 0} {
+		t.Run(fmt.Sprintf("Keep%d", keep), func(t *testing.T) {
+			created := map[string]*closingDB{}
+			factory := func(version string) (geolocation.Repository, error) {
+				created[version] = &closingDB{testDB: newTestDB()}
+				return created[version], nil
+			}
+			g := NewGeoService(newTestDB(), WithVersioning(factory, keep))
+
+			for _, version := range []string{"v1", "v2", "v3"} {
+				if _, err := g.ImportVersion(context.Background(), strings.NewReader(importCSV), VersionOptions{Version: version}); err != nil {
+					t.Fatalf("ImportVersion(%s) error = %v", version, err)
+				}
+			}
+			if !created["v1"].closed || !created["v2"].closed || created["v3"].closed {
+				t.Errorf("ActivateVersion() closed v1 = %v, v2 = %v, v3 = %v, want only v3 open", created["v1"].closed, created["v2"].closed, created["v3"].closed)
+			}
+			if err := g.Rollback(); err == nil {
+				t.Errorf("Rollback() error = nil without a kept dataset")
+			}
+		})
+	}
+}
+
+func TestGeoService_PrepareVersionConcurrent(t *testing.T) {
+	entered, proceed := make(chan struct{}), make(chan struct{})
+	factory := func(version string) (geolocation.Repository, error) {
+		close(entered)
+		<-proceed
+		return newTestDB(), nil
+	}
+	g := NewGeoService(newTestDB(), WithVersioning(factory, 1))
+
+	done := make(chan error)
+	go func() {
+		_, err := g.PrepareVersion(context.Background(), strings.NewReader(importCSV), VersionOptions{Version: "v1"})
+		done <- err
+	}()
+
+	<-entered
+	if _, err := g.PrepareVersion(context.Background(), strings.NewReader(importCSV), VersionOptions{Version: "v1"}); !errors.Is(err, ErrVersionExists) {
+		t.Errorf("PrepareVersion() of a version being prepared error = %v, want %v", err, ErrVersionExists)
+	}
+	close(proceed)
+	if err := <-done; err != nil {
+		t.Fatalf("PrepareVersion() error = %v", err)
+	}
+	if err := g.ActivateVersion("v1"); err != nil {
+		t.Errorf("ActivateVersion() error = %v", err)
+	}
+}